@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"pixiv-tailor/backend/internal/config"
+	"pixiv-tailor/backend/internal/tagger"
 	"pixiv-tailor/backend/pkg/models"
 	"pixiv-tailor/backend/pkg/paths"
 )
@@ -366,60 +367,23 @@ func (g *SDWebUIGenerator) GetLoras() ([]string, error) {
 // 标签器接口和实现
 // ============================================================================
 
-// Tagger 标签器接口
-type Tagger interface {
-	// GenerateTags 生成标签
-	GenerateTags(request *models.TagRequest) ([]*models.TaggedImage, error)
+// Tagger 标签器接口，与标签生成流程使用同一个接口（后端见 tagger.RegisterTagger）
+type Tagger = tagger.Tagger
 
-	// SaveTags 保存标签
-	SaveTags(taggedImage *models.TaggedImage, outputDir, saveType string) error
-
-	// GetAvailableAnalyzers 获取可用的分析器
-	GetAvailableAnalyzers() ([]string, error)
-
-	// AnalyzeImage 分析单张图像
-	AnalyzeImage(imagePath string, analyzer string) (*models.TaggedImage, error)
-}
-
-// WD14Tagger WD14Tagger实现
-type WD14Tagger struct {
-	url     string
-	timeout int
-}
-
-// NewTagger 创建新的标签器实例
+// NewTagger 按配置创建默认的标签后端
 func NewTagger() (Tagger, error) {
 	// 从配置文件加载AI配置
 	aiConfig := config.GetAIConfig()
 
-	return &WD14Tagger{
-		url:     aiConfig.SDWebUI.URL, // 使用SD WebUI的URL
-		timeout: aiConfig.SDWebUI.Timeout,
-	}, nil
-}
-
-// GenerateTags 生成标签
-func (t *WD14Tagger) GenerateTags(request *models.TagRequest) ([]*models.TaggedImage, error) {
-	// TODO: 实现标签生成逻辑
-	return nil, nil
-}
-
-// SaveTags 保存标签
-func (t *WD14Tagger) SaveTags(taggedImage *models.TaggedImage, outputDir, saveType string) error {
-	// TODO: 实现标签保存逻辑
-	return nil
-}
-
-// GetAvailableAnalyzers 获取可用的分析器
-func (t *WD14Tagger) GetAvailableAnalyzers() ([]string, error) {
-	// 只返回 WD14 Tagger
-	return []string{"wd14tagger"}, nil
-}
-
-// AnalyzeImage 分析单张图像
-func (t *WD14Tagger) AnalyzeImage(imagePath string, analyzer string) (*models.TaggedImage, error) {
-	// TODO: 实现单张图像分析逻辑
-	return nil, nil
+	backend := aiConfig.WD14Tagger.Backend
+	if backend == "" {
+		backend = tagger.BackendWD14Tagger
+	}
+	return tagger.NewTaggerBackend(backend, tagger.TaggerBackendConfig{
+		BaseURL:    aiConfig.SDWebUI.URL, // 使用SD WebUI的URL
+		ServiceURL: aiConfig.WD14Tagger.ServiceURL,
+		Client:     &http.Client{Timeout: time.Duration(aiConfig.SDWebUI.Timeout) * time.Second},
+	})
 }
 
 // ============================================================================
@@ -506,7 +470,7 @@ func NewAIManager() (*AIManager, error) {
 		return nil, err
 	}
 
-	tagger, err := NewTagger()
+	imageTagger, err := NewTagger()
 	if err != nil {
 		return nil, err
 	}
@@ -519,7 +483,7 @@ func NewAIManager() (*AIManager, error) {
 	return &AIManager{
 		classifier: classifier,
 		generator:  generator,
-		tagger:     tagger,
+		tagger:     imageTagger,
 		trainer:    trainer,
 	}, nil
 }
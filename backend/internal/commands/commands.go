@@ -19,6 +19,7 @@ import (
 	"pixiv-tailor/backend/internal/logger"
 	"pixiv-tailor/backend/internal/repository"
 	"pixiv-tailor/backend/internal/service"
+	"pixiv-tailor/backend/internal/tagger"
 
 	"pixiv-tailor/backend/pkg/models"
 	"pixiv-tailor/backend/pkg/paths"
@@ -623,8 +624,8 @@ func TagAction(ctx *cli.Context) error {
 		outputDir = pm.GetTagsDir()
 	}
 
-	// 创建标签器实例
-	tagger, err := ai.NewTagger()
+	// 创建标签器实例（按 analyzer 选择标签后端）
+	wd14Tagger, err := tagger.NewWD14Tagger()
 	if err != nil {
 		return fmt.Errorf("创建标签器实例失败: %v", err)
 	}
@@ -639,12 +640,58 @@ func TagAction(ctx *cli.Context) error {
 
 	// 执行标签生成
 	logger.Infof("开始生成标签，输入目录: %s", inputDir)
-	taggedImages, err := tagger.GenerateTags(request)
-	if err != nil {
+	if err := wd14Tagger.GenerateTags(request); err != nil {
 		return fmt.Errorf("标签生成失败: %v", err)
 	}
 
-	logger.Infof("成功处理 %d 张图像", len(taggedImages))
+	logger.Infof("成功处理 %d 张图像", wd14Tagger.Stats().Tagged)
+
+	return nil
+}
+
+// TagsEditAction 标签编辑命令处理函数（op 为 undo 时撤销编辑）
+func TagsEditAction(ctx *cli.Context) error {
+	tagEditService := service.NewTagEditService("")
+
+	var result *service.TagEditResult
+	var err error
+	if ctx.String("op") == "undo" {
+		result, err = tagEditService.Undo(ctx.String("journal"))
+		if err != nil {
+			return fmt.Errorf("撤销标签编辑失败: %v", err)
+		}
+	} else {
+		request := &service.TagEditRequest{
+			Dir:       ctx.String("dir"),
+			Operation: ctx.String("op"),
+			Tags:      ctx.StringSlice("tag"),
+			From:      ctx.String("from"),
+			To:        ctx.String("to"),
+			Position:  ctx.String("position"),
+			Query:     ctx.StringSlice("query"),
+			Recursive: ctx.Bool("recursive"),
+			DryRun:    ctx.Bool("dry-run"),
+		}
+		result, err = tagEditService.Edit(request)
+		if err != nil {
+			return fmt.Errorf("编辑标签失败: %v", err)
+		}
+	}
+
+	for _, change := range result.Changes {
+		logger.Infof("%s: %s -> %s", change.Path, strings.TrimSpace(change.Before), strings.TrimSpace(change.After))
+	}
+	for _, path := range result.Conflicts {
+		logger.Warnf("文件已被再次修改，未恢复: %s", path)
+	}
+	if result.DryRun {
+		logger.Infof("预览完成: 扫描 %d 个文件，匹配 %d 个，将修改 %d 个", result.Scanned, result.Matched, result.Changed)
+	} else {
+		logger.Infof("完成: 扫描 %d 个文件，匹配 %d 个，修改 %d 个", result.Scanned, result.Matched, result.Changed)
+	}
+	if result.JournalID != "" && result.Operation != "undo" {
+		logger.Infof("编辑日志: %s（使用 --op undo --journal %s 撤销）", result.JournalID, result.JournalID)
+	}
 
 	return nil
 }
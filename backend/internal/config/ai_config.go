@@ -73,15 +73,21 @@ type ExtendedOpenAIConfig struct {
 
 // WD14TaggerConfig WD14Tagger配置
 type WD14TaggerConfig struct {
-	ModelPath  string                 `json:"model_path"`
-	Threshold  float64                `json:"threshold"`
-	BatchSize  int                    `json:"batch_size"`
-	MaxTags    int                    `json:"max_tags"`
-	SkipTags   []string               `json:"skip_tags"`
-	ExtendTags []string               `json:"extend_tags"`
-	TagOrder   string                 `json:"tag_order"`
-	SaveType   string                 `json:"save_type"`
-	Options    map[string]interface{} `json:"options"`
+	ModelPath          string                 `json:"model_path"`
+	Backend            string                 `json:"backend"`       // 默认标签后端：wd14tagger、deepbooru、clip、tagger_service
+	ServiceURL         string                 `json:"service_url"`   // 独立标签服务地址（tagger_service 后端使用）
+	CaptionURL         string                 `json:"caption_url"`   // 描述服务接口地址（caption_service 后端使用）
+	CaptionModel       string                 `json:"caption_model"` // 描述服务使用的模型，为空时由服务决定
+	Threshold          float64                `json:"threshold"`
+	CharacterThreshold float64                `json:"character_threshold"` // 角色标签阈值，0 表示使用 threshold
+	CategoryThresholds map[string]float64     `json:"category_thresholds"` // 按标签类别设置的阈值（优先于 character_threshold 和 threshold）
+	BatchSize          int                    `json:"batch_size"`
+	MaxTags            int                    `json:"max_tags"`
+	SkipTags           []string               `json:"skip_tags"`
+	ExtendTags         []string               `json:"extend_tags"`
+	TagOrder           string                 `json:"tag_order"`
+	SaveType           string                 `json:"save_type"`
+	Options            map[string]interface{} `json:"options"`
 }
 
 // Config 完整配置结构
@@ -265,6 +271,15 @@ func applyEnvOverrides(aiConfig *ExtendedAIConfig) {
 	if modelPath := os.Getenv("WD14TAGGER_MODEL_PATH"); modelPath != "" {
 		aiConfig.WD14Tagger.ModelPath = modelPath
 	}
+	if serviceURL := os.Getenv("TAGGER_SERVICE_URL"); serviceURL != "" {
+		aiConfig.WD14Tagger.ServiceURL = serviceURL
+	}
+	if captionURL := os.Getenv("CAPTION_SERVICE_URL"); captionURL != "" {
+		aiConfig.WD14Tagger.CaptionURL = captionURL
+	}
+	if captionModel := os.Getenv("CAPTION_MODEL"); captionModel != "" {
+		aiConfig.WD14Tagger.CaptionModel = captionModel
+	}
 }
 
 // GetAIConfig 获取AI配置（单例模式）
@@ -301,15 +316,18 @@ func getDefaultAIConfig() *ExtendedAIConfig {
 			},
 		},
 		WD14Tagger: WD14TaggerConfig{
-			ModelPath:  "",
-			Threshold:  0.35,
-			BatchSize:  1,
-			MaxTags:    100,
-			SkipTags:   []string{"low_quality", "blurry"},
-			ExtendTags: []string{"high_quality", "detailed"},
-			TagOrder:   "character",
-			SaveType:   "txt",
-			Options:    make(map[string]interface{}),
+			ModelPath:          "",
+			Backend:            "wd14tagger",
+			Threshold:          0.35,
+			CharacterThreshold: 0.85,
+			CategoryThresholds: make(map[string]float64),
+			BatchSize:          1,
+			MaxTags:            100,
+			SkipTags:           []string{"low_quality", "blurry"},
+			ExtendTags:         []string{"high_quality", "detailed"},
+			TagOrder:           "character",
+			SaveType:           "txt",
+			Options:            make(map[string]interface{}),
 		},
 		Timeout:    30,
 		RetryCount: 3,
@@ -0,0 +1,172 @@
+package events
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"pixiv-tailor/backend/internal/logger"
+)
+
+// EventType 事件类型
+type EventType string
+
+const (
+	// TaskStatus 任务生命周期/进度变化（状态切换、进度、阶段、图片计数、结果更新）
+	TaskStatus EventType = "task.status"
+	// TaskLog 任务日志
+	TaskLog EventType = "task.log"
+	// WebUILog WebUI 进程日志
+	WebUILog EventType = "webui.log"
+)
+
+// DefaultBufferSize 订阅者默认缓冲区大小
+const DefaultBufferSize = 100
+
+// Event 事件
+type Event struct {
+	Type   EventType   `json:"type"`
+	TaskID string      `json:"task_id,omitempty"`
+	Time   time.Time   `json:"time"`
+	Data   interface{} `json:"data,omitempty"`
+}
+
+// TaskStatusData 任务状态事件数据
+type TaskStatusData struct {
+	Status   string `json:"status"`
+	Progress int    `json:"progress"`
+	Stage    string `json:"stage,omitempty"` // 当前执行阶段（仅带阶段的进度更新时设置）
+}
+
+// TaskLogData 任务日志事件数据
+type TaskLogData struct {
+	ID      int64  `json:"id"` // 持久化后的日志ID（写库失败时为0）
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+// WebUILogData WebUI 日志事件数据
+type WebUILogData struct {
+	Message string `json:"message"`
+}
+
+// Filter 订阅过滤条件，零值表示不过滤
+type Filter struct {
+	Types  []EventType // 只接收这些类型的事件
+	TaskID string      // 只接收该任务的事件
+}
+
+// match 判断事件是否满足过滤条件
+func (f Filter) match(event *Event) bool {
+	if f.TaskID != "" && f.TaskID != event.TaskID {
+		return false
+	}
+	if len(f.Types) == 0 {
+		return true
+	}
+	for _, t := range f.Types {
+		if t == event.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscription 事件订阅
+type Subscription struct {
+	C       <-chan *Event
+	ch      chan *Event
+	filter  Filter
+	name    string
+	dropped int64
+	bus     *eventBusImpl
+	once    sync.Once
+}
+
+// Unsubscribe 取消订阅（可重复调用）
+func (sub *Subscription) Unsubscribe() {
+	sub.once.Do(func() {
+		sub.bus.remove(sub)
+	})
+}
+
+// Dropped 因缓冲区已满而丢弃的事件数量
+func (sub *Subscription) Dropped() int64 {
+	return atomic.LoadInt64(&sub.dropped)
+}
+
+// Bus 事件总线接口：发布者从不阻塞，每个订阅者拥有独立的有界缓冲区
+type Bus interface {
+	// Publish 发布事件，订阅者缓冲区已满时丢弃该订阅者的这条事件
+	Publish(event *Event)
+	// Subscribe 订阅事件，bufferSize <= 0 时使用 DefaultBufferSize
+	Subscribe(name string, filter Filter, bufferSize int) *Subscription
+}
+
+// eventBusImpl 事件总线实现
+type eventBusImpl struct {
+	subscribers map[*Subscription]bool
+	mutex       sync.RWMutex
+}
+
+// NewBus 创建事件总线
+func NewBus() Bus {
+	return &eventBusImpl{
+		subscribers: make(map[*Subscription]bool),
+	}
+}
+
+// Publish 发布事件
+func (b *eventBusImpl) Publish(event *Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	for sub := range b.subscribers {
+		if !sub.filter.match(event) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			// 慢订阅者不能阻塞任务，丢弃并计数
+			dropped := atomic.AddInt64(&sub.dropped, 1)
+			if dropped == 1 || dropped%100 == 0 {
+				logger.Warnf("事件订阅者 %s 缓冲区已满，已丢弃 %d 个事件", sub.name, dropped)
+			}
+		}
+	}
+}
+
+// Subscribe 订阅事件
+func (b *eventBusImpl) Subscribe(name string, filter Filter, bufferSize int) *Subscription {
+	if bufferSize <= 0 {
+		bufferSize = DefaultBufferSize
+	}
+
+	ch := make(chan *Event, bufferSize)
+	sub := &Subscription{
+		C:      ch,
+		ch:     ch,
+		filter: filter,
+		name:   name,
+		bus:    b,
+	}
+
+	b.mutex.Lock()
+	b.subscribers[sub] = true
+	b.mutex.Unlock()
+
+	return sub
+}
+
+// remove 移除订阅者并关闭其通道
+func (b *eventBusImpl) remove(sub *Subscription) {
+	b.mutex.Lock()
+	delete(b.subscribers, sub)
+	b.mutex.Unlock()
+	close(sub.ch)
+}
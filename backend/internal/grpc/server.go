@@ -2,9 +2,13 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"time"
 
+	"pixiv-tailor/backend/internal/events"
 	"pixiv-tailor/backend/internal/logger"
 	"pixiv-tailor/backend/internal/repository"
 	"pixiv-tailor/backend/internal/service"
@@ -29,7 +33,7 @@ func convertTask(task *repository.Task) *pb.Task {
 	if task == nil {
 		return nil
 	}
-	return &pb.Task{
+	pbTask := &pb.Task{
 		Id:           task.ID,
 		Type:         task.Type,
 		Status:       task.Status,
@@ -37,7 +41,15 @@ func convertTask(task *repository.Task) *pb.Task {
 		CreatedAt:    task.CreatedAt.Format(time.RFC3339),
 		UpdatedAt:    task.UpdatedAt.Format(time.RFC3339),
 		ErrorMessage: task.ErrorMessage,
+		Attempt:      int32(task.Attempt),
+		MaxAttempts:  int32(task.MaxAttempts),
+		Labels:       task.Labels,
+		Notes:        task.Notes,
 	}
+	if task.NextRetryAt != nil {
+		pbTask.NextRetryAt = task.NextRetryAt.Format(time.RFC3339)
+	}
+	return pbTask
 }
 
 // convertTasks 转换任务列表
@@ -49,6 +61,30 @@ func convertTasks(tasks []*repository.Task) []*pb.Task {
 	return result
 }
 
+// convertTaskLog 转换任务日志
+func convertTaskLog(taskLog *repository.TaskLog) *pb.LogEntry {
+	return &pb.LogEntry{
+		Timestamp: taskLog.CreatedAt.Format(time.RFC3339),
+		Level:     taskLog.Level,
+		Message:   taskLog.Message,
+		Details:   taskLog.TaskID,
+	}
+}
+
+// convertTaskArtifact 转换任务产出文件
+func convertTaskArtifact(artifact *repository.TaskArtifact) *pb.TaskArtifact {
+	return &pb.TaskArtifact{
+		Id:        artifact.ID,
+		TaskId:    artifact.TaskID,
+		Path:      artifact.Path,
+		Size:      artifact.Size,
+		Sha256:    artifact.SHA256,
+		MimeType:  artifact.MimeType,
+		Role:      artifact.Role,
+		CreatedAt: artifact.CreatedAt.Format(time.RFC3339),
+	}
+}
+
 // convertCrawlResult 转换爬取结果
 func convertCrawlResult(result *repository.CrawlResult) *pb.CrawlResult {
 	if result == nil {
@@ -211,6 +247,17 @@ func (s *PixivTailorServer) CreateTask(ctx context.Context, req *pb.CreateTaskRe
 
 	task, err := s.TaskService.CreateTask(req.Type, req.Config)
 	if err != nil {
+		// 配置校验失败时 Details 中列出所有字段错误
+		var validationErr *service.ConfigValidationError
+		if errors.As(err, &validationErr) {
+			return &pb.CreateTaskResponse{
+				Status: &pb.Status{
+					Code:    1,
+					Message: "任务配置无效",
+					Details: validationErr.Error(),
+				},
+			}, nil
+		}
 		return &pb.CreateTaskResponse{
 			Status: &pb.Status{
 				Code:    1,
@@ -257,7 +304,28 @@ func (s *PixivTailorServer) GetTaskStatus(ctx context.Context, req *pb.GetTaskSt
 func (s *PixivTailorServer) ListTasks(ctx context.Context, req *pb.ListTasksRequest) (*pb.ListTasksResponse, error) {
 	logger.Infof("收到列出任务请求: %v", req)
 
-	tasks, total, err := s.TaskService.ListTasks(req.Pagination.Page, req.Pagination.PageSize, req.Status, req.Type)
+	createdFrom, createdTo, err := service.ParseTaskTimeRange(req.CreatedFrom, req.CreatedTo)
+	if err != nil {
+		return &pb.ListTasksResponse{
+			Status: &pb.Status{
+				Code:    1,
+				Message: "时间范围无效",
+				Details: err.Error(),
+			},
+		}, nil
+	}
+
+	filter := &repository.TaskFilter{
+		Status:      req.Status,
+		Type:        req.Type,
+		Labels:      req.Labels,
+		Search:      req.Search,
+		CreatedFrom: createdFrom,
+		CreatedTo:   createdTo,
+		SortBy:      req.SortBy,
+		SortOrder:   req.SortOrder,
+	}
+	tasks, total, err := s.TaskService.SearchTasks(filter, req.GetPagination().GetPage(), req.GetPagination().GetPageSize())
 	if err != nil {
 		return &pb.ListTasksResponse{
 			Status: &pb.Status{
@@ -275,8 +343,8 @@ func (s *PixivTailorServer) ListTasks(ctx context.Context, req *pb.ListTasksRequ
 		},
 		Tasks: convertTasks(tasks),
 		Pagination: &pb.Pagination{
-			Page:     req.Pagination.Page,
-			PageSize: req.Pagination.PageSize,
+			Page:     req.GetPagination().GetPage(),
+			PageSize: req.GetPagination().GetPageSize(),
 			Total:    int32(total),
 		},
 	}, nil
@@ -310,9 +378,17 @@ func (s *PixivTailorServer) CancelTask(ctx context.Context, req *pb.CancelTaskRe
 // ============================================================================
 
 // GetTaskProgress 获取任务进度（流式响应）
+// 订阅任务状态事件，推送每次进度/阶段/状态变化，直到任务结束或客户端断开
 func (s *PixivTailorServer) GetTaskProgress(req *pb.GetTaskProgressRequest, stream pb.PixivTailorService_GetTaskProgressServer) error {
 	logger.Infof("收到获取任务进度请求: %v", req)
 
+	// 先订阅再读取当前状态，避免两者之间的状态变化丢失
+	sub := s.TaskService.EventBus().Subscribe("grpc-progress", events.Filter{
+		Types:  []events.EventType{events.TaskStatus},
+		TaskID: req.TaskId,
+	}, events.DefaultBufferSize)
+	defer sub.Unsubscribe()
+
 	// 获取任务信息
 	task, err := s.TaskService.GetTask(req.TaskId)
 	if err != nil {
@@ -340,46 +416,76 @@ func (s *PixivTailorServer) GetTaskProgress(req *pb.GetTaskProgressRequest, stre
 		return err
 	}
 
-	// 如果任务正在运行，持续发送进度更新
-	if task.Status == "running" {
-		ticker := time.NewTicker(2 * time.Second)
-		defer ticker.Stop()
+	if isTerminalStatus(task.Status) {
+		return nil
+	}
 
-		for i := 0; i < 5; i++ { // 最多发送5次更新
-			select {
-			case <-ticker.C:
-				// 重新获取任务状态
-				updatedTask, err := s.TaskService.GetTask(req.TaskId)
-				if err != nil {
-					continue
-				}
+	// 事件可能因订阅者缓冲区满被丢弃，定期检查数据库状态作为兜底，确保任务结束时流能正常关闭
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
 
-				progress := &pb.TaskProgressUpdate{
-					TaskId:   req.TaskId,
-					Progress: int32(updatedTask.Progress),
-					Status:   updatedTask.Status,
-					Message:  "进度更新",
-					Details:  fmt.Sprintf("当前进度: %d%%", updatedTask.Progress),
-				}
+	lastStatus, lastProgress := task.Status, task.Progress
+	for {
+		select {
+		case event := <-sub.C:
+			data, ok := event.Data.(*events.TaskStatusData)
+			if !ok {
+				continue
+			}
+			message := "进度更新"
+			if data.Stage != "" {
+				message = data.Stage
+			} else if data.Status != lastStatus {
+				message = "状态更新"
+			}
+			lastStatus, lastProgress = data.Status, data.Progress
+
+			progress := &pb.TaskProgressUpdate{
+				TaskId:   req.TaskId,
+				Progress: int32(data.Progress),
+				Status:   data.Status,
+				Message:  message,
+				Details:  fmt.Sprintf("当前进度: %d%%", data.Progress),
+			}
+			if err := stream.Send(progress); err != nil {
+				return err
+			}
 
-				if err := stream.Send(progress); err != nil {
-					return err
-				}
+			if isTerminalStatus(data.Status) {
+				return nil
+			}
+		case <-ticker.C:
+			updatedTask, err := s.TaskService.GetTask(req.TaskId)
+			if err != nil {
+				continue
+			}
+			if updatedTask.Status == lastStatus && updatedTask.Progress == lastProgress {
+				continue
+			}
+			lastStatus, lastProgress = updatedTask.Status, updatedTask.Progress
+
+			progress := &pb.TaskProgressUpdate{
+				TaskId:   req.TaskId,
+				Progress: int32(updatedTask.Progress),
+				Status:   updatedTask.Status,
+				Message:  "进度更新",
+				Details:  fmt.Sprintf("当前进度: %d%%", updatedTask.Progress),
+			}
+			if err := stream.Send(progress); err != nil {
+				return err
+			}
 
-				// 如果任务完成或失败，停止更新
-				if updatedTask.Status == "completed" || updatedTask.Status == "failed" || updatedTask.Status == "cancelled" {
-					return nil
-				}
-			case <-stream.Context().Done():
-				return stream.Context().Err()
+			if isTerminalStatus(updatedTask.Status) {
+				return nil
 			}
+		case <-stream.Context().Done():
+			return stream.Context().Err()
 		}
 	}
-
-	return nil
 }
 
 // GetTaskLogs 获取任务日志（流式响应）
+// 先发送历史日志（limit > 0 时只发送最近 limit 条），任务未结束时继续推送实时日志直到任务结束或客户端断开
 func (s *PixivTailorServer) GetTaskLogs(req *pb.GetTaskLogsRequest, stream pb.PixivTailorService_GetTaskLogsServer) error {
 	logger.Infof("收到获取任务日志请求: %v", req)
 
@@ -395,83 +501,188 @@ func (s *PixivTailorServer) GetTaskLogs(req *pb.GetTaskLogsRequest, stream pb.Pi
 		return stream.Send(logEntry)
 	}
 
-	// 发送任务基本信息日志
-	logs := []struct {
-		level   string
-		message string
-		details string
-	}{
-		{"info", "任务信息", fmt.Sprintf("任务ID: %s, 类型: %s, 状态: %s", task.ID, task.Type, task.Status)},
-		{"info", "任务配置", task.Config},
-		{"info", "创建时间", task.CreatedAt.Format(time.RFC3339)},
-		{"info", "更新时间", task.UpdatedAt.Format(time.RFC3339)},
-	}
-
-	// 如果有错误信息，添加错误日志
-	if task.ErrorMessage != "" {
-		logs = append(logs, struct {
-			level   string
-			message string
-			details string
-		}{"error", "任务错误", task.ErrorMessage})
-	}
-
-	// 根据任务状态添加状态日志
-	switch task.Status {
-	case "pending":
-		logs = append(logs, struct {
-			level   string
-			message string
-			details string
-		}{"info", "任务状态", "任务等待执行"})
-	case "running":
-		logs = append(logs, struct {
-			level   string
-			message string
-			details string
-		}{"info", "任务状态", fmt.Sprintf("任务正在执行，进度: %d%%", task.Progress)})
-	case "completed":
-		logs = append(logs, struct {
-			level   string
-			message string
-			details string
-		}{"info", "任务状态", "任务已完成"})
-	case "failed":
-		logs = append(logs, struct {
-			level   string
-			message string
-			details string
-		}{"error", "任务状态", "任务执行失败"})
-	case "cancelled":
-		logs = append(logs, struct {
-			level   string
-			message string
-			details string
-		}{"warning", "任务状态", "任务已取消"})
-	}
-
-	// 流式发送日志
+	// 先订阅实时日志再读取历史，避免两者之间产生的日志丢失
+	sub := s.TaskService.EventBus().Subscribe("grpc-logs", events.Filter{
+		Types:  []events.EventType{events.TaskLog},
+		TaskID: req.TaskId,
+	}, events.DefaultBufferSize)
+	defer sub.Unsubscribe()
+
+	history, _, err := s.TaskService.GetTaskLogs(req.TaskId, "", 1, 0)
+	if err != nil {
+		return stream.Send(&pb.LogEntry{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Level:     "error",
+			Message:   "获取任务日志失败",
+			Details:   err.Error(),
+		})
+	}
+
 	limit := int(req.Limit)
-	if limit <= 0 || limit > len(logs) {
-		limit = len(logs)
+	if limit > 0 && len(history) > limit {
+		history = history[len(history)-limit:]
 	}
 
-	for i := 0; i < limit; i++ {
-		logEntry := &pb.LogEntry{
-			Timestamp: time.Now().Add(-time.Duration(len(logs)-i) * time.Second).Format(time.RFC3339),
-			Level:     logs[i].level,
-			Message:   logs[i].message,
-			Details:   logs[i].details,
+	// 日志先写库再发布事件，ID 不大于最后一条历史日志的事件已包含在历史中
+	// （按ID而不是时间去重，同一时刻的多条日志不会被误判为重复）
+	var lastID int64
+	for _, taskLog := range history {
+		if err := stream.Send(convertTaskLog(taskLog)); err != nil {
+			return err
 		}
+		lastID = taskLog.ID
+	}
 
-		if err := stream.Send(logEntry); err != nil {
-			return err
+	if isTerminalStatus(task.Status) {
+		return nil
+	}
+
+	// 跟踪实时日志，定期检查任务是否已结束
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	sendLive := func(event *events.Event) error {
+		data, ok := event.Data.(*events.TaskLogData)
+		if !ok || (data.ID != 0 && data.ID <= lastID) {
+			return nil // 已在历史日志中发送过
+		}
+		if data.ID != 0 {
+			lastID = data.ID
 		}
+		return stream.Send(&pb.LogEntry{
+			Timestamp: event.Time.Format(time.RFC3339),
+			Level:     data.Level,
+			Message:   data.Message,
+			Details:   event.TaskID,
+		})
+	}
+
+	for {
+		select {
+		case event := <-sub.C:
+			if err := sendLive(event); err != nil {
+				return err
+			}
+		case <-ticker.C:
+			updatedTask, err := s.TaskService.GetTask(req.TaskId)
+			if err != nil || isTerminalStatus(updatedTask.Status) {
+				// 发送剩余的缓冲日志后结束
+				for {
+					select {
+					case event := <-sub.C:
+						if err := sendLive(event); err != nil {
+							return err
+						}
+					default:
+						return nil
+					}
+				}
+			}
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
+// isTerminalStatus 判断任务是否处于结束状态
+func isTerminalStatus(status string) bool {
+	return status == "completed" || status == "failed" || status == "cancelled"
+}
+
+// ============================================================================
+// 任务产出文件
+// ============================================================================
+
+// artifactChunkSize 下载产出文件时每个分块的大小
+const artifactChunkSize = 64 * 1024
+
+// ListTaskArtifacts 获取任务产出文件清单
+func (s *PixivTailorServer) ListTaskArtifacts(ctx context.Context, req *pb.ListTaskArtifactsRequest) (*pb.ListTaskArtifactsResponse, error) {
+	logger.Infof("收到获取任务产出文件请求: %v", req)
+
+	artifacts, err := s.TaskService.ListTaskArtifacts(req.TaskId, req.Role)
+	if err != nil {
+		return &pb.ListTaskArtifactsResponse{
+			Status: &pb.Status{
+				Code:    1,
+				Message: "获取任务产出文件失败",
+				Details: err.Error(),
+			},
+		}, nil
+	}
+
+	pbArtifacts := make([]*pb.TaskArtifact, len(artifacts))
+	for i, artifact := range artifacts {
+		pbArtifacts[i] = convertTaskArtifact(artifact)
+	}
+	return &pb.ListTaskArtifactsResponse{
+		Status: &pb.Status{
+			Code:    0,
+			Message: "成功",
+		},
+		Artifacts: pbArtifacts,
+	}, nil
+}
 
-		// 模拟延迟
-		time.Sleep(100 * time.Millisecond)
+// DownloadTaskArtifact 下载任务产出文件（流式响应，按分块发送文件内容）
+func (s *PixivTailorServer) DownloadTaskArtifact(req *pb.DownloadTaskArtifactRequest, stream pb.PixivTailorService_DownloadTaskArtifactServer) error {
+	logger.Infof("收到下载任务产出文件请求: %v", req)
+
+	artifact, path, err := s.TaskService.GetTaskArtifact(req.TaskId, req.ArtifactId)
+	if err != nil {
+		return stream.Send(&pb.ArtifactChunk{
+			Status: &pb.Status{
+				Code:    1,
+				Message: "产出文件不存在",
+				Details: err.Error(),
+			},
+		})
 	}
 
+	file, err := os.Open(path)
+	if err != nil {
+		return stream.Send(&pb.ArtifactChunk{
+			Status: &pb.Status{
+				Code:    1,
+				Message: "打开产出文件失败",
+				Details: err.Error(),
+			},
+		})
+	}
+	defer file.Close()
+
+	first := &pb.ArtifactChunk{
+		Status: &pb.Status{
+			Code:    0,
+			Message: "成功",
+		},
+		Artifact: convertTaskArtifact(artifact),
+	}
+	buf := make([]byte, artifactChunkSize)
+	for {
+		n, readErr := file.Read(buf)
+		if n > 0 {
+			chunk := &pb.ArtifactChunk{Data: append([]byte(nil), buf[:n]...)}
+			if first != nil {
+				first.Data = chunk.Data
+				chunk, first = first, nil
+			}
+			if err := stream.Send(chunk); err != nil {
+				return err
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return fmt.Errorf("读取产出文件失败: %v", readErr)
+		}
+	}
+
+	// 空文件也要发送带有元数据的分块
+	if first != nil {
+		return stream.Send(first)
+	}
 	return nil
 }
 
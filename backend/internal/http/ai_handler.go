@@ -15,6 +15,7 @@ import (
 
 	"pixiv-tailor/backend/internal/ai"
 	"pixiv-tailor/backend/internal/logger"
+	"pixiv-tailor/backend/internal/repository"
 	"pixiv-tailor/backend/internal/service"
 	"pixiv-tailor/backend/pkg/models"
 	"pixiv-tailor/backend/pkg/paths"
@@ -24,28 +25,65 @@ import (
 type AIHandler struct {
 	taskService             service.TaskService
 	generationConfigService service.GenerationConfigService
+	webUIURL                string
 }
 
+// defaultWebUIURL WebUI 默认地址
+const defaultWebUIURL = "http://127.0.0.1:7860"
+
 // NewAIHandler 创建AI处理器
 func NewAIHandler(aiService *ai.AIManager, taskService service.TaskService, generationConfigService service.GenerationConfigService) *AIHandler {
 	handler := &AIHandler{
 		taskService:             taskService,
 		generationConfigService: generationConfigService,
+		webUIURL:                defaultWebUIURL,
 	}
 
 	// 注册 generate 任务的执行器
-	taskService.RegisterExecutor("generate", handler)
+	taskService.RegisterExecutor("generate", &generateTaskExecutor{handler: handler})
 
 	return handler
 }
 
-// ExecuteGenerateTask 实现 TaskExecutor 接口，执行AI生成任务
-func (h *AIHandler) ExecuteGenerateTask(ctx context.Context, taskID string, config map[string]interface{}) {
-	// 获取循环数量
-	loopCount := 1
-	if loopCountVal, ok := config["loop_count"].(float64); ok {
-		loopCount = int(loopCountVal)
+// SetWebUIURL 设置 WebUI 地址，空字符串恢复默认地址
+func (h *AIHandler) SetWebUIURL(url string) {
+	if url == "" {
+		url = defaultWebUIURL
 	}
+	h.webUIURL = strings.TrimRight(url, "/")
+}
+
+// generateTaskExecutor AI生成任务执行器
+type generateTaskExecutor struct {
+	handler *AIHandler
+}
+
+// Schema AI生成任务配置 Schema
+func (e *generateTaskExecutor) Schema() *service.ConfigSchema {
+	return service.GenerateConfigSchema()
+}
+
+// Validate AI生成任务没有跨字段校验，字段类型由 Schema 校验
+func (e *generateTaskExecutor) Validate(config map[string]interface{}) error {
+	return nil
+}
+
+// Execute 执行AI生成任务
+func (e *generateTaskExecutor) Execute(ctx context.Context, task *repository.Task, config map[string]interface{}, reporter service.TaskReporter) error {
+	return e.handler.ExecuteGenerateTask(ctx, task.ID, config, reporter)
+}
+
+// Cleanup AI生成任务无需额外清理
+func (e *generateTaskExecutor) Cleanup(task *repository.Task) {}
+
+// ExecuteGenerateTask 执行AI生成任务
+func (h *AIHandler) ExecuteGenerateTask(ctx context.Context, taskID string, config map[string]interface{}, reporter service.TaskReporter) error {
+	// 获取循环数量（其余生成参数原样转发给 WebUI）
+	var taskConfig service.GenerateTaskConfig
+	if err := service.DecodeConfig(config, &taskConfig); err != nil {
+		return err
+	}
+	loopCount := taskConfig.LoopCount
 	if loopCount <= 0 {
 		loopCount = 1
 	}
@@ -61,76 +99,84 @@ func (h *AIHandler) ExecuteGenerateTask(ctx context.Context, taskID string, conf
 		logger.Infof("开始第 %d/%d 次发包", currentLoop, loopCount)
 
 		// 检查上下文是否已被取消
-		select {
-		case <-ctx.Done():
-			h.taskService.UpdateTaskStatus(taskID, "cancelled")
-			h.taskService.UpdateTaskError(taskID, "任务已被用户取消")
+		if err := ctx.Err(); err != nil {
 			logger.Infof("任务 %s 已取消", taskID)
-			return
-		default:
+			return err
 		}
 
 		// 计算当前循环的进度
 		loopProgress := int(float64(currentLoop-1) / float64(loopCount) * 90) // 0-90%用于循环
-		h.taskService.UpdateTaskProgress(taskID, 5+loopProgress)
+		reporter.Progress(5 + loopProgress)
 
 		// 转发到 WebUI API
-		h.taskService.UpdateTaskProgress(taskID, 5+loopProgress+5)
-		response, err := h.forwardToWebUI(config)
+		reporter.Progress(5 + loopProgress + 5)
+		// 单次生成可能持续很久，等待期间定期刷新心跳
+		stopKeepAlive := service.KeepAlive(reporter)
+		response, err := h.forwardToWebUI(ctx, config)
+		stopKeepAlive()
 		if err != nil {
+			if ctx.Err() != nil {
+				logger.Infof("任务 %s 已取消", taskID)
+				return ctx.Err()
+			}
 			logger.Infof("第 %d 次发包失败: %v", currentLoop, err)
-			h.taskService.UpdateTaskProgress(taskID, 0)
-			h.taskService.UpdateTaskError(taskID, fmt.Sprintf("第 %d 次发包失败: %v", currentLoop, err))
-			h.taskService.UpdateTaskStatus(taskID, "failed")
-			return
+			reporter.Progress(0)
+			return fmt.Errorf("第 %d 次发包失败: %v", currentLoop, err)
 		}
 
 		// response已经是map[string]interface{}类型，直接使用
 		webuiResponse := response
 
 		// 检查是否有图片
-		if images, ok := webuiResponse["images"].([]interface{}); ok && len(images) > 0 {
-			logger.Infof("第 %d 次发包返回了 %d 张图片", currentLoop, len(images))
-
-			// 更新图片统计
-			h.taskService.UpdateTaskImagesFound(taskID, totalImagesGenerated+len(images))
-
-			// 更新进度到当前循环的50%
-			currentLoopProgress := int(float64(currentLoop-1)/float64(loopCount)*90) + int(float64(currentLoop)/float64(loopCount)*50)
-			h.taskService.UpdateTaskProgress(taskID, 5+currentLoopProgress)
-
-			// 下载并保存图片
-			if err := h.downloadAndSaveImagesWithOffset(taskID, images, totalImagesGenerated); err != nil {
-				logger.Infof("第 %d 次发包图片下载失败: %v", currentLoop, err)
-				h.taskService.UpdateTaskProgress(taskID, 0)
-				h.taskService.UpdateTaskError(taskID, fmt.Sprintf("图片下载失败: %v", err))
-				h.taskService.UpdateTaskStatus(taskID, "failed")
-				return
-			}
+		images, ok := webuiResponse["images"].([]interface{})
+		if !ok || len(images) == 0 {
+			logger.Infof("第 %d 次发包未返回图片数据", currentLoop)
+			reporter.Progress(0)
+			return fmt.Errorf("WebUI响应中缺少图片数据")
+		}
 
-			// 更新成功下载的图片统计
-			h.taskService.UpdateTaskImagesDownloaded(taskID, totalImagesGenerated+len(images))
+		logger.Infof("第 %d 次发包返回了 %d 张图片", currentLoop, len(images))
 
-			// 构建图片URL列表
-			for i := range images {
-				imageUrl := fmt.Sprintf("http://localhost:50052/api/tasks/%s/images/%d", taskID, totalImagesGenerated+i+1)
-				allImageUrls = append(allImageUrls, imageUrl)
-			}
-			totalImagesGenerated += len(images)
+		// 更新图片统计
+		reporter.ImagesFound(totalImagesGenerated + len(images))
 
-			// 更新进度到当前循环的80%
-			currentLoopProgress = int(float64(currentLoop-1)/float64(loopCount)*90) + int(float64(currentLoop)/float64(loopCount)*80)
-			h.taskService.UpdateTaskProgress(taskID, 5+currentLoopProgress)
+		// 更新进度到当前循环的50%
+		currentLoopProgress := int(float64(currentLoop-1)/float64(loopCount)*90) + int(float64(currentLoop)/float64(loopCount)*50)
+		reporter.Progress(5 + currentLoopProgress)
 
-			logger.Infof("第 %d 次发包完成，累计生成 %d 张图片", currentLoop, totalImagesGenerated)
-		} else {
-			logger.Infof("第 %d 次发包未返回图片数据", currentLoop)
-			h.taskService.UpdateTaskProgress(taskID, 0)
-			h.taskService.UpdateTaskError(taskID, "WebUI响应中缺少图片数据")
-			h.taskService.UpdateTaskStatus(taskID, "failed")
-			return
+		// 下载并保存图片
+		savedPaths, err := h.downloadAndSaveImagesWithOffset(taskID, images, totalImagesGenerated)
+		if err != nil {
+			logger.Infof("第 %d 次发包图片下载失败: %v", currentLoop, err)
+			reporter.Progress(0)
+			return fmt.Errorf("图片下载失败: %v", err)
+		}
+		for _, savedPath := range savedPaths {
+			reporter.Artifact(savedPath, service.ArtifactRoleImage)
 		}
 
+		// 更新成功下载的图片统计
+		reporter.ImagesDownloaded(totalImagesGenerated + len(images))
+
+		// 构建图片URL列表，并记录生成的图像
+		for i := range images {
+			imageUrl := fmt.Sprintf("http://localhost:50052/api/tasks/%s/images/%d", taskID, totalImagesGenerated+i+1)
+			allImageUrls = append(allImageUrls, imageUrl)
+			reporter.GeneratedImage(&repository.GeneratedImage{
+				ID:       fmt.Sprintf("%s_%d", taskID, totalImagesGenerated+i+1),
+				Prompt:   h.getStringFromMap(config, "prompt"),
+				ImageURL: imageUrl,
+				Model:    h.getStringFromMap(config, "model"),
+			})
+		}
+		totalImagesGenerated += len(images)
+
+		// 更新进度到当前循环的80%
+		currentLoopProgress = int(float64(currentLoop-1)/float64(loopCount)*90) + int(float64(currentLoop)/float64(loopCount)*80)
+		reporter.Progress(5 + currentLoopProgress)
+
+		logger.Infof("第 %d 次发包完成，累计生成 %d 张图片", currentLoop, totalImagesGenerated)
+
 		// 如果不是最后一次循环，等待一下再继续
 		if currentLoop < loopCount {
 			logger.Infof("等待 2 秒后开始下一次发包...")
@@ -138,16 +184,16 @@ func (h *AIHandler) ExecuteGenerateTask(ctx context.Context, taskID string, conf
 		}
 	}
 
-	// 所有循环完成，更新任务状态为完成
+	// 所有循环完成，保存结果（任务状态由 TaskService 设置为完成）
 	taskResult := map[string]interface{}{
 		"images": allImageUrls,
 		"count":  totalImagesGenerated,
 		"loops":  loopCount,
 	}
-	h.taskService.UpdateTaskResult(taskID, taskResult)
-	h.taskService.UpdateTaskProgress(taskID, 100)
-	h.taskService.UpdateTaskStatus(taskID, "completed")
+	reporter.Result(taskResult)
+	reporter.Progress(100)
 	logger.Infof("任务 %s 完成，共执行 %d 次发包，生成 %d 张图片", taskID, loopCount, totalImagesGenerated)
+	return nil
 }
 
 // AIGenerationRequest AI生成请求
@@ -234,11 +280,14 @@ func (h *AIHandler) HandleGenerateWithConfig(w http.ResponseWriter, r *http.Requ
 	configJSON, _ := json.Marshal(taskConfig)
 	task, err := h.taskService.CreateTask("generate", string(configJSON))
 	if err != nil {
+		if sendConfigValidationError(w, err) {
+			return
+		}
 		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to create task", err.Error())
 		return
 	}
 
-	// 不再需要后台处理，task_service 会自动调用 ExecuteGenerateTask
+	// 不再需要后台处理，task_service 会自动调用 generate 执行器
 	h.sendSuccessResponse(w, map[string]interface{}{
 		"task_id": task.ID,
 		"message": "AI生成任务已创建",
@@ -254,7 +303,7 @@ func (h *AIHandler) checkWebUIStatus() bool {
 	}
 
 	// 尝试访问WebUI的API端点
-	resp, err := client.Get("http://127.0.0.1:7860/sdapi/v1/options")
+	resp, err := client.Get(h.webUIURL + "/sdapi/v1/options")
 	if err != nil {
 		return false
 	}
@@ -353,7 +402,7 @@ func (h *AIHandler) loadConfigFromFile(configID string) (*models.GenerationConfi
 }
 
 // forwardToWebUI 转发请求到 WebUI API
-func (h *AIHandler) forwardToWebUI(params map[string]interface{}) (map[string]interface{}, error) {
+func (h *AIHandler) forwardToWebUI(ctx context.Context, params map[string]interface{}) (map[string]interface{}, error) {
 	// 获取并转换参数，确保不是 nil
 	getInt := func(key string, defaultValue int) int {
 		if val, ok := params[key]; ok && val != nil {
@@ -474,13 +523,13 @@ func (h *AIHandler) forwardToWebUI(params map[string]interface{}) (map[string]in
 		return nil, fmt.Errorf("序列化请求失败: %v", err)
 	}
 
-	req, err := http.NewRequest("POST", "http://localhost:7860/sdapi/v1/txt2img", bytes.NewBuffer(requestBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", h.webUIURL+"/sdapi/v1/txt2img", bytes.NewBuffer(requestBody))
 	if err != nil {
 		return nil, fmt.Errorf("创建请求失败: %v", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	// 创建没有超时限制的HTTP客户端
+	// 创建没有超时限制的HTTP客户端，任务取消或超时时通过 ctx 中断请求
 	client := &http.Client{
 		Timeout: 0, // 无超时限制
 	}
@@ -640,15 +689,16 @@ func (h *AIHandler) getBoolFromMap(m map[string]interface{}, key string, default
 
 // downloadAndSaveImages 下载并保存WebUI生成的图片
 func (h *AIHandler) downloadAndSaveImages(taskID string, images []interface{}) error {
-	return h.downloadAndSaveImagesWithOffset(taskID, images, 0)
+	_, err := h.downloadAndSaveImagesWithOffset(taskID, images, 0)
+	return err
 }
 
-// downloadAndSaveImagesWithOffset 下载并保存WebUI生成的图片（带偏移量）
-func (h *AIHandler) downloadAndSaveImagesWithOffset(taskID string, images []interface{}, offset int) error {
+// downloadAndSaveImagesWithOffset 下载并保存WebUI生成的图片（带偏移量），返回保存成功的文件路径
+func (h *AIHandler) downloadAndSaveImagesWithOffset(taskID string, images []interface{}, offset int) ([]string, error) {
 	// 获取路径管理器
 	pathManager := paths.GetPathManager()
 	if pathManager == nil {
-		return fmt.Errorf("路径管理器未初始化")
+		return nil, fmt.Errorf("路径管理器未初始化")
 	}
 
 	// 获取任务信息以生成正确的任务目录名
@@ -665,10 +715,11 @@ func (h *AIHandler) downloadAndSaveImagesWithOffset(taskID string, images []inte
 
 	// 确保目录存在
 	if err := os.MkdirAll(taskDir, 0755); err != nil {
-		return fmt.Errorf("创建任务图片目录失败: %v", err)
+		return nil, fmt.Errorf("创建任务图片目录失败: %v", err)
 	}
 
 	// 下载每张图片
+	savedPaths := make([]string, 0, len(images))
 	for i, imageData := range images {
 		imageStr, ok := imageData.(string)
 		if !ok {
@@ -694,9 +745,10 @@ func (h *AIHandler) downloadAndSaveImagesWithOffset(taskID string, images []inte
 		}
 
 		logger.Infof("图片已保存: %s", filepath)
+		savedPaths = append(savedPaths, filepath)
 	}
 
-	return nil
+	return savedPaths, nil
 }
 
 // decodeBase64Image 解码base64图片数据
@@ -760,7 +812,7 @@ func (h *AIHandler) stopWebUIGeneration() error {
 	}
 
 	// 调用WebUI的interrupt API
-	resp, err := client.Post("http://127.0.0.1:7860/sdapi/v1/interrupt", "application/json", nil)
+	resp, err := client.Post(h.webUIURL+"/sdapi/v1/interrupt", "application/json", nil)
 	if err != nil {
 		return fmt.Errorf("调用WebUI停止API失败: %v", err)
 	}
@@ -55,6 +55,9 @@ func (h *CrawlerHandler) HandleCrawl(w http.ResponseWriter, r *http.Request) {
 	configJSON, _ := json.Marshal(taskConfig)
 	task, err := h.taskService.CreateTask("crawl", string(configJSON))
 	if err != nil {
+		if sendConfigValidationError(w, err) {
+			return
+		}
 		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to create task", err.Error())
 		return
 	}
@@ -3,22 +3,27 @@ package http
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"mime"
 	"net"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"pixiv-tailor/backend/internal/events"
 	"pixiv-tailor/backend/internal/logger"
 	"pixiv-tailor/backend/internal/repository"
 	"pixiv-tailor/backend/internal/service"
+	"pixiv-tailor/backend/internal/tagger"
 	"pixiv-tailor/backend/pkg/paths"
 
 	"github.com/gorilla/mux"
@@ -33,16 +38,15 @@ type HTTPServer struct {
 	SystemService           service.SystemService
 	GenerationConfigService service.GenerationConfigService
 	characterService        *service.CharacterService
+	tagEditService          service.TagEditService
 	router                  *mux.Router
 	upgrader                websocket.Upgrader
 	clients                 map[*websocket.Conn]bool
 	clientsMutex            sync.RWMutex
 	broadcast               chan []byte
 	// WebUI相关字段
-	webUIProcess     *os.Process
-	webUILogChannels []chan string
-	webUIStatus      string
-	webUILogMutex    sync.RWMutex
+	webUIProcess *os.Process
+	webUIStatus  string
 }
 
 // 响应结构
@@ -52,9 +56,10 @@ type APIResponse struct {
 }
 
 type Status struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
-	Details string `json:"details,omitempty"`
+	Code    int                  `json:"code"`
+	Message string               `json:"message"`
+	Details string               `json:"details,omitempty"`
+	Errors  []service.FieldError `json:"errors,omitempty"` // 字段级校验错误
 }
 
 // 生成请求结构
@@ -93,6 +98,10 @@ type TaskStatusResponse struct {
 	Result       []string   `json:"result,omitempty"`
 	CreatedAt    time.Time  `json:"created_at"`
 	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+	Attempt      int        `json:"attempt"`                 // 当前第几次尝试
+	MaxAttempts  int        `json:"max_attempts"`            // 最多尝试次数
+	AttemptText  string     `json:"attempt_text,omitempty"`  // "attempt n/m"
+	NextRetryAt  *time.Time `json:"next_retry_at,omitempty"` // 下一次自动重试的时间
 }
 
 // WebSocket 消息结构
@@ -118,6 +127,7 @@ func NewHTTPServer(
 		SystemService:           systemService,
 		GenerationConfigService: generationConfigService,
 		characterService:        service.NewCharacterService(),
+		tagEditService:          service.NewTagEditService(""),
 		router:                  mux.NewRouter(),
 		upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool {
@@ -128,20 +138,9 @@ func NewHTTPServer(
 		broadcast: make(chan []byte),
 	}
 
-	// 设置任务服务的日志回调，将任务日志广播到WebSocket客户端
-	taskService.SetLogCallback(func(taskID, level, message string) {
-		server.broadcastLogMessage(taskID, level, message)
-	})
-
-	// 设置任务服务的状态回调，将任务状态更新广播到WebSocket客户端
-	taskService.SetStatusCallback(func(taskID, status string, progress int) {
-		server.broadcastTaskUpdate(taskID, status, progress)
-		// 同时发送全局日志
-		server.broadcastGlobalLog("info", fmt.Sprintf("任务 %s 状态更新: %s (进度: %d%%)", taskID, status, progress))
-	})
-
 	server.setupRoutes()
 	server.setupWebSocket()
+	server.subscribeTaskEvents()
 	return server
 }
 
@@ -200,6 +199,7 @@ func (s *HTTPServer) setupRoutes() {
 
 	// 任务图片服务
 	api.HandleFunc("/tasks/{taskId}/images/{imageIndex}", s.handleGetTaskImage).Methods("GET")
+	api.HandleFunc("/tasks/{taskId}/artifacts/{artifactId}", s.handleDownloadTaskArtifact).Methods("GET")
 
 	// 配置管理
 	api.HandleFunc("/config/get", s.handleGetConfig).Methods("POST", "OPTIONS")
@@ -217,9 +217,13 @@ func (s *HTTPServer) setupRoutes() {
 	api.HandleFunc("/tag/files", s.handleListTagFiles).Methods("GET", "OPTIONS")           // 新增：列出标签文件
 	api.HandleFunc("/tag/file/{filename}", s.handleGetTagFile).Methods("GET", "OPTIONS")   // 新增：获取标签文件内容
 	api.HandleFunc("/tag/analyzers", s.handleGetAvailableAnalyzers).Methods("GET", "OPTIONS")
+	api.HandleFunc("/tag/backends", s.handleGetTaggerBackends).Methods("GET", "OPTIONS")
 	api.HandleFunc("/tag/analyze", s.handleAnalyzeImage).Methods("POST", "OPTIONS")
 	api.HandleFunc("/tag/status", s.handleGetTagTaskStatus).Methods("GET", "OPTIONS")
 	api.HandleFunc("/tag/stop", s.handleStopTagTask).Methods("POST", "OPTIONS")
+	api.HandleFunc("/tag/edit", s.handleEditTags).Methods("POST", "OPTIONS")
+	api.HandleFunc("/tag/edit/undo", s.handleUndoTagEdit).Methods("POST", "OPTIONS")
+	api.HandleFunc("/tag/edit/journals", s.handleListTagEditJournals).Methods("GET", "OPTIONS")
 
 	// 角色特征管理
 	api.HandleFunc("/character/extract", s.handleExtractCharacterTags).Methods("POST", "OPTIONS")
@@ -231,8 +235,26 @@ func (s *HTTPServer) setupRoutes() {
 
 	// 任务管理
 	api.HandleFunc("/task/start", s.handleStartTask).Methods("POST", "OPTIONS")
+	api.HandleFunc("/task/resume", s.handleResumeTask).Methods("POST", "OPTIONS")
+	api.HandleFunc("/task/failed-items", s.handleGetTaskFailedItems).Methods("POST", "OPTIONS")
+	api.HandleFunc("/task/retry-failed", s.handleRetryFailedItems).Methods("POST", "OPTIONS")
+	api.HandleFunc("/task/logs", s.handleGetTaskLogs).Methods("POST", "OPTIONS")
+	api.HandleFunc("/task/attempts", s.handleGetTaskAttempts).Methods("POST", "OPTIONS")
+	api.HandleFunc("/task/artifacts", s.handleListTaskArtifacts).Methods("POST", "OPTIONS")
+	api.HandleFunc("/task/labels", s.handleUpdateTaskLabels).Methods("POST", "OPTIONS")
+	api.HandleFunc("/task/labels", s.handleListTaskLabels).Methods("GET")
+	api.HandleFunc("/task/notes", s.handleUpdateTaskNotes).Methods("POST", "OPTIONS")
+	api.HandleFunc("/task/batch", s.handleBatchTasks).Methods("POST", "OPTIONS")
+	api.HandleFunc("/task/batch/create", s.handleBatchCreateTasks).Methods("POST", "OPTIONS")
+	api.HandleFunc("/task/clone", s.handleCloneTask).Methods("POST", "OPTIONS")
+	api.HandleFunc("/task/sweep", s.handleCreateSweep).Methods("POST", "OPTIONS")
+	api.HandleFunc("/task/group", s.handleGetTaskGroup).Methods("POST", "OPTIONS")
 	api.HandleFunc("/task/stop", s.handleStopTask).Methods("POST", "OPTIONS")
 	api.HandleFunc("/task/cleanup", s.handleCleanupTasks).Methods("POST", "OPTIONS")
+	api.HandleFunc("/task/star", s.handleStarTask).Methods("POST", "OPTIONS")
+	api.HandleFunc("/task/retention/preview", s.handlePreviewRetention).Methods("POST", "OPTIONS")
+	api.HandleFunc("/task/retention/apply", s.handleApplyRetention).Methods("POST", "OPTIONS")
+	api.HandleFunc("/task/schema", s.handleGetTaskSchema).Methods("GET", "OPTIONS")
 
 	// 系统信息
 	api.HandleFunc("/system/info", s.handleGetSystemInfo).Methods("POST", "OPTIONS")
@@ -316,6 +338,12 @@ func (s *HTTPServer) handleGetTaskStatus(w http.ResponseWriter, r *http.Request)
 		ErrorMessage: task.ErrorMessage,
 		CreatedAt:    task.CreatedAt,
 		CompletedAt:  nil, // 默认不设置完成时间
+		Attempt:      task.Attempt,
+		MaxAttempts:  task.MaxAttempts,
+		NextRetryAt:  task.NextRetryAt,
+	}
+	if task.Attempt > 0 {
+		response.AttemptText = fmt.Sprintf("attempt %d/%d", task.Attempt, task.MaxAttempts)
 	}
 
 	// 只有当任务完成、失败或取消时才设置完成时间
@@ -364,7 +392,8 @@ func (s *HTTPServer) handleCancelTask(w http.ResponseWriter, r *http.Request) {
 // 删除任务处理器
 func (s *HTTPServer) handleDeleteTask(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		TaskID string `json:"task_id"`
+		TaskID     string `json:"task_id"`
+		PurgeFiles bool   `json:"purge_files"` // 同时删除磁盘上的产出文件，默认只删除记录
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -372,13 +401,16 @@ func (s *HTTPServer) handleDeleteTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := s.TaskService.DeleteTask(req.TaskID)
+	report, err := s.TaskService.DeleteTaskWithOptions(req.TaskID, req.PurgeFiles)
 	if err != nil {
 		s.sendErrorResponse(w, http.StatusInternalServerError, "Failed to delete task", err.Error())
 		return
 	}
 
-	s.sendSuccessResponse(w, map[string]string{"message": "Task deleted successfully"})
+	s.sendSuccessResponse(w, struct {
+		Message string `json:"message"`
+		*service.TaskDeleteReport
+	}{"Task deleted successfully", report})
 }
 
 // 获取任务图片处理器
@@ -457,6 +489,34 @@ func (s *HTTPServer) handleGetTaskImage(w http.ResponseWriter, r *http.Request)
 	http.ServeFile(w, r, imagePath)
 }
 
+// taskFilterRequest 任务筛选条件（任务列表和批量操作共用）
+type taskFilterRequest struct {
+	Status      string   `json:"status"`
+	Type        string   `json:"type"`
+	Labels      []string `json:"labels"`       // 必须同时包含的标签
+	Search      string   `json:"search"`       // 关键字，搜索任务ID、配置内容、备注、错误信息和标签
+	CreatedFrom string   `json:"created_from"` // RFC3339 或 YYYY-MM-DD
+	CreatedTo   string   `json:"created_to"`   // RFC3339 或 YYYY-MM-DD（包含当天）
+	GroupID     string   `json:"group_id"`     // 任务组ID
+}
+
+// toFilter 转换为存储层的筛选条件
+func (f *taskFilterRequest) toFilter() (*repository.TaskFilter, error) {
+	createdFrom, createdTo, err := service.ParseTaskTimeRange(f.CreatedFrom, f.CreatedTo)
+	if err != nil {
+		return nil, err
+	}
+	return &repository.TaskFilter{
+		Status:      f.Status,
+		Type:        f.Type,
+		Labels:      f.Labels,
+		Search:      f.Search,
+		CreatedFrom: createdFrom,
+		CreatedTo:   createdTo,
+		GroupID:     f.GroupID,
+	}, nil
+}
+
 // 获取任务列表处理器
 func (s *HTTPServer) handleGetTasks(w http.ResponseWriter, r *http.Request) {
 	var req struct {
@@ -465,8 +525,9 @@ func (s *HTTPServer) handleGetTasks(w http.ResponseWriter, r *http.Request) {
 			PageSize int `json:"page_size"`
 			Total    int `json:"total"`
 		} `json:"pagination"`
-		Status string `json:"status"`
-		Type   string `json:"type"`
+		taskFilterRequest
+		SortBy    string `json:"sort_by"`    // created_at、updated_at、status、type、progress、priority
+		SortOrder string `json:"sort_order"` // asc 或 desc
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -474,7 +535,14 @@ func (s *HTTPServer) handleGetTasks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tasks, total, err := s.TaskService.ListTasks(int32(req.Pagination.Page), int32(req.Pagination.PageSize), req.Status, req.Type)
+	filter, err := req.toFilter()
+	if err != nil {
+		s.sendErrorResponse(w, http.StatusBadRequest, "Invalid date range", err.Error())
+		return
+	}
+	filter.SortBy = req.SortBy
+	filter.SortOrder = req.SortOrder
+	tasks, total, err := s.TaskService.SearchTasks(filter, int32(req.Pagination.Page), int32(req.Pagination.PageSize))
 	if err != nil {
 		s.sendErrorResponse(w, http.StatusInternalServerError, "Failed to get tasks", err.Error())
 		return
@@ -1078,6 +1146,26 @@ func (s *HTTPServer) setupWebSocket() {
 	}()
 }
 
+// subscribeTaskEvents 订阅任务事件总线，将任务日志和状态更新广播到WebSocket客户端
+func (s *HTTPServer) subscribeTaskEvents() {
+	sub := s.TaskService.EventBus().Subscribe("websocket", events.Filter{
+		Types: []events.EventType{events.TaskStatus, events.TaskLog},
+	}, 1000)
+
+	go func() {
+		for event := range sub.C {
+			switch data := event.Data.(type) {
+			case *events.TaskLogData:
+				s.broadcastLogMessage(event.TaskID, data.Level, data.Message)
+			case *events.TaskStatusData:
+				s.broadcastTaskUpdate(event.TaskID, data.Status, data.Progress)
+				// 同时发送全局日志
+				s.broadcastGlobalLog("info", fmt.Sprintf("任务 %s 状态更新: %s (进度: %d%%)", event.TaskID, data.Status, data.Progress))
+			}
+		}
+	}()
+}
+
 // 广播日志消息
 func (s *HTTPServer) broadcastLogMessage(taskID, level, message string) {
 	msg := WSMessage{
@@ -1184,6 +1272,29 @@ func (s *HTTPServer) sendErrorResponse(w http.ResponseWriter, statusCode int, me
 	json.NewEncoder(w).Encode(response)
 }
 
+// sendConfigValidationError 任务配置校验失败时返回 400 和字段级错误
+// 返回 false 表示 err 不是配置校验错误，调用方应继续按原方式处理
+func sendConfigValidationError(w http.ResponseWriter, err error) bool {
+	var validationErr *service.ConfigValidationError
+	if !errors.As(err, &validationErr) {
+		return false
+	}
+
+	response := APIResponse{
+		Status: Status{
+			Code:    1,
+			Message: "任务配置无效",
+			Details: validationErr.Error(),
+			Errors:  validationErr.Errors,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(response)
+	return true
+}
+
 // 创建爬虫任务处理器
 func (s *HTTPServer) handleCreateCrawlTask(w http.ResponseWriter, r *http.Request) {
 	var req struct {
@@ -1285,6 +1396,9 @@ func (s *HTTPServer) handleCreateCrawlTask(w http.ResponseWriter, r *http.Reques
 	logger.Infof("HTTP: 准备创建爬虫任务, 配置: %s", string(configJSON))
 	task, err := s.TaskService.CreateTask("crawl", string(configJSON))
 	if err != nil {
+		if sendConfigValidationError(w, err) {
+			return
+		}
 		logger.Errorf("HTTP: 创建任务失败: %v", err)
 		s.sendErrorResponse(w, http.StatusInternalServerError, "Failed to create crawl task", err.Error())
 		return
@@ -1330,6 +1444,587 @@ func (s *HTTPServer) handleStartTask(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleResumeTask 从断点恢复任务处理器
+func (s *HTTPServer) handleResumeTask(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TaskID string `json:"task_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.sendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if req.TaskID == "" {
+		s.sendErrorResponse(w, http.StatusBadRequest, "Task ID is required", "")
+		return
+	}
+
+	taskID := req.TaskID
+
+	logger.Infof("HTTP: 恢复任务: %s", taskID)
+	if err := s.TaskService.ResumeTask(taskID); err != nil {
+		logger.Errorf("HTTP: 恢复任务失败: %v", err)
+		s.sendErrorResponse(w, http.StatusInternalServerError, "Failed to resume task", err.Error())
+		return
+	}
+
+	logger.Infof("HTTP: 任务恢复成功: %s", taskID)
+	s.sendSuccessResponse(w, map[string]interface{}{
+		"message": "Task resumed successfully",
+		"task_id": taskID,
+	})
+}
+
+// handleGetTaskFailedItems 获取任务失败条目处理器
+func (s *HTTPServer) handleGetTaskFailedItems(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TaskID string `json:"task_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.sendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if req.TaskID == "" {
+		s.sendErrorResponse(w, http.StatusBadRequest, "Task ID is required", "")
+		return
+	}
+
+	items, err := s.TaskService.GetTaskFailedItems(req.TaskID)
+	if err != nil {
+		s.sendErrorResponse(w, http.StatusInternalServerError, "Failed to get failed items", err.Error())
+		return
+	}
+	if items == nil {
+		items = []*repository.TaskFailedItem{}
+	}
+
+	s.sendSuccessResponse(w, map[string]interface{}{
+		"task_id": req.TaskID,
+		"items":   items,
+		"total":   len(items),
+	})
+}
+
+// handleGetTaskAttempts 获取任务执行历史（每次尝试的结果和错误分类）处理器
+func (s *HTTPServer) handleGetTaskAttempts(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TaskID string `json:"task_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.sendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if req.TaskID == "" {
+		s.sendErrorResponse(w, http.StatusBadRequest, "Task ID is required", "")
+		return
+	}
+
+	task, err := s.TaskService.GetTask(req.TaskID)
+	if err != nil {
+		s.sendErrorResponse(w, http.StatusNotFound, "Task not found", err.Error())
+		return
+	}
+
+	attempts, err := s.TaskService.GetTaskAttempts(req.TaskID)
+	if err != nil {
+		s.sendErrorResponse(w, http.StatusInternalServerError, "Failed to get task attempts", err.Error())
+		return
+	}
+	if attempts == nil {
+		attempts = []*repository.TaskAttempt{}
+	}
+
+	s.sendSuccessResponse(w, map[string]interface{}{
+		"task_id":       req.TaskID,
+		"attempt":       task.Attempt,
+		"max_attempts":  task.MaxAttempts,
+		"next_retry_at": task.NextRetryAt,
+		"attempts":      attempts,
+		"total":         len(attempts),
+	})
+}
+
+// handleUpdateTaskLabels 替换任务标签处理器
+func (s *HTTPServer) handleUpdateTaskLabels(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TaskID string   `json:"task_id"`
+		Labels []string `json:"labels"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.sendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if req.TaskID == "" {
+		s.sendErrorResponse(w, http.StatusBadRequest, "Task ID is required", "")
+		return
+	}
+
+	if err := s.TaskService.UpdateTaskLabels(req.TaskID, req.Labels); err != nil {
+		s.sendErrorResponse(w, http.StatusBadRequest, "Failed to update task labels", err.Error())
+		return
+	}
+
+	task, err := s.TaskService.GetTask(req.TaskID)
+	if err != nil {
+		s.sendErrorResponse(w, http.StatusNotFound, "Task not found", err.Error())
+		return
+	}
+
+	s.sendSuccessResponse(w, task)
+}
+
+// handleListTaskLabels 列出所有任务标签及任务数量处理器
+func (s *HTTPServer) handleListTaskLabels(w http.ResponseWriter, r *http.Request) {
+	labels, err := s.TaskService.ListTaskLabels()
+	if err != nil {
+		s.sendErrorResponse(w, http.StatusInternalServerError, "Failed to list task labels", err.Error())
+		return
+	}
+
+	s.sendSuccessResponse(w, map[string]interface{}{
+		"labels": labels,
+	})
+}
+
+// handleUpdateTaskNotes 更新任务备注处理器
+func (s *HTTPServer) handleUpdateTaskNotes(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TaskID string `json:"task_id"`
+		Notes  string `json:"notes"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.sendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if req.TaskID == "" {
+		s.sendErrorResponse(w, http.StatusBadRequest, "Task ID is required", "")
+		return
+	}
+
+	if err := s.TaskService.UpdateTaskNotes(req.TaskID, req.Notes); err != nil {
+		s.sendErrorResponse(w, http.StatusBadRequest, "Failed to update task notes", err.Error())
+		return
+	}
+
+	task, err := s.TaskService.GetTask(req.TaskID)
+	if err != nil {
+		s.sendErrorResponse(w, http.StatusNotFound, "Task not found", err.Error())
+		return
+	}
+
+	s.sendSuccessResponse(w, task)
+}
+
+// handleBatchCreateTasks 批量创建任务处理器
+// 支持直接传入多个配置（configs），或基础配置加一个变化字段（config + vary），每个值创建一个任务
+func (s *HTTPServer) handleBatchCreateTasks(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Type    string                   `json:"type"`
+		Configs []map[string]interface{} `json:"configs"`
+		Config  map[string]interface{}   `json:"config"`
+		Vary    *struct {
+			Field  string        `json:"field"`
+			Values []interface{} `json:"values"`
+		} `json:"vary"`
+		Priority int `json:"priority"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.sendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if req.Type == "" {
+		s.sendErrorResponse(w, http.StatusBadRequest, "Task type is required", "")
+		return
+	}
+
+	configs := req.Configs
+	if req.Vary != nil {
+		if len(req.Configs) > 0 {
+			s.sendErrorResponse(w, http.StatusBadRequest, "configs and vary cannot be used together", "")
+			return
+		}
+		if req.Vary.Field == "" {
+			s.sendErrorResponse(w, http.StatusBadRequest, "vary.field is required", "")
+			return
+		}
+		configs = make([]map[string]interface{}, 0, len(req.Vary.Values))
+		for _, value := range req.Vary.Values {
+			config := make(map[string]interface{}, len(req.Config)+1)
+			for key, baseValue := range req.Config {
+				config[key] = baseValue
+			}
+			config[req.Vary.Field] = value
+			configs = append(configs, config)
+		}
+	}
+	if len(configs) == 0 {
+		s.sendErrorResponse(w, http.StatusBadRequest, "At least one config is required", "")
+		return
+	}
+
+	configJSONs := make([]string, 0, len(configs))
+	for _, config := range configs {
+		configJSON, err := json.Marshal(config)
+		if err != nil {
+			s.sendErrorResponse(w, http.StatusBadRequest, "Invalid config", err.Error())
+			return
+		}
+		configJSONs = append(configJSONs, string(configJSON))
+	}
+
+	logger.Infof("HTTP: 批量创建 %d 个 %s 任务", len(configJSONs), req.Type)
+	tasks, err := s.TaskService.CreateTasks(req.Type, configJSONs, req.Priority)
+	if err != nil {
+		if sendConfigValidationError(w, err) {
+			return
+		}
+		logger.Errorf("HTTP: 批量创建任务失败: %v", err)
+		s.sendErrorResponse(w, http.StatusInternalServerError, "Failed to create tasks", err.Error())
+		return
+	}
+
+	s.broadcastGlobalLog("info", fmt.Sprintf("已批量创建 %d 个任务 (类型: %s)", len(tasks), req.Type))
+	s.sendSuccessResponse(w, map[string]interface{}{
+		"tasks": tasks,
+		"count": len(tasks),
+	})
+}
+
+// handleListTaskArtifacts 获取任务产出文件清单处理器
+func (s *HTTPServer) handleListTaskArtifacts(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TaskID string `json:"task_id"`
+		Role   string `json:"role"` // image、tag、sidecar，为空时列出全部
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.sendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if req.TaskID == "" {
+		s.sendErrorResponse(w, http.StatusBadRequest, "Task ID is required", "")
+		return
+	}
+
+	artifacts, err := s.TaskService.ListTaskArtifacts(req.TaskID, req.Role)
+	if err != nil {
+		s.sendErrorResponse(w, http.StatusNotFound, "Failed to get task artifacts", err.Error())
+		return
+	}
+	if artifacts == nil {
+		artifacts = []*repository.TaskArtifact{}
+	}
+
+	var totalSize int64
+	for _, artifact := range artifacts {
+		totalSize += artifact.Size
+	}
+	s.sendSuccessResponse(w, map[string]interface{}{
+		"task_id":    req.TaskID,
+		"artifacts":  artifacts,
+		"count":      len(artifacts),
+		"total_size": totalSize,
+	})
+}
+
+// handleDownloadTaskArtifact 下载任务产出文件处理器
+func (s *HTTPServer) handleDownloadTaskArtifact(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	taskID := vars["taskId"]
+	artifactID, err := strconv.ParseInt(vars["artifactId"], 10, 64)
+	if err != nil {
+		s.sendErrorResponse(w, http.StatusBadRequest, "Invalid artifact ID", err.Error())
+		return
+	}
+
+	artifact, path, err := s.TaskService.GetTaskArtifact(taskID, artifactID)
+	if err != nil {
+		s.sendErrorResponse(w, http.StatusNotFound, "Artifact not found", err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", artifact.MimeType)
+	// FormatMediaType 会对非 ASCII 文件名按 RFC 2231 编码
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filepath.Base(path)}))
+	w.Header().Set("X-Artifact-SHA256", artifact.SHA256)
+	http.ServeFile(w, r, path)
+}
+
+// handleCloneTask 复制任务处理器，overrides 中的字段覆盖原配置（值为 null 时删除该字段）
+func (s *HTTPServer) handleCloneTask(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TaskID    string                 `json:"task_id"`
+		Overrides map[string]interface{} `json:"overrides"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.sendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if req.TaskID == "" {
+		s.sendErrorResponse(w, http.StatusBadRequest, "Task ID is required", "")
+		return
+	}
+
+	task, err := s.TaskService.CloneTask(req.TaskID, req.Overrides)
+	if err != nil {
+		if sendConfigValidationError(w, err) {
+			return
+		}
+		logger.Errorf("HTTP: 复制任务失败: %v", err)
+		s.sendErrorResponse(w, http.StatusInternalServerError, "Failed to clone task", err.Error())
+		return
+	}
+
+	s.broadcastGlobalLog("info", fmt.Sprintf("任务 %s 已复制为 %s", req.TaskID, task.ID))
+	s.sendSuccessResponse(w, task)
+}
+
+// handleCreateSweep 参数扫描处理器：按每个字段的取值列表展开配置，每种组合创建一个任务（同一任务组）
+func (s *HTTPServer) handleCreateSweep(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Type     string                   `json:"type"` // 默认 generate
+		Config   map[string]interface{}   `json:"config"`
+		Sweep    map[string][]interface{} `json:"sweep"`
+		Priority int                      `json:"priority"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.sendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if req.Type == "" {
+		req.Type = "generate"
+	}
+	if len(req.Sweep) == 0 {
+		s.sendErrorResponse(w, http.StatusBadRequest, "Sweep fields are required", "")
+		return
+	}
+
+	groupID, tasks, err := s.TaskService.CreateSweep(req.Type, req.Config, req.Sweep, req.Priority)
+	if err != nil {
+		if sendConfigValidationError(w, err) {
+			return
+		}
+		logger.Errorf("HTTP: 参数扫描创建任务失败: %v", err)
+		s.sendErrorResponse(w, http.StatusInternalServerError, "Failed to create sweep", err.Error())
+		return
+	}
+
+	s.broadcastGlobalLog("info", fmt.Sprintf("参数扫描已创建任务组 %s: %d 个任务 (类型: %s)", groupID, len(tasks), req.Type))
+	s.sendSuccessResponse(w, map[string]interface{}{
+		"group_id": groupID,
+		"tasks":    tasks,
+		"count":    len(tasks),
+	})
+}
+
+// handleGetTaskGroup 获取任务组汇总进度处理器
+func (s *HTTPServer) handleGetTaskGroup(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		GroupID string `json:"group_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.sendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if req.GroupID == "" {
+		s.sendErrorResponse(w, http.StatusBadRequest, "Group ID is required", "")
+		return
+	}
+
+	group, err := s.TaskService.GetTaskGroup(req.GroupID)
+	if err != nil {
+		s.sendErrorResponse(w, http.StatusNotFound, "Task group not found", err.Error())
+		return
+	}
+
+	s.sendSuccessResponse(w, group)
+}
+
+// handleBatchTasks 批量启动、恢复、停止、取消、删除任务或调整优先级
+// 通过 task_ids 指定任务，或通过 filter 按条件选择任务（筛选条件不能为空）
+func (s *HTTPServer) handleBatchTasks(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Action     string             `json:"action"` // start、resume、stop、cancel、delete、prioritize
+		TaskIDs    []string           `json:"task_ids"`
+		Filter     *taskFilterRequest `json:"filter"`
+		Priority   int                `json:"priority"`    // prioritize 时使用
+		PurgeFiles bool               `json:"purge_files"` // delete 时同时删除产出文件，默认保留
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.sendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if req.Action == "" {
+		s.sendErrorResponse(w, http.StatusBadRequest, "Action is required", "")
+		return
+	}
+	if (len(req.TaskIDs) > 0) == (req.Filter != nil) {
+		s.sendErrorResponse(w, http.StatusBadRequest, "Exactly one of task_ids or filter is required", "")
+		return
+	}
+
+	taskIDs := req.TaskIDs
+	if req.Filter != nil {
+		filter, err := req.Filter.toFilter()
+		if err != nil {
+			s.sendErrorResponse(w, http.StatusBadRequest, "Invalid date range", err.Error())
+			return
+		}
+		taskIDs, err = s.TaskService.FindTaskIDs(filter)
+		if err != nil {
+			s.sendErrorResponse(w, http.StatusBadRequest, "Invalid filter", err.Error())
+			return
+		}
+	}
+
+	logger.Infof("HTTP: 批量操作 %s, %d 个任务", req.Action, len(taskIDs))
+	results, err := s.TaskService.BatchTasks(req.Action, taskIDs, req.Priority, req.PurgeFiles)
+	if err != nil {
+		s.sendErrorResponse(w, http.StatusBadRequest, "Failed to run batch operation", err.Error())
+		return
+	}
+
+	succeeded := 0
+	for _, result := range results {
+		if result.Success {
+			succeeded++
+		}
+	}
+	s.sendSuccessResponse(w, map[string]interface{}{
+		"action":    req.Action,
+		"total":     len(results),
+		"succeeded": succeeded,
+		"failed":    len(results) - succeeded,
+		"results":   results,
+	})
+}
+
+// handleRetryFailedItems 创建只重试失败条目的新任务处理器
+func (s *HTTPServer) handleRetryFailedItems(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TaskID string `json:"task_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.sendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if req.TaskID == "" {
+		s.sendErrorResponse(w, http.StatusBadRequest, "Task ID is required", "")
+		return
+	}
+
+	logger.Infof("HTTP: 重试任务失败条目: %s", req.TaskID)
+	task, err := s.TaskService.RetryFailedItems(req.TaskID)
+	if err != nil {
+		logger.Errorf("HTTP: 重试失败条目失败: %v", err)
+		s.sendErrorResponse(w, http.StatusInternalServerError, "Failed to retry failed items", err.Error())
+		return
+	}
+
+	s.sendSuccessResponse(w, map[string]interface{}{
+		"message":  "Retry task created successfully",
+		"task_id":  task.ID,
+		"retry_of": req.TaskID,
+	})
+}
+
+// handleGetTaskLogs 分页获取任务历史日志处理器
+func (s *HTTPServer) handleGetTaskLogs(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TaskID     string `json:"task_id"`
+		Level      string `json:"level"` // 按级别筛选 (info, warning, error)，为空表示全部
+		Pagination struct {
+			Page     int `json:"page"`
+			PageSize int `json:"page_size"`
+			Total    int `json:"total"`
+		} `json:"pagination"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.sendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if req.TaskID == "" {
+		s.sendErrorResponse(w, http.StatusBadRequest, "Task ID is required", "")
+		return
+	}
+
+	if req.Pagination.Page <= 0 {
+		req.Pagination.Page = 1
+	}
+	if req.Pagination.PageSize <= 0 {
+		req.Pagination.PageSize = 100
+	}
+
+	logs, total, err := s.TaskService.GetTaskLogs(req.TaskID, req.Level, int32(req.Pagination.Page), int32(req.Pagination.PageSize))
+	if err != nil {
+		s.sendErrorResponse(w, http.StatusInternalServerError, "Failed to get task logs", err.Error())
+		return
+	}
+
+	// 确保logs字段始终是数组而不是null
+	if logs == nil {
+		logs = []*repository.TaskLog{}
+	}
+
+	s.sendSuccessResponse(w, map[string]interface{}{
+		"task_id": req.TaskID,
+		"logs":    logs,
+		"pagination": map[string]interface{}{
+			"page":      req.Pagination.Page,
+			"page_size": req.Pagination.PageSize,
+			"total":     total,
+		},
+	})
+}
+
+// handleGetTaskSchema 获取任务配置 Schema（?type=crawl 获取单个类型，不指定则返回全部）
+func (s *HTTPServer) handleGetTaskSchema(w http.ResponseWriter, r *http.Request) {
+	schemas := s.TaskService.GetConfigSchemas()
+
+	taskType := r.URL.Query().Get("type")
+	if taskType == "" {
+		s.sendSuccessResponse(w, map[string]interface{}{
+			"schemas": schemas,
+		})
+		return
+	}
+
+	schema, exists := schemas[taskType]
+	if !exists {
+		s.sendErrorResponse(w, http.StatusNotFound, "Unknown task type", fmt.Sprintf("不支持的任务类型: %s", taskType))
+		return
+	}
+
+	s.sendSuccessResponse(w, map[string]interface{}{
+		"type":   taskType,
+		"schema": schema,
+	})
+}
+
 // handleStopTask 停止任务处理器
 func (s *HTTPServer) handleStopTask(w http.ResponseWriter, r *http.Request) {
 	var req struct {
@@ -1383,6 +2078,77 @@ func (s *HTTPServer) handleStopTask(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleStarTask 设置任务星标处理器（加星标的任务可在保留策略中免于清理）
+func (s *HTTPServer) handleStarTask(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TaskID  string `json:"task_id"`
+		Starred bool   `json:"starred"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.sendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	if req.TaskID == "" {
+		s.sendErrorResponse(w, http.StatusBadRequest, "Task ID is required", "")
+		return
+	}
+
+	if err := s.TaskService.SetTaskStarred(req.TaskID, req.Starred); err != nil {
+		s.sendErrorResponse(w, http.StatusInternalServerError, "Failed to update task star", err.Error())
+		return
+	}
+
+	task, err := s.TaskService.GetTask(req.TaskID)
+	if err != nil {
+		s.sendErrorResponse(w, http.StatusInternalServerError, "Failed to get task", err.Error())
+		return
+	}
+	s.sendSuccessResponse(w, task)
+}
+
+// handlePreviewRetention 预览保留策略将清理的任务（不删除任何内容）
+func (s *HTTPServer) handlePreviewRetention(w http.ResponseWriter, r *http.Request) {
+	s.runRetention(w, r, true)
+}
+
+// handleApplyRetention 按保留策略立即清理任务及其产出文件
+func (s *HTTPServer) handleApplyRetention(w http.ResponseWriter, r *http.Request) {
+	s.runRetention(w, r, false)
+}
+
+// runRetention 执行或预览保留策略，请求中未提供策略时使用已保存的策略
+func (s *HTTPServer) runRetention(w http.ResponseWriter, r *http.Request, dryRun bool) {
+	var req struct {
+		Policy *service.RetentionPolicy `json:"policy"`
+	}
+
+	// 请求体可以为空
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		s.sendErrorResponse(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+	if req.Policy != nil {
+		// 未提供的字段使用默认值
+		policy := service.DefaultRetentionPolicy()
+		policyJSON, _ := json.Marshal(req.Policy)
+		json.Unmarshal(policyJSON, policy)
+		req.Policy = policy
+	}
+
+	report, err := s.TaskService.RunRetention(req.Policy, dryRun)
+	if err != nil {
+		s.sendErrorResponse(w, http.StatusBadRequest, "Failed to run retention policy", err.Error())
+		return
+	}
+
+	if !dryRun && report.Deleted > 0 {
+		s.broadcastGlobalLog("info", fmt.Sprintf("保留策略清理了 %d 个任务", report.Deleted))
+	}
+	s.sendSuccessResponse(w, report)
+}
+
 // handleCleanupTasks 清理任务处理器
 func (s *HTTPServer) handleCleanupTasks(w http.ResponseWriter, r *http.Request) {
 	var req struct {
@@ -1599,11 +2365,10 @@ func (s *HTTPServer) handleWebUILogs(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
 	w.Header().Set("Access-Control-Allow-Credentials", "true")
 
-	// 创建日志流
-	logChan := make(chan string, 100)
-	s.webUILogMutex.Lock()
-	s.webUILogChannels = append(s.webUILogChannels, logChan)
-	s.webUILogMutex.Unlock()
+	// 创建日志流（订阅事件总线中的 WebUI 日志）
+	sub := s.TaskService.EventBus().Subscribe("webui-logs", events.Filter{
+		Types: []events.EventType{events.WebUILog},
+	}, 100)
 
 	// 发送初始状态
 	fmt.Fprintf(w, "data: %s\n\n", "WebUI日志流已连接")
@@ -1622,18 +2387,8 @@ func (s *HTTPServer) handleWebUILogs(w http.ResponseWriter, r *http.Request) {
 
 	// 简化的日志流 - 只发送状态更新
 	go func() {
-		defer func() {
-			// 清理通道
-			s.webUILogMutex.Lock()
-			for i, ch := range s.webUILogChannels {
-				if ch == logChan {
-					s.webUILogChannels = append(s.webUILogChannels[:i], s.webUILogChannels[i+1:]...)
-					break
-				}
-			}
-			s.webUILogMutex.Unlock()
-			close(logChan)
-		}()
+		// 取消订阅
+		defer sub.Unsubscribe()
 
 		// 监听客户端断开连接
 		ctx := r.Context()
@@ -1683,11 +2438,16 @@ func (s *HTTPServer) handleWebUILogs(w http.ResponseWriter, r *http.Request) {
 
 		for {
 			select {
-			case log, ok := <-logChan:
+			case event, ok := <-sub.C:
 				if !ok {
 					return
 				}
-				if !safeWrite(log) {
+				data, isWebUILog := event.Data.(*events.WebUILogData)
+				if !isWebUILog {
+					continue
+				}
+				logMessage := fmt.Sprintf("[%s] %s", event.Time.Format("15:04:05"), data.Message)
+				if !safeWrite(logMessage) {
 					return
 				}
 			case <-ticker.C:
@@ -1993,32 +2753,11 @@ func (s *HTTPServer) checkWebUIAPI() bool {
 
 // broadcastWebUILog 广播WebUI日志
 func (s *HTTPServer) broadcastWebUILog(message string) {
-	timestamp := time.Now().Format("15:04:05")
-	logMessage := fmt.Sprintf("[%s] %s", timestamp, message)
-
-	// 发送到所有日志通道
-	s.webUILogMutex.RLock()
-	channels := make([]chan string, len(s.webUILogChannels))
-	copy(channels, s.webUILogChannels)
-	s.webUILogMutex.RUnlock()
-
-	for _, ch := range channels {
-		select {
-		case ch <- logMessage:
-			// 成功发送
-		default:
-			// 通道已满，移除该通道
-			s.webUILogMutex.Lock()
-			for j, originalCh := range s.webUILogChannels {
-				if originalCh == ch {
-					s.webUILogChannels = append(s.webUILogChannels[:j], s.webUILogChannels[j+1:]...)
-					break
-				}
-			}
-			s.webUILogMutex.Unlock()
-			close(ch)
-		}
-	}
+	// 发布到事件总线，由各日志流订阅者接收（缓冲区已满的订阅者会丢弃该条日志）
+	s.TaskService.EventBus().Publish(&events.Event{
+		Type: events.WebUILog,
+		Data: &events.WebUILogData{Message: message},
+	})
 }
 
 // 启动服务器
@@ -2084,14 +2823,29 @@ func (s *HTTPServer) StopWebUI() {
 // handleCreateTagTask 创建标签任务
 func (s *HTTPServer) handleCreateTagTask(w http.ResponseWriter, r *http.Request) {
 	var request struct {
-		InputDir   interface{} `json:"input_dir"` // 可以是字符串或字符串数组
-		OutputDir  string      `json:"output_dir"`
-		Analyzer   string      `json:"analyzer"`
-		SkipTags   []string    `json:"skip_tags"`
-		ExtendTags []string    `json:"extend_tags"`
-		TagOrder   string      `json:"tag_order"`
-		SaveType   string      `json:"save_type"`
-		Limit      int         `json:"limit"`
+		InputDir           interface{}        `json:"input_dir"` // 可以是字符串或字符串数组
+		OutputDir          string             `json:"output_dir"`
+		Analyzer           string             `json:"analyzer"`
+		SkipTags           []string           `json:"skip_tags"`
+		ExtendTags         []string           `json:"extend_tags"`
+		ExtendPosition     string             `json:"extend_position"` // append 或 prepend
+		Threshold          float64            `json:"threshold"`
+		CharacterThreshold float64            `json:"character_threshold"`
+		CategoryThresholds map[string]float64 `json:"category_thresholds"`
+		TagOrder           string             `json:"tag_order"`
+		SaveType           string             `json:"save_type"`
+		Limit              int                `json:"limit"`
+		Concurrency        int                `json:"concurrency"`
+		Force              bool               `json:"force"`
+		OutputMode         string             `json:"output_mode"`
+		DatasetRepeats     int                `json:"dataset_repeats"`
+		DatasetConcept     string             `json:"dataset_concept"`
+		ReplaceUnderscore  bool               `json:"replace_underscore"`
+		EscapeParentheses  bool               `json:"escape_parentheses"`
+		Caption            bool               `json:"caption"`
+		CaptionBackend     string             `json:"caption_backend"`
+		CaptionFile        bool               `json:"caption_file"`
+		CaptionTemplate    string             `json:"caption_template"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
@@ -2127,10 +2881,7 @@ func (s *HTTPServer) handleCreateTagTask(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// 设置默认值
-	if request.Analyzer == "" {
-		request.Analyzer = "wd14tagger"
-	}
+	// 设置默认值（未指定分析器时使用配置中的默认标签后端）
 	if request.TagOrder == "" {
 		request.TagOrder = "score"
 	}
@@ -2143,21 +2894,41 @@ func (s *HTTPServer) handleCreateTagTask(w http.ResponseWriter, r *http.Request)
 
 	// 创建任务配置（使用转换后的 inputDirs）
 	config := map[string]interface{}{
-		"type":        "tag",
-		"input_dir":   inputDirs,
-		"output_dir":  request.OutputDir,
-		"analyzer":    request.Analyzer,
-		"skip_tags":   request.SkipTags,
-		"extend_tags": request.ExtendTags,
-		"tag_order":   request.TagOrder,
-		"save_type":   request.SaveType,
-		"limit":       request.Limit,
+		"type":                "tag",
+		"input_dir":           inputDirs,
+		"output_dir":          request.OutputDir,
+		"skip_tags":           request.SkipTags,
+		"extend_tags":         request.ExtendTags,
+		"extend_position":     request.ExtendPosition,
+		"tag_order":           request.TagOrder,
+		"threshold":           request.Threshold,
+		"character_threshold": request.CharacterThreshold,
+		"category_thresholds": request.CategoryThresholds,
+		"save_type":           request.SaveType,
+		"limit":               request.Limit,
+		"concurrency":         request.Concurrency,
+		"force":               request.Force,
+		"output_mode":         request.OutputMode,
+		"dataset_repeats":     request.DatasetRepeats,
+		"dataset_concept":     request.DatasetConcept,
+		"replace_underscore":  request.ReplaceUnderscore,
+		"escape_parentheses":  request.EscapeParentheses,
+		"caption":             request.Caption,
+		"caption_backend":     request.CaptionBackend,
+		"caption_file":        request.CaptionFile,
+		"caption_template":    request.CaptionTemplate,
+	}
+	if request.Analyzer != "" {
+		config["analyzer"] = request.Analyzer
 	}
 
 	// 创建任务
 	configJSON, _ := json.Marshal(config)
 	task, err := s.TaskService.CreateTask("tag", string(configJSON))
 	if err != nil {
+		if sendConfigValidationError(w, err) {
+			return
+		}
 		s.sendErrorResponse(w, http.StatusInternalServerError, "创建任务失败", err.Error())
 		return
 	}
@@ -2331,13 +3102,14 @@ func (s *HTTPServer) handleDeleteTaggedImages(w http.ResponseWriter, r *http.Req
 	})
 }
 
-// handleGetAvailableAnalyzers 获取可用的分析器
+// handleGetAvailableAnalyzers 获取可用的分析器（已注册的标签后端名称）
 func (s *HTTPServer) handleGetAvailableAnalyzers(w http.ResponseWriter, r *http.Request) {
-	analyzers := []string{
-		"wd14tagger",
-	}
+	s.sendSuccessResponse(w, tagger.TaggerNames())
+}
 
-	s.sendSuccessResponse(w, analyzers)
+// handleGetTaggerBackends 获取已注册的标签后端及其能力
+func (s *HTTPServer) handleGetTaggerBackends(w http.ResponseWriter, r *http.Request) {
+	s.sendSuccessResponse(w, tagger.AvailableTaggers())
 }
 
 // handleAnalyzeImage 分析单张图像
@@ -0,0 +1,57 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"pixiv-tailor/backend/internal/service"
+)
+
+// handleEditTags 批量编辑标签目录中的标签文件
+func (s *HTTPServer) handleEditTags(w http.ResponseWriter, r *http.Request) {
+	var request service.TagEditRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		s.sendErrorResponse(w, http.StatusBadRequest, "解析请求失败", err.Error())
+		return
+	}
+
+	result, err := s.tagEditService.Edit(&request)
+	if err != nil {
+		s.sendErrorResponse(w, http.StatusBadRequest, "编辑标签失败", err.Error())
+		return
+	}
+
+	s.sendSuccessResponse(w, result)
+}
+
+// handleUndoTagEdit 撤销标签编辑（未指定 journal_id 时撤销最近一次）
+func (s *HTTPServer) handleUndoTagEdit(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		JournalID string `json:"journal_id"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			s.sendErrorResponse(w, http.StatusBadRequest, "解析请求失败", err.Error())
+			return
+		}
+	}
+
+	result, err := s.tagEditService.Undo(request.JournalID)
+	if err != nil {
+		s.sendErrorResponse(w, http.StatusBadRequest, "撤销标签编辑失败", err.Error())
+		return
+	}
+
+	s.sendSuccessResponse(w, result)
+}
+
+// handleListTagEditJournals 列出标签编辑日志
+func (s *HTTPServer) handleListTagEditJournals(w http.ResponseWriter, r *http.Request) {
+	journals, err := s.tagEditService.ListJournals()
+	if err != nil {
+		s.sendErrorResponse(w, http.StatusInternalServerError, "获取标签编辑日志失败", err.Error())
+		return
+	}
+
+	s.sendSuccessResponse(w, journals)
+}
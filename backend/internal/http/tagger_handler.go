@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"pixiv-tailor/backend/internal/service"
+	"pixiv-tailor/backend/internal/tagger"
 	"pixiv-tailor/backend/pkg/models"
 )
 
@@ -47,13 +48,7 @@ func (h *TaggerHandler) CreateTagTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 设置默认值
-	if request.Analyzer == "" {
-		request.Analyzer = "wd14tagger"
-	}
-	if request.TagOrder == "" {
-		request.TagOrder = "score"
-	}
+	// 设置默认值（未指定分析器、排序方式时使用配置中的 wd14tagger 设置）
 	if request.SaveType == "" {
 		request.SaveType = "txt"
 	}
@@ -63,21 +58,41 @@ func (h *TaggerHandler) CreateTagTask(w http.ResponseWriter, r *http.Request) {
 
 	// 创建任务配置
 	config := map[string]interface{}{
-		"type":        "tag",
-		"input_dir":   request.InputDir,
-		"output_dir":  request.OutputDir,
-		"analyzer":    request.Analyzer,
-		"skip_tags":   request.SkipTags,
-		"extend_tags": request.ExtendTags,
-		"tag_order":   request.TagOrder,
-		"save_type":   request.SaveType,
-		"limit":       request.Limit,
+		"type":                "tag",
+		"input_dir":           request.InputDir,
+		"output_dir":          request.OutputDir,
+		"skip_tags":           request.SkipTags,
+		"extend_tags":         request.ExtendTags,
+		"extend_position":     request.ExtendPosition,
+		"tag_order":           request.TagOrder,
+		"threshold":           request.Threshold,
+		"character_threshold": request.CharacterThreshold,
+		"category_thresholds": request.CategoryThresholds,
+		"save_type":           request.SaveType,
+		"limit":               request.Limit,
+		"concurrency":         request.Concurrency,
+		"force":               request.Force,
+		"output_mode":         request.OutputMode,
+		"dataset_repeats":     request.DatasetRepeats,
+		"dataset_concept":     request.DatasetConcept,
+		"replace_underscore":  request.ReplaceUnderscore,
+		"escape_parentheses":  request.EscapeParentheses,
+		"caption":             request.Caption,
+		"caption_backend":     request.CaptionBackend,
+		"caption_file":        request.CaptionFile,
+		"caption_template":    request.CaptionTemplate,
+	}
+	if request.Analyzer != "" {
+		config["analyzer"] = request.Analyzer
 	}
 
 	// 创建任务
 	configJSON, _ := json.Marshal(config)
 	task, err := h.taskService.CreateTask("tag", string(configJSON))
 	if err != nil {
+		if sendConfigValidationError(w, err) {
+			return
+		}
 		http.Error(w, fmt.Sprintf("创建任务失败: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -202,10 +217,7 @@ func (h *TaggerHandler) GetTaggedImages(w http.ResponseWriter, r *http.Request)
 
 // GetAvailableAnalyzers 获取可用的分析器
 func (h *TaggerHandler) GetAvailableAnalyzers(w http.ResponseWriter, r *http.Request) {
-	analyzers := []string{
-		"wd14tagger",
-		"deepdanbooru",
-	}
+	analyzers := tagger.TaggerNames()
 
 	response := map[string]interface{}{
 		"status": map[string]interface{}{
@@ -3,6 +3,7 @@ package repository
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -16,12 +17,24 @@ type Storage interface {
 	UpdateTaskStatus(id, status string) error
 	UpdateTaskProgress(id string, progress int) error
 	UpdateTaskError(id, errorMsg string) error
+	UpdateTaskHeartbeat(id string, heartbeatAt time.Time) error
+	UpdateTaskAttempt(id string, attempt, maxAttempts int) error
+	UpdateTaskNextRetry(id string, nextRetryAt *time.Time) error
+	ListDueRetryTasks(now time.Time) ([]*Task, error)
 	UpdateTaskImagesFound(id string, count int) error
 	UpdateTaskImagesDownloaded(id string, count int) error
 	UpdateTaskResult(id string, result string) error
 	ListTasks(status, taskType string, limit, offset int) ([]*Task, error)
 	CountTasks(status, taskType string) (int, error)
+	SearchTasks(filter *TaskFilter, limit, offset int) ([]*Task, error)
+	CountTasksByFilter(filter *TaskFilter) (int, error)
+	SetTaskLabels(id string, labels []string) error
+	UpdateTaskNotes(id, notes string) error
+	UpdateTaskPriority(id string, priority int) error
+	UpdateTaskStarred(id string, starred bool) error
+	ListTaskLabels() (map[string]int, error)
 	DeleteTask(id string) error
+	DeleteTaskRecords(id string) (*TaskDeleteResult, error)
 	CleanupTasksByStatus(status string) (int, error)
 	CleanupAllTasks() (int, error)
 	GetConfig(module string) (string, error)
@@ -35,26 +48,107 @@ type Storage interface {
 	AddGeneratedImage(image *GeneratedImage) error
 	GetTrainedModels(limit, offset int) ([]*TrainedModel, error)
 	CountTrainedModels() (int, error)
+	AddTaskCheckpoint(taskID, item string) error
+	GetTaskCheckpoints(taskID string) ([]string, error)
+	ClearTaskCheckpoints(taskID string) error
+	AddTaskFailedItem(item *TaskFailedItem) error
+	GetTaskFailedItems(taskID string) ([]*TaskFailedItem, error)
+	RemoveTaskFailedItem(taskID, item string) error
+	ClearTaskFailedItems(taskID string) error
+	AddTaskLog(log *TaskLog) error
+	ListTaskLogs(taskID, level string, limit, offset int) ([]*TaskLog, error)
+	CountTaskLogs(taskID, level string) (int, error)
+	AddTaskAttempt(attempt *TaskAttempt) error
+	ListTaskAttempts(taskID string) ([]*TaskAttempt, error)
+	AddTaskArtifact(artifact *TaskArtifact) error
+	ListTaskArtifacts(taskID, role string) ([]*TaskArtifact, error)
+	GetTaskArtifact(taskID string, id int64) (*TaskArtifact, error)
+	CountArtifactsByPath(path, excludeTaskID string) (int, error)
 }
 
 // Task 任务结构
 type Task struct {
-	ID               string    `json:"id"`
-	Type             string    `json:"type"`
-	Status           string    `json:"status"`
-	Config           string    `json:"config"`
-	Progress         int       `json:"progress"`
-	ErrorMessage     string    `json:"error_message"`
-	Result           string    `json:"result"`            // 任务结果（JSON格式）
-	ImagesFound      int       `json:"images_found"`      // 获取到的图片数量
-	ImagesDownloaded int       `json:"images_downloaded"` // 下载的图片数量
-	CreatedAt        time.Time `json:"created_at"`
-	UpdatedAt        time.Time `json:"updated_at"`
+	ID               string     `json:"id"`
+	Type             string     `json:"type"`
+	Status           string     `json:"status"`
+	Config           string     `json:"config"`
+	Progress         int        `json:"progress"`
+	ErrorMessage     string     `json:"error_message"`
+	Result           string     `json:"result"`                  // 任务结果（JSON格式）
+	ImagesFound      int        `json:"images_found"`            // 获取到的图片数量
+	ImagesDownloaded int        `json:"images_downloaded"`       // 下载的图片数量
+	HeartbeatAt      *time.Time `json:"heartbeat_at,omitempty"`  // 执行器最近一次上报的时间
+	Attempt          int        `json:"attempt"`                 // 当前第几次尝试（从 1 开始，未执行过为 0）
+	MaxAttempts      int        `json:"max_attempts"`            // 最多尝试次数（包括第一次）
+	NextRetryAt      *time.Time `json:"next_retry_at,omitempty"` // 下一次自动重试的时间
+	Labels           []string   `json:"labels"`                  // 标签
+	Notes            string     `json:"notes"`                   // 备注
+	Priority         int        `json:"priority"`                // 优先级，数值越大越先从等待队列中启动
+	GroupID          string     `json:"group_id,omitempty"`      // 任务组ID（参数扫描创建的任务属于同一组）
+	Starred          bool       `json:"starred"`                 // 加星标的任务可在保留策略中免于清理
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// TaskFailedItem 任务中处理失败的条目
+type TaskFailedItem struct {
+	TaskID    string    `json:"task_id"`
+	Item      string    `json:"item"` // 条目标识（爬虫为图片URL，标签任务为图片路径）
+	Name      string    `json:"name"` // 条目名称（爬虫为保存的文件名，标签任务为图片文件名）
+	Error     string    `json:"error"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TaskFilter 任务查询条件（零值字段表示不筛选）
+type TaskFilter struct {
+	Status      string
+	Type        string
+	Labels      []string   // 必须同时包含的标签
+	Search      string     // 关键字（空格分隔，全部匹配），搜索任务ID、配置内容、备注、错误信息和标签
+	CreatedFrom *time.Time // 创建时间下限（包含）
+	CreatedTo   *time.Time // 创建时间上限（不包含）
+	GroupID     string     // 任务组ID
+	SortBy      string     // 排序字段：created_at、updated_at、status、type、progress、priority
+	SortOrder   string     // asc 或 desc，默认 desc
+}
+
+// TaskAttempt 任务的一次执行记录
+type TaskAttempt struct {
+	ID         int64     `json:"id"`
+	TaskID     string    `json:"task_id"`
+	Attempt    int       `json:"attempt"`
+	Status     string    `json:"status"` // 本次执行的结果：completed、failed、cancelled
+	Error      string    `json:"error,omitempty"`
+	ErrorClass string    `json:"error_class,omitempty"` // 错误分类（network、timeout 等），用于判断是否自动重试
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// TaskArtifact 任务产出的文件
+type TaskArtifact struct {
+	ID        int64     `json:"id"`
+	TaskID    string    `json:"task_id"`
+	Path      string    `json:"path"` // 相对于数据目录的路径（使用 / 分隔），数据目录之外的文件为绝对路径
+	Size      int64     `json:"size"`
+	SHA256    string    `json:"sha256"`
+	MimeType  string    `json:"mime_type"`
+	Role      string    `json:"role"` // 文件用途：image、tag、sidecar
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TaskLog 任务日志
+type TaskLog struct {
+	ID        int64     `json:"id"`
+	TaskID    string    `json:"task_id"`
+	Level     string    `json:"level"`
+	Message   string    `json:"message"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 // CrawlResult 爬取结果结构
 type CrawlResult struct {
 	ID        string    `json:"id"`
+	TaskID    string    `json:"task_id,omitempty"` // 产生该结果的任务
 	URL       string    `json:"url"`
 	Title     string    `json:"title"`
 	Author    string    `json:"author"`
@@ -66,12 +160,26 @@ type CrawlResult struct {
 // GeneratedImage 生成图像结构
 type GeneratedImage struct {
 	ID        string    `json:"id"`
+	TaskID    string    `json:"task_id,omitempty"` // 产生该图像的任务
 	Prompt    string    `json:"prompt"`
 	ImageURL  string    `json:"image_url"`
 	Model     string    `json:"model"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// TaskDeleteResult 删除任务时从各表删除的记录数量
+type TaskDeleteResult struct {
+	Tasks           int64 `json:"tasks"`
+	Checkpoints     int64 `json:"checkpoints"`
+	FailedItems     int64 `json:"failed_items"`
+	Logs            int64 `json:"logs"`
+	Attempts        int64 `json:"attempts"`
+	Artifacts       int64 `json:"artifacts"`
+	Labels          int64 `json:"labels"`
+	CrawlResults    int64 `json:"crawl_results"`
+	GeneratedImages int64 `json:"generated_images"`
+}
+
 // TrainedModel 训练模型结构
 type TrainedModel struct {
 	ID        string    `json:"id"`
@@ -114,11 +222,6 @@ func (s *SQLiteStorage) GetDB() *sql.DB {
 
 // initTables 初始化数据库表
 func (s *SQLiteStorage) initTables() error {
-	// 先检查并添加新字段（数据库迁移）
-	if err := s.migrateTables(); err != nil {
-		return fmt.Errorf("数据库迁移失败: %v", err)
-	}
-
 	queries := []string{
 		`CREATE TABLE IF NOT EXISTS tasks (
 			id TEXT PRIMARY KEY,
@@ -130,6 +233,14 @@ func (s *SQLiteStorage) initTables() error {
 			result TEXT,
 			images_found INTEGER DEFAULT 0,
 			images_downloaded INTEGER DEFAULT 0,
+			heartbeat_at DATETIME,
+			attempt INTEGER DEFAULT 0,
+			max_attempts INTEGER DEFAULT 1,
+			next_retry_at DATETIME,
+			notes TEXT,
+			priority INTEGER DEFAULT 0,
+			group_id TEXT,
+			starred INTEGER DEFAULT 0,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 		)`,
@@ -140,6 +251,7 @@ func (s *SQLiteStorage) initTables() error {
 		)`,
 		`CREATE TABLE IF NOT EXISTS crawl_results (
 			id TEXT PRIMARY KEY,
+			task_id TEXT,
 			url TEXT,
 			title TEXT,
 			author TEXT,
@@ -149,6 +261,7 @@ func (s *SQLiteStorage) initTables() error {
 		)`,
 		`CREATE TABLE IF NOT EXISTS generated_images (
 			id TEXT PRIMARY KEY,
+			task_id TEXT,
 			prompt TEXT,
 			image_url TEXT,
 			model TEXT,
@@ -161,6 +274,57 @@ func (s *SQLiteStorage) initTables() error {
 			path TEXT,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 		)`,
+		`CREATE TABLE IF NOT EXISTS task_checkpoints (
+			task_id TEXT NOT NULL,
+			item TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (task_id, item)
+		)`,
+		`CREATE TABLE IF NOT EXISTS task_failed_items (
+			task_id TEXT NOT NULL,
+			item TEXT NOT NULL,
+			name TEXT,
+			error TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (task_id, item)
+		)`,
+		`CREATE TABLE IF NOT EXISTS task_logs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			task_id TEXT NOT NULL,
+			level TEXT NOT NULL,
+			message TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_task_logs_task_id ON task_logs (task_id, id)`,
+		`CREATE TABLE IF NOT EXISTS task_attempts (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			task_id TEXT NOT NULL,
+			attempt INTEGER NOT NULL,
+			status TEXT NOT NULL,
+			error TEXT,
+			error_class TEXT,
+			started_at DATETIME,
+			finished_at DATETIME
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_task_attempts_task_id ON task_attempts (task_id, id)`,
+		`CREATE TABLE IF NOT EXISTS task_artifacts (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			task_id TEXT NOT NULL,
+			path TEXT NOT NULL,
+			size INTEGER DEFAULT 0,
+			sha256 TEXT,
+			mime_type TEXT,
+			role TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (task_id, path)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_task_artifacts_path ON task_artifacts (path)`,
+		`CREATE TABLE IF NOT EXISTS task_labels (
+			task_id TEXT NOT NULL,
+			label TEXT NOT NULL,
+			PRIMARY KEY (task_id, label)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_task_labels_label ON task_labels (label)`,
 	}
 
 	for _, query := range queries {
@@ -169,6 +333,11 @@ func (s *SQLiteStorage) initTables() error {
 		}
 	}
 
+	// 表创建完成后再检查并添加新字段（数据库迁移），新建数据库时 ALTER TABLE 才有目标表
+	if err := s.migrateTables(); err != nil {
+		return fmt.Errorf("数据库迁移失败: %v", err)
+	}
+
 	return nil
 }
 
@@ -189,6 +358,56 @@ func (s *SQLiteStorage) migrateTables() error {
 		return err
 	}
 
+	// 检查并添加 heartbeat_at 字段
+	if err := s.addColumnIfNotExists("tasks", "heartbeat_at", "DATETIME"); err != nil {
+		return err
+	}
+
+	// 检查并添加重试相关字段
+	if err := s.addColumnIfNotExists("tasks", "attempt", "INTEGER DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := s.addColumnIfNotExists("tasks", "max_attempts", "INTEGER DEFAULT 1"); err != nil {
+		return err
+	}
+	if err := s.addColumnIfNotExists("tasks", "next_retry_at", "DATETIME"); err != nil {
+		return err
+	}
+
+	// 检查并添加 notes 字段
+	if err := s.addColumnIfNotExists("tasks", "notes", "TEXT"); err != nil {
+		return err
+	}
+
+	// 检查并添加 priority 字段
+	if err := s.addColumnIfNotExists("tasks", "priority", "INTEGER DEFAULT 0"); err != nil {
+		return err
+	}
+
+	// 检查并添加 group_id 字段（索引在字段存在后创建）
+	if err := s.addColumnIfNotExists("tasks", "group_id", "TEXT"); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(`CREATE INDEX IF NOT EXISTS idx_tasks_group_id ON tasks (group_id)`); err != nil {
+		return fmt.Errorf("创建任务组索引失败: %v", err)
+	}
+
+	// 检查并添加 starred 字段
+	if err := s.addColumnIfNotExists("tasks", "starred", "INTEGER DEFAULT 0"); err != nil {
+		return err
+	}
+
+	// 结果表添加 task_id 字段（索引在字段存在后创建）
+	// 连接未开启外键约束，删除任务时由 DeleteTaskRecords 在同一事务中删除关联结果
+	for _, table := range []string{"crawl_results", "generated_images"} {
+		if err := s.addColumnIfNotExists(table, "task_id", "TEXT"); err != nil {
+			return err
+		}
+		if _, err := s.db.Exec(fmt.Sprintf(`CREATE INDEX IF NOT EXISTS idx_%s_task_id ON %s (task_id)`, table, table)); err != nil {
+			return fmt.Errorf("创建 %s 任务索引失败: %v", table, err)
+		}
+	}
+
 	return nil
 }
 
@@ -216,23 +435,35 @@ func (s *SQLiteStorage) addColumnIfNotExists(tableName, columnName, columnType s
 
 // CreateTask 创建任务
 func (s *SQLiteStorage) CreateTask(task *Task) error {
-	query := `INSERT INTO tasks (id, type, status, config, progress, images_found, images_downloaded, created_at, updated_at) 
-			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
-	_, err := s.db.Exec(query, task.ID, task.Type, task.Status, task.Config, task.Progress, task.ImagesFound, task.ImagesDownloaded, task.CreatedAt, task.UpdatedAt)
+	query := `INSERT INTO tasks (id, type, status, config, progress, images_found, images_downloaded, priority, group_id, created_at, updated_at) 
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := s.db.Exec(query, task.ID, task.Type, task.Status, task.Config, task.Progress, task.ImagesFound, task.ImagesDownloaded, task.Priority, task.GroupID, task.CreatedAt, task.UpdatedAt)
 	return err
 }
 
-// GetTask 获取任务
-func (s *SQLiteStorage) GetTask(id string) (*Task, error) {
-	query := `SELECT id, type, status, config, progress, error_message, result, images_found, images_downloaded, created_at, updated_at 
-			  FROM tasks WHERE id = ?`
-	row := s.db.QueryRow(query, id)
+// taskColumns 查询任务时读取的列（与 scanTask 的顺序一致）
+const taskColumns = `id, type, status, config, progress, error_message, result, images_found, images_downloaded, heartbeat_at, attempt, max_attempts, next_retry_at, notes, priority, group_id, starred, created_at, updated_at`
+
+// rowScanner 兼容 *sql.Row 和 *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
 
+// scanTask 从查询结果中读取任务
+func scanTask(row rowScanner) (*Task, error) {
 	task := &Task{}
 	var errorMessage sql.NullString
 	var result sql.NullString
+	var heartbeatAt sql.NullTime
+	var attempt, maxAttempts sql.NullInt64
+	var nextRetryAt sql.NullTime
+	var notes sql.NullString
+	var priority sql.NullInt64
+	var groupID sql.NullString
+	var starred sql.NullBool
 	err := row.Scan(&task.ID, &task.Type, &task.Status, &task.Config, &task.Progress,
-		&errorMessage, &result, &task.ImagesFound, &task.ImagesDownloaded, &task.CreatedAt, &task.UpdatedAt)
+		&errorMessage, &result, &task.ImagesFound, &task.ImagesDownloaded, &heartbeatAt,
+		&attempt, &maxAttempts, &nextRetryAt, &notes, &priority, &groupID, &starred, &task.CreatedAt, &task.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -243,10 +474,36 @@ func (s *SQLiteStorage) GetTask(id string) (*Task, error) {
 	if result.Valid {
 		task.Result = result.String
 	}
+	if heartbeatAt.Valid {
+		task.HeartbeatAt = &heartbeatAt.Time
+	}
+	task.Attempt = int(attempt.Int64)
+	task.MaxAttempts = int(maxAttempts.Int64)
+	if nextRetryAt.Valid {
+		task.NextRetryAt = &nextRetryAt.Time
+	}
+	task.Notes = notes.String
+	task.Priority = int(priority.Int64)
+	task.GroupID = groupID.String
+	task.Starred = starred.Bool
+	task.Labels = []string{}
 
 	return task, nil
 }
 
+// GetTask 获取任务
+func (s *SQLiteStorage) GetTask(id string) (*Task, error) {
+	query := `SELECT ` + taskColumns + ` FROM tasks WHERE id = ?`
+	task, err := scanTask(s.db.QueryRow(query, id))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.loadTaskLabels([]*Task{task}); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
 // UpdateTaskStatus 更新任务状态
 func (s *SQLiteStorage) UpdateTaskStatus(id, status string) error {
 	query := `UPDATE tasks SET status = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
@@ -282,6 +539,51 @@ func (s *SQLiteStorage) UpdateTaskResult(id string, result string) error {
 	return err
 }
 
+// UpdateTaskHeartbeat 更新任务心跳时间（不修改 updated_at）
+func (s *SQLiteStorage) UpdateTaskHeartbeat(id string, heartbeatAt time.Time) error {
+	query := `UPDATE tasks SET heartbeat_at = ? WHERE id = ?`
+	_, err := s.db.Exec(query, heartbeatAt, id)
+	return err
+}
+
+// UpdateTaskAttempt 更新任务的尝试次数
+func (s *SQLiteStorage) UpdateTaskAttempt(id string, attempt, maxAttempts int) error {
+	query := `UPDATE tasks SET attempt = ?, max_attempts = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+	_, err := s.db.Exec(query, attempt, maxAttempts, id)
+	return err
+}
+
+// UpdateTaskNextRetry 更新任务下一次自动重试的时间（nil 表示没有待执行的重试）
+func (s *SQLiteStorage) UpdateTaskNextRetry(id string, nextRetryAt *time.Time) error {
+	query := `UPDATE tasks SET next_retry_at = ? WHERE id = ?`
+	var value interface{}
+	if nextRetryAt != nil {
+		value = *nextRetryAt
+	}
+	_, err := s.db.Exec(query, value, id)
+	return err
+}
+
+// ListDueRetryTasks 列出重试时间已到、仍在等待自动重试的任务
+func (s *SQLiteStorage) ListDueRetryTasks(now time.Time) ([]*Task, error) {
+	query := `SELECT ` + taskColumns + ` FROM tasks WHERE status = 'pending' AND next_retry_at IS NOT NULL AND next_retry_at <= ? ORDER BY next_retry_at ASC`
+	rows, err := s.db.Query(query, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []*Task
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
+}
+
 // UpdateTaskError 更新任务错误信息
 func (s *SQLiteStorage) UpdateTaskError(id, errorMsg string) error {
 	query := `UPDATE tasks SET error_message = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
@@ -289,22 +591,88 @@ func (s *SQLiteStorage) UpdateTaskError(id, errorMsg string) error {
 	return err
 }
 
-// ListTasks 列出任务
+// ListTasks 按状态和类型列出任务
 func (s *SQLiteStorage) ListTasks(status, taskType string, limit, offset int) ([]*Task, error) {
-	query := `SELECT id, type, status, config, progress, error_message, result, images_found, images_downloaded, created_at, updated_at 
-			  FROM tasks WHERE 1=1`
+	return s.SearchTasks(&TaskFilter{Status: status, Type: taskType}, limit, offset)
+}
+
+// taskSortColumns 允许排序的任务字段
+var taskSortColumns = map[string]string{
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+	"status":     "status",
+	"type":       "type",
+	"progress":   "progress",
+	"priority":   "priority",
+}
+
+// escapeLike 转义 LIKE 中的通配符
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+// where 根据筛选条件生成 WHERE 子句和参数
+func (f *TaskFilter) where() (string, []interface{}) {
+	clause := ` WHERE 1=1`
 	args := []interface{}{}
+	if f == nil {
+		return clause, args
+	}
 
-	if status != "" {
-		query += " AND status = ?"
-		args = append(args, status)
+	if f.Status != "" {
+		clause += " AND status = ?"
+		args = append(args, f.Status)
 	}
-	if taskType != "" {
-		query += " AND type = ?"
-		args = append(args, taskType)
+	if f.Type != "" {
+		clause += " AND type = ?"
+		args = append(args, f.Type)
 	}
+	if f.GroupID != "" {
+		clause += " AND group_id = ?"
+		args = append(args, f.GroupID)
+	}
+	for _, label := range f.Labels {
+		clause += " AND id IN (SELECT task_id FROM task_labels WHERE label = ?)"
+		args = append(args, label)
+	}
+	// 多个关键字之间为"且"，每个关键字匹配任务ID、配置内容、备注、错误信息或标签之一
+	for _, keyword := range strings.Fields(f.Search) {
+		pattern := "%" + escapeLike(keyword) + "%"
+		clause += ` AND (id LIKE ? ESCAPE '\' OR config LIKE ? ESCAPE '\' OR notes LIKE ? ESCAPE '\' OR error_message LIKE ? ESCAPE '\'` +
+			` OR id IN (SELECT task_id FROM task_labels WHERE label LIKE ? ESCAPE '\'))`
+		args = append(args, pattern, pattern, pattern, pattern, pattern)
+	}
+	if f.CreatedFrom != nil {
+		clause += " AND created_at >= ?"
+		args = append(args, f.CreatedFrom.In(time.Local))
+	}
+	if f.CreatedTo != nil {
+		clause += " AND created_at < ?"
+		args = append(args, f.CreatedTo.In(time.Local))
+	}
+	return clause, args
+}
+
+// orderBy 根据筛选条件生成 ORDER BY 子句，默认按创建时间倒序
+func (f *TaskFilter) orderBy() string {
+	column := "created_at"
+	order := "DESC"
+	if f != nil {
+		if sortColumn, exists := taskSortColumns[f.SortBy]; exists {
+			column = sortColumn
+		}
+		if strings.EqualFold(f.SortOrder, "asc") {
+			order = "ASC"
+		}
+	}
+	// 排序字段相同时按ID排序，保证分页结果稳定
+	return fmt.Sprintf(" ORDER BY %s %s, id %s", column, order, order)
+}
 
-	query += " ORDER BY created_at DESC LIMIT ? OFFSET ?"
+// SearchTasks 按筛选条件查询任务
+func (s *SQLiteStorage) SearchTasks(filter *TaskFilter, limit, offset int) ([]*Task, error) {
+	where, args := filter.where()
+	query := `SELECT ` + taskColumns + ` FROM tasks` + where + filter.orderBy() + ` LIMIT ? OFFSET ?`
 	args = append(args, limit, offset)
 
 	rows, err := s.db.Query(query, args...)
@@ -315,23 +683,19 @@ func (s *SQLiteStorage) ListTasks(status, taskType string, limit, offset int) ([
 
 	var tasks []*Task
 	for rows.Next() {
-		task := &Task{}
-		var errorMessage sql.NullString
-		var result sql.NullString
-		err := rows.Scan(&task.ID, &task.Type, &task.Status, &task.Config, &task.Progress,
-			&errorMessage, &result, &task.ImagesFound, &task.ImagesDownloaded, &task.CreatedAt, &task.UpdatedAt)
+		task, err := scanTask(rows)
 		if err != nil {
 			return nil, err
 		}
-		if errorMessage.Valid {
-			task.ErrorMessage = errorMessage.String
-		}
-		if result.Valid {
-			task.Result = result.String
-		}
 		tasks = append(tasks, task)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
+	if err := s.loadTaskLabels(tasks); err != nil {
+		return nil, err
+	}
 	return tasks, nil
 }
 
@@ -358,7 +722,7 @@ func (s *SQLiteStorage) SetConfig(module, config string) error {
 
 // GetCrawlResults 获取爬取结果
 func (s *SQLiteStorage) GetCrawlResults(limit, offset int) ([]*CrawlResult, error) {
-	query := `SELECT id, url, title, author, tags, image_url, created_at 
+	query := `SELECT id, task_id, url, title, author, tags, image_url, created_at 
 			  FROM crawl_results ORDER BY created_at DESC LIMIT ? OFFSET ?`
 	rows, err := s.db.Query(query, limit, offset)
 	if err != nil {
@@ -369,11 +733,13 @@ func (s *SQLiteStorage) GetCrawlResults(limit, offset int) ([]*CrawlResult, erro
 	var results []*CrawlResult
 	for rows.Next() {
 		result := &CrawlResult{}
-		err := rows.Scan(&result.ID, &result.URL, &result.Title, &result.Author,
+		var taskID sql.NullString
+		err := rows.Scan(&result.ID, &taskID, &result.URL, &result.Title, &result.Author,
 			&result.Tags, &result.ImageURL, &result.CreatedAt)
 		if err != nil {
 			return nil, err
 		}
+		result.TaskID = taskID.String
 		results = append(results, result)
 	}
 
@@ -382,7 +748,7 @@ func (s *SQLiteStorage) GetCrawlResults(limit, offset int) ([]*CrawlResult, erro
 
 // GetGeneratedImages 获取生成的图像
 func (s *SQLiteStorage) GetGeneratedImages(limit, offset int) ([]*GeneratedImage, error) {
-	query := `SELECT id, prompt, image_url, model, created_at 
+	query := `SELECT id, task_id, prompt, image_url, model, created_at 
 			  FROM generated_images ORDER BY created_at DESC LIMIT ? OFFSET ?`
 	rows, err := s.db.Query(query, limit, offset)
 	if err != nil {
@@ -393,10 +759,12 @@ func (s *SQLiteStorage) GetGeneratedImages(limit, offset int) ([]*GeneratedImage
 	var images []*GeneratedImage
 	for rows.Next() {
 		image := &GeneratedImage{}
-		err := rows.Scan(&image.ID, &image.Prompt, &image.ImageURL, &image.Model, &image.CreatedAt)
+		var taskID sql.NullString
+		err := rows.Scan(&image.ID, &taskID, &image.Prompt, &image.ImageURL, &image.Model, &image.CreatedAt)
 		if err != nil {
 			return nil, err
 		}
+		image.TaskID = taskID.String
 		images = append(images, image)
 	}
 
@@ -426,22 +794,16 @@ func (s *SQLiteStorage) GetTrainedModels(limit, offset int) ([]*TrainedModel, er
 	return models, nil
 }
 
-// CountTasks 统计任务数量
+// CountTasks 按状态和类型统计任务数量
 func (s *SQLiteStorage) CountTasks(status, taskType string) (int, error) {
-	query := `SELECT COUNT(*) FROM tasks WHERE 1=1`
-	args := []interface{}{}
-
-	if status != "" {
-		query += " AND status = ?"
-		args = append(args, status)
-	}
-	if taskType != "" {
-		query += " AND type = ?"
-		args = append(args, taskType)
-	}
+	return s.CountTasksByFilter(&TaskFilter{Status: status, Type: taskType})
+}
 
+// CountTasksByFilter 按筛选条件统计任务数量
+func (s *SQLiteStorage) CountTasksByFilter(filter *TaskFilter) (int, error) {
+	where, args := filter.where()
 	var count int
-	err := s.db.QueryRow(query, args...).Scan(&count)
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM tasks`+where, args...).Scan(&count)
 	return count, err
 }
 
@@ -482,6 +844,11 @@ func (s *SQLiteStorage) CleanupTasksByStatus(status string) (int, error) {
 		return 0, err
 	}
 
+	// 清理已删除任务遗留的断点记录
+	if err := s.cleanupOrphanCheckpoints(); err != nil {
+		return int(rowsAffected), err
+	}
+
 	return int(rowsAffected), nil
 }
 
@@ -498,38 +865,465 @@ func (s *SQLiteStorage) CleanupAllTasks() (int, error) {
 		return 0, err
 	}
 
+	// 清理已删除任务遗留的断点记录
+	if err := s.cleanupOrphanCheckpoints(); err != nil {
+		return int(rowsAffected), err
+	}
+
 	return int(rowsAffected), nil
 }
 
 // DeleteTask 删除指定任务
 func (s *SQLiteStorage) DeleteTask(id string) error {
-	query := `DELETE FROM tasks WHERE id = ?`
-	_, err := s.db.Exec(query, id)
+	_, err := s.DeleteTaskRecords(id)
+	return err
+}
+
+// DeleteTaskRecords 在一个事务中删除任务及其断点记录、失败条目、日志、执行记录、产出文件记录、标签和结果记录
+// 返回从各表删除的记录数量，任何一步失败时全部回滚
+func (s *SQLiteStorage) DeleteTaskRecords(id string) (*TaskDeleteResult, error) {
+	tx, err := s.db.Begin()
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("开始事务失败: %v", err)
 	}
-	return nil
+	defer tx.Rollback()
+
+	result := &TaskDeleteResult{}
+	deletes := []struct {
+		query string
+		args  []interface{}
+		count *int64
+	}{
+		{`DELETE FROM task_checkpoints WHERE task_id = ?`, []interface{}{id}, &result.Checkpoints},
+		{`DELETE FROM task_failed_items WHERE task_id = ?`, []interface{}{id}, &result.FailedItems},
+		{`DELETE FROM task_logs WHERE task_id = ?`, []interface{}{id}, &result.Logs},
+		{`DELETE FROM task_attempts WHERE task_id = ?`, []interface{}{id}, &result.Attempts},
+		{`DELETE FROM task_artifacts WHERE task_id = ?`, []interface{}{id}, &result.Artifacts},
+		{`DELETE FROM task_labels WHERE task_id = ?`, []interface{}{id}, &result.Labels},
+		// 旧版本的爬取结果没有 task_id，按ID前缀匹配
+		{`DELETE FROM crawl_results WHERE task_id = ? OR (task_id IS NULL AND id LIKE ?)`, []interface{}{id, id + "_%"}, &result.CrawlResults},
+		{`DELETE FROM generated_images WHERE task_id = ?`, []interface{}{id}, &result.GeneratedImages},
+		{`DELETE FROM tasks WHERE id = ?`, []interface{}{id}, &result.Tasks},
+	}
+	for _, d := range deletes {
+		res, err := tx.Exec(d.query, d.args...)
+		if err != nil {
+			return nil, err
+		}
+		if *d.count, err = res.RowsAffected(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("提交事务失败: %v", err)
+	}
+	return result, nil
 }
 
-// AddCrawlResult 添加爬取结果
+// AddCrawlResult 添加爬取结果（同一ID重复添加时覆盖）
 func (s *SQLiteStorage) AddCrawlResult(result *CrawlResult) error {
-	query := `INSERT INTO crawl_results (id, url, title, author, tags, image_url, created_at) 
-			  VALUES (?, ?, ?, ?, ?, ?, ?)`
-	_, err := s.db.Exec(query, result.ID, result.URL, result.Title, result.Author, result.Tags, result.ImageURL, result.CreatedAt)
+	query := `INSERT OR REPLACE INTO crawl_results (id, task_id, url, title, author, tags, image_url, created_at) 
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := s.db.Exec(query, result.ID, nullString(result.TaskID), result.URL, result.Title, result.Author, result.Tags, result.ImageURL, result.CreatedAt)
 	return err
 }
 
-// AddGeneratedImage 添加生成的图像
+// AddGeneratedImage 添加生成的图像（同一ID重复添加时覆盖）
 func (s *SQLiteStorage) AddGeneratedImage(image *GeneratedImage) error {
-	query := `INSERT INTO generated_images (id, prompt, image_url, model, created_at) 
-			  VALUES (?, ?, ?, ?, ?)`
-	_, err := s.db.Exec(query, image.ID, image.Prompt, image.ImageURL, image.Model, image.CreatedAt)
+	query := `INSERT OR REPLACE INTO generated_images (id, task_id, prompt, image_url, model, created_at) 
+			  VALUES (?, ?, ?, ?, ?, ?)`
+	_, err := s.db.Exec(query, image.ID, nullString(image.TaskID), image.Prompt, image.ImageURL, image.Model, image.CreatedAt)
 	return err
 }
 
+// nullString 空字符串写入为 NULL（没有关联任务的结果 task_id 为 NULL）
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
 // DeleteCrawlResultsByTaskID 根据任务ID删除爬取结果
 func (s *SQLiteStorage) DeleteCrawlResultsByTaskID(taskID string) error {
-	query := `DELETE FROM crawl_results WHERE id LIKE ?`
-	_, err := s.db.Exec(query, taskID+"_%")
+	query := `DELETE FROM crawl_results WHERE task_id = ? OR (task_id IS NULL AND id LIKE ?)`
+	_, err := s.db.Exec(query, taskID, taskID+"_%")
+	return err
+}
+
+// AddTaskCheckpoint 记录任务中已完成的条目（如已下载的URL、已打标的图片路径）
+func (s *SQLiteStorage) AddTaskCheckpoint(taskID, item string) error {
+	query := `INSERT OR IGNORE INTO task_checkpoints (task_id, item, created_at) VALUES (?, ?, CURRENT_TIMESTAMP)`
+	_, err := s.db.Exec(query, taskID, item)
+	return err
+}
+
+// GetTaskCheckpoints 获取任务已完成的条目列表
+func (s *SQLiteStorage) GetTaskCheckpoints(taskID string) ([]string, error) {
+	query := `SELECT item FROM task_checkpoints WHERE task_id = ? ORDER BY created_at`
+	rows, err := s.db.Query(query, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []string
+	for rows.Next() {
+		var item string
+		if err := rows.Scan(&item); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// ClearTaskCheckpoints 清除任务的断点记录
+func (s *SQLiteStorage) ClearTaskCheckpoints(taskID string) error {
+	query := `DELETE FROM task_checkpoints WHERE task_id = ?`
+	_, err := s.db.Exec(query, taskID)
+	return err
+}
+
+// cleanupOrphanCheckpoints 删除不再对应任何任务的断点记录、失败条目、日志、执行记录、产出文件记录和标签
+func (s *SQLiteStorage) cleanupOrphanCheckpoints() error {
+	queries := []string{
+		`DELETE FROM task_checkpoints WHERE task_id NOT IN (SELECT id FROM tasks)`,
+		`DELETE FROM task_failed_items WHERE task_id NOT IN (SELECT id FROM tasks)`,
+		`DELETE FROM task_logs WHERE task_id NOT IN (SELECT id FROM tasks)`,
+		`DELETE FROM task_attempts WHERE task_id NOT IN (SELECT id FROM tasks)`,
+		`DELETE FROM task_artifacts WHERE task_id NOT IN (SELECT id FROM tasks)`,
+		`DELETE FROM task_labels WHERE task_id NOT IN (SELECT id FROM tasks)`,
+		`DELETE FROM crawl_results WHERE task_id IS NOT NULL AND task_id NOT IN (SELECT id FROM tasks)`,
+		`DELETE FROM generated_images WHERE task_id IS NOT NULL AND task_id NOT IN (SELECT id FROM tasks)`,
+	}
+	for _, query := range queries {
+		if _, err := s.db.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddTaskFailedItem 记录任务中处理失败的条目（同一条目重复失败时更新错误信息）
+func (s *SQLiteStorage) AddTaskFailedItem(item *TaskFailedItem) error {
+	query := `INSERT OR REPLACE INTO task_failed_items (task_id, item, name, error, created_at) VALUES (?, ?, ?, ?, ?)`
+	_, err := s.db.Exec(query, item.TaskID, item.Item, item.Name, item.Error, item.CreatedAt)
 	return err
 }
+
+// GetTaskFailedItems 获取任务中处理失败的条目
+func (s *SQLiteStorage) GetTaskFailedItems(taskID string) ([]*TaskFailedItem, error) {
+	query := `SELECT task_id, item, name, error, created_at FROM task_failed_items WHERE task_id = ? ORDER BY created_at`
+	rows, err := s.db.Query(query, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []*TaskFailedItem
+	for rows.Next() {
+		item := &TaskFailedItem{}
+		var name, errorMsg sql.NullString
+		if err := rows.Scan(&item.TaskID, &item.Item, &name, &errorMsg, &item.CreatedAt); err != nil {
+			return nil, err
+		}
+		item.Name = name.String
+		item.Error = errorMsg.String
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// RemoveTaskFailedItem 移除失败条目（条目重试成功后调用）
+func (s *SQLiteStorage) RemoveTaskFailedItem(taskID, item string) error {
+	query := `DELETE FROM task_failed_items WHERE task_id = ? AND item = ?`
+	_, err := s.db.Exec(query, taskID, item)
+	return err
+}
+
+// ClearTaskFailedItems 清除任务的所有失败条目
+func (s *SQLiteStorage) ClearTaskFailedItems(taskID string) error {
+	query := `DELETE FROM task_failed_items WHERE task_id = ?`
+	_, err := s.db.Exec(query, taskID)
+	return err
+}
+
+// AddTaskLog 添加任务日志
+func (s *SQLiteStorage) AddTaskLog(log *TaskLog) error {
+	query := `INSERT INTO task_logs (task_id, level, message, created_at) VALUES (?, ?, ?, ?)`
+	result, err := s.db.Exec(query, log.TaskID, log.Level, log.Message, log.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	log.ID = id
+	return nil
+}
+
+// ListTaskLogs 按时间顺序列出任务日志（limit <= 0 表示不限制数量）
+func (s *SQLiteStorage) ListTaskLogs(taskID, level string, limit, offset int) ([]*TaskLog, error) {
+	query := `SELECT id, task_id, level, message, created_at FROM task_logs WHERE task_id = ?`
+	args := []interface{}{taskID}
+
+	if level != "" {
+		query += " AND level = ?"
+		args = append(args, level)
+	}
+
+	if limit <= 0 {
+		limit = -1 // SQLite 中 LIMIT -1 表示不限制
+	}
+	query += " ORDER BY id ASC LIMIT ? OFFSET ?"
+	args = append(args, limit, offset)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var logs []*TaskLog
+	for rows.Next() {
+		log := &TaskLog{}
+		var message sql.NullString
+		if err := rows.Scan(&log.ID, &log.TaskID, &log.Level, &message, &log.CreatedAt); err != nil {
+			return nil, err
+		}
+		log.Message = message.String
+		logs = append(logs, log)
+	}
+
+	return logs, nil
+}
+
+// CountTaskLogs 统计任务日志数量
+func (s *SQLiteStorage) CountTaskLogs(taskID, level string) (int, error) {
+	query := `SELECT COUNT(*) FROM task_logs WHERE task_id = ?`
+	args := []interface{}{taskID}
+
+	if level != "" {
+		query += " AND level = ?"
+		args = append(args, level)
+	}
+
+	var count int
+	err := s.db.QueryRow(query, args...).Scan(&count)
+	return count, err
+}
+
+// AddTaskAttempt 记录任务的一次执行
+func (s *SQLiteStorage) AddTaskAttempt(attempt *TaskAttempt) error {
+	query := `INSERT INTO task_attempts (task_id, attempt, status, error, error_class, started_at, finished_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	result, err := s.db.Exec(query, attempt.TaskID, attempt.Attempt, attempt.Status, attempt.Error, attempt.ErrorClass, attempt.StartedAt, attempt.FinishedAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	attempt.ID = id
+	return nil
+}
+
+// ListTaskAttempts 按时间顺序列出任务的执行记录
+func (s *SQLiteStorage) ListTaskAttempts(taskID string) ([]*TaskAttempt, error) {
+	query := `SELECT id, task_id, attempt, status, error, error_class, started_at, finished_at FROM task_attempts WHERE task_id = ? ORDER BY id ASC`
+	rows, err := s.db.Query(query, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attempts []*TaskAttempt
+	for rows.Next() {
+		attempt := &TaskAttempt{}
+		var errorMessage, errorClass sql.NullString
+		if err := rows.Scan(&attempt.ID, &attempt.TaskID, &attempt.Attempt, &attempt.Status,
+			&errorMessage, &errorClass, &attempt.StartedAt, &attempt.FinishedAt); err != nil {
+			return nil, err
+		}
+		attempt.Error = errorMessage.String
+		attempt.ErrorClass = errorClass.String
+		attempts = append(attempts, attempt)
+	}
+
+	return attempts, nil
+}
+
+// AddTaskArtifact 记录任务产出的文件，同一任务的同一路径再次记录时覆盖原记录（如重试时重新生成）
+func (s *SQLiteStorage) AddTaskArtifact(artifact *TaskArtifact) error {
+	query := `INSERT OR REPLACE INTO task_artifacts (task_id, path, size, sha256, mime_type, role, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	result, err := s.db.Exec(query, artifact.TaskID, artifact.Path, artifact.Size, artifact.SHA256, artifact.MimeType, artifact.Role, artifact.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	artifact.ID = id
+	return nil
+}
+
+// taskArtifactColumns 查询产出文件时读取的列（与 scanTaskArtifact 的顺序一致）
+const taskArtifactColumns = `id, task_id, path, size, sha256, mime_type, role, created_at`
+
+// scanTaskArtifact 从查询结果中读取产出文件记录
+func scanTaskArtifact(row rowScanner) (*TaskArtifact, error) {
+	artifact := &TaskArtifact{}
+	var sha256, mimeType, role sql.NullString
+	if err := row.Scan(&artifact.ID, &artifact.TaskID, &artifact.Path, &artifact.Size,
+		&sha256, &mimeType, &role, &artifact.CreatedAt); err != nil {
+		return nil, err
+	}
+	artifact.SHA256 = sha256.String
+	artifact.MimeType = mimeType.String
+	artifact.Role = role.String
+	return artifact, nil
+}
+
+// ListTaskArtifacts 按记录顺序列出任务的产出文件，role 为空时列出全部
+func (s *SQLiteStorage) ListTaskArtifacts(taskID, role string) ([]*TaskArtifact, error) {
+	query := `SELECT ` + taskArtifactColumns + ` FROM task_artifacts WHERE task_id = ?`
+	args := []interface{}{taskID}
+	if role != "" {
+		query += ` AND role = ?`
+		args = append(args, role)
+	}
+	query += ` ORDER BY id ASC`
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var artifacts []*TaskArtifact
+	for rows.Next() {
+		artifact, err := scanTaskArtifact(rows)
+		if err != nil {
+			return nil, err
+		}
+		artifacts = append(artifacts, artifact)
+	}
+	return artifacts, rows.Err()
+}
+
+// GetTaskArtifact 获取任务的单个产出文件记录
+func (s *SQLiteStorage) GetTaskArtifact(taskID string, id int64) (*TaskArtifact, error) {
+	query := `SELECT ` + taskArtifactColumns + ` FROM task_artifacts WHERE task_id = ? AND id = ?`
+	return scanTaskArtifact(s.db.QueryRow(query, taskID, id))
+}
+
+// CountArtifactsByPath 统计记录了同一路径的产出文件数量（不包括 excludeTaskID 的记录）
+func (s *SQLiteStorage) CountArtifactsByPath(path, excludeTaskID string) (int, error) {
+	query := `SELECT COUNT(*) FROM task_artifacts WHERE path = ? AND task_id != ?`
+	var count int
+	err := s.db.QueryRow(query, path, excludeTaskID).Scan(&count)
+	return count, err
+}
+
+// loadTaskLabels 批量读取任务的标签
+func (s *SQLiteStorage) loadTaskLabels(tasks []*Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	byID := make(map[string]*Task, len(tasks))
+	placeholders := make([]string, 0, len(tasks))
+	args := make([]interface{}, 0, len(tasks))
+	for _, task := range tasks {
+		byID[task.ID] = task
+		placeholders = append(placeholders, "?")
+		args = append(args, task.ID)
+	}
+
+	query := `SELECT task_id, label FROM task_labels WHERE task_id IN (` + strings.Join(placeholders, ", ") + `) ORDER BY label`
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID, label string
+		if err := rows.Scan(&taskID, &label); err != nil {
+			return err
+		}
+		if task, exists := byID[taskID]; exists {
+			task.Labels = append(task.Labels, label)
+		}
+	}
+	return rows.Err()
+}
+
+// SetTaskLabels 替换任务的全部标签
+func (s *SQLiteStorage) SetTaskLabels(id string, labels []string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM task_labels WHERE task_id = ?`, id); err != nil {
+		return err
+	}
+	for _, label := range labels {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO task_labels (task_id, label) VALUES (?, ?)`, id, label); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`UPDATE tasks SET updated_at = CURRENT_TIMESTAMP WHERE id = ?`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateTaskNotes 更新任务备注
+func (s *SQLiteStorage) UpdateTaskNotes(id, notes string) error {
+	query := `UPDATE tasks SET notes = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+	_, err := s.db.Exec(query, notes, id)
+	return err
+}
+
+// UpdateTaskPriority 更新任务优先级
+func (s *SQLiteStorage) UpdateTaskPriority(id string, priority int) error {
+	query := `UPDATE tasks SET priority = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+	_, err := s.db.Exec(query, priority, id)
+	return err
+}
+
+// UpdateTaskStarred 更新任务星标
+func (s *SQLiteStorage) UpdateTaskStarred(id string, starred bool) error {
+	query := `UPDATE tasks SET starred = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+	_, err := s.db.Exec(query, starred, id)
+	return err
+}
+
+// ListTaskLabels 列出所有标签及使用该标签的任务数量
+func (s *SQLiteStorage) ListTaskLabels() (map[string]int, error) {
+	rows, err := s.db.Query(`SELECT label, COUNT(*) FROM task_labels GROUP BY label`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	labels := make(map[string]int)
+	for rows.Next() {
+		var label string
+		var count int
+		if err := rows.Scan(&label, &count); err != nil {
+			return nil, err
+		}
+		labels[label] = count
+	}
+	return labels, rows.Err()
+}
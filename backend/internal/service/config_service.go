@@ -71,7 +71,7 @@ func (s *configServiceImpl) SetConfig(module, config string) error {
 func (s *configServiceImpl) ExportConfig(modules []string) (string, error) {
 	if len(modules) == 0 {
 		// 导出所有模块配置
-		modules = []string{"ai", "crawler", "logger", "storage"}
+		modules = []string{"ai", "crawler", "logger", "storage", TaskRuntimeConfigModule, RetentionConfigModule}
 	}
 
 	configMap := make(map[string]interface{})
@@ -138,6 +138,10 @@ func (s *configServiceImpl) ValidateConfig(module, config string) error {
 		return s.validateLoggerConfig(config)
 	case "storage":
 		return s.validateStorageConfig(config)
+	case TaskRuntimeConfigModule:
+		return s.validateTaskRuntimeConfig(config)
+	case RetentionConfigModule:
+		return s.validateRetentionPolicy(config)
 	default:
 		// 对于未知模块，只验证JSON格式
 		if config != "" {
@@ -235,3 +239,31 @@ func (s *configServiceImpl) validateStorageConfig(config string) error {
 
 	return nil
 }
+
+// validateTaskRuntimeConfig 验证任务运行配置（超时、心跳检测）
+func (s *configServiceImpl) validateTaskRuntimeConfig(config string) error {
+	if config == "" {
+		return nil
+	}
+
+	taskConfig := DefaultTaskRuntimeConfig()
+	if err := json.Unmarshal([]byte(config), taskConfig); err != nil {
+		return fmt.Errorf("任务运行配置格式无效: %v", err)
+	}
+
+	return taskConfig.Validate()
+}
+
+// validateRetentionPolicy 验证任务产出保留策略
+func (s *configServiceImpl) validateRetentionPolicy(config string) error {
+	if config == "" {
+		return nil
+	}
+
+	policy := DefaultRetentionPolicy()
+	if err := json.Unmarshal([]byte(config), policy); err != nil {
+		return fmt.Errorf("保留策略格式无效: %v", err)
+	}
+
+	return policy.Validate()
+}
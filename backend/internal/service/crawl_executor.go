@@ -0,0 +1,298 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"pixiv-tailor/backend/internal/crawler"
+	"pixiv-tailor/backend/internal/logger"
+	"pixiv-tailor/backend/internal/repository"
+	"pixiv-tailor/backend/pkg/models"
+	"pixiv-tailor/backend/pkg/paths"
+)
+
+// crawlTaskExecutor 爬虫任务执行器
+type crawlTaskExecutor struct{}
+
+// NewCrawlTaskExecutor 创建爬虫任务执行器
+func NewCrawlTaskExecutor() TaskExecutor {
+	return &crawlTaskExecutor{}
+}
+
+// Schema 爬虫任务配置 Schema
+func (e *crawlTaskExecutor) Schema() *ConfigSchema {
+	return CrawlConfigSchema()
+}
+
+// Validate 校验爬虫任务配置中与爬取类型相关的字段
+func (e *crawlTaskExecutor) Validate(config map[string]interface{}) error {
+	var cfg CrawlTaskConfig
+	if err := DecodeConfig(config, &cfg); err != nil {
+		return err
+	}
+
+	// 重试失败条目的任务直接下载指定图片，不需要爬取参数
+	if len(cfg.RetryItems) > 0 {
+		return nil
+	}
+
+	switch cfg.Type {
+	case "tag":
+		if cfg.Query == "" {
+			return newFieldError("query", "按标签爬取时不能为空")
+		}
+	case "user":
+		if cfg.UserID <= 0 {
+			return newFieldError("user_id", "按用户爬取时不能为空")
+		}
+	case "illust":
+		if cfg.IllustID <= 0 {
+			return newFieldError("illust_id", "按作品爬取时不能为空")
+		}
+	case "":
+		return newFieldError("type", "必填字段")
+	}
+	return nil
+}
+
+// newCrawlResult 下载成功的图片对应的爬取结果（ID 为 {任务ID}_{文件名}）
+func newCrawlResult(taskID, filename string, image *models.PixivImage) *repository.CrawlResult {
+	tags, _ := json.Marshal(image.Tags)
+	return &repository.CrawlResult{
+		ID:       fmt.Sprintf("%s_%s", taskID, strings.TrimSuffix(filename, filepath.Ext(filename))),
+		URL:      fmt.Sprintf("https://www.pixiv.net/artworks/%d", image.ID),
+		Title:    image.Title,
+		Author:   image.Author,
+		Tags:     string(tags),
+		ImageURL: image.URL,
+	}
+}
+
+// Execute 执行爬虫任务
+func (e *crawlTaskExecutor) Execute(ctx context.Context, task *repository.Task, config map[string]interface{}, reporter TaskReporter) error {
+	reporter.Log("info", "开始执行爬虫任务")
+
+	var cfg CrawlTaskConfig
+	if err := DecodeConfig(config, &cfg); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// 更新进度到5%
+	reporter.Progress(5)
+	reporter.Log("info", "爬虫任务初始化完成 (5%)")
+
+	// 创建任务特定的爬虫实例
+	crawlerInstance, err := crawler.NewCrawlerForTask(task.ID, config)
+	if err != nil {
+		return fmt.Errorf("创建爬虫失败: %v", err)
+	}
+
+	// 设置任务信息（用于生成任务文件夹名：[时间]_任务类型_哈希值）
+	crawlerInstance.SetTaskInfo(task.Type, task.CreatedAt)
+	// 下载的图片保存在任务目录中，记录为任务产出文件
+	var taskImagesDir string
+	if pathManager := paths.GetPathManager(); pathManager != nil {
+		taskImagesDir = pathManager.GetTaskImagesDir(task.ID, task.Type, task.CreatedAt)
+	}
+
+	// 配置代理设置
+	if cfg.ProxyEnabled {
+		if cfg.ProxyURL != "" {
+			crawlerInstance.SetProxy(true, cfg.ProxyURL)
+		}
+	} else {
+		crawlerInstance.SetProxy(false, "")
+	}
+
+	// 配置Cookie设置（"default" 表示使用默认Cookie）
+	if cfg.Cookie != "" && cfg.Cookie != "default" {
+		crawlerInstance.SetCookie(cfg.Cookie)
+	}
+
+	// 更新进度到20%
+	reporter.Progress(20)
+	reporter.Log("info", "爬虫配置完成 (20%)")
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// 根据配置执行爬取
+	crawlType := cfg.Type
+	var results []*models.PixivImage
+	var crawlErr error
+
+	// 重试失败条目的任务：直接下载指定的图片，不再重新爬取
+	retryFilenames := make(map[string]string)
+	if len(cfg.RetryItems) > 0 {
+		crawlType = "retry"
+	}
+
+	switch crawlType {
+	case "retry":
+		for _, item := range cfg.RetryItems {
+			if item.URL == "" {
+				continue
+			}
+			retryFilenames[item.URL] = item.Filename
+			results = append(results, &models.PixivImage{URL: item.URL, Title: item.Filename})
+		}
+		reporter.Log("info", fmt.Sprintf("重试失败条目：共 %d 张图片", len(results)))
+
+	case "tag":
+		// 爬取阶段没有进度上报，定期刷新心跳避免被看门狗判定为卡死
+		stopKeepAlive := KeepAlive(reporter)
+		results, crawlErr = crawlerInstance.CrawlByTag(cfg.Query, cfg.Order, cfg.Mode, cfg.Limit)
+		stopKeepAlive()
+
+	case "user":
+		stopKeepAlive := KeepAlive(reporter)
+		results, crawlErr = crawlerInstance.CrawlByUser(cfg.UserID, cfg.Limit)
+		stopKeepAlive()
+
+	case "illust":
+		illustResults, err := crawlerInstance.CrawlByIllust(cfg.IllustID)
+		crawlErr = err
+		if illustResults != nil {
+			results = illustResults
+		}
+
+	default:
+		return fmt.Errorf("不支持的类型: %s", crawlType)
+	}
+
+	if crawlErr != nil {
+		return fmt.Errorf("爬取失败: %w", crawlErr)
+	}
+
+	// 保存原始获取到的图片数量
+	originalImagesCount := len(results)
+
+	// 应用图片数量限制
+	maxImages := cfg.MaxImages
+
+	// 如果有设置限制且获取到的图片数量超过限制，只保留前maxImages张
+	if maxImages > 0 && len(results) > maxImages {
+		results = results[:maxImages]
+		reporter.Log("info", fmt.Sprintf("应用图片数量限制：已获取 %d 张，限制下载 %d 张", originalImagesCount, len(results)))
+	}
+
+	// 记录原始获取数量（不包括限制）
+	reporter.ImagesFound(originalImagesCount)
+	reporter.Log("info", fmt.Sprintf("爬取成功，获得 %d 张图片", originalImagesCount))
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// 开始下载图片
+	if len(results) > 0 {
+		// 断点续传：已下载的URL不再重复下载
+		downloadedURLs := make(map[string]bool)
+		for _, url := range reporter.Checkpoints() {
+			downloadedURLs[url] = true
+		}
+
+		downloadedCount := 0
+		for _, image := range results {
+			if downloadedURLs[image.URL] {
+				downloadedCount++
+			}
+		}
+		if downloadedCount > 0 {
+			reporter.Log("info", fmt.Sprintf("从断点恢复：已下载 %d 张图片，将跳过", downloadedCount))
+			reporter.ImagesDownloaded(downloadedCount)
+		}
+
+		reporter.Log("info", fmt.Sprintf("开始下载 %d 张图片", len(results)-downloadedCount))
+		for i, image := range results {
+			if downloadedURLs[image.URL] {
+				continue
+			}
+
+			// 检查是否被取消
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			// 更新进度: 50% + 45% * (i / total)
+			reporter.Progress(50 + int(45*float64(i)/float64(len(results))))
+
+			// 每10张图片或每张图片发送下载进度日志
+			if (i+1)%10 == 0 || i == 0 || i == len(results)-1 {
+				reporter.Log("info", fmt.Sprintf("正在下载图片 %d/%d", i+1, len(results)))
+			}
+
+			// 生成文件名
+			// 从URL中提取文件扩展名，如果无法提取则使用.jpg
+			fileExt := ".jpg"
+			if ext := filepath.Ext(image.URL); ext != "" {
+				fileExt = ext
+			}
+			// 使用图片ID和索引生成唯一文件名（重试任务沿用原文件名）
+			filename := fmt.Sprintf("artworks_%d_p%02d%s", image.ID, i+1, fileExt)
+			if retryFilename := retryFilenames[image.URL]; retryFilename != "" {
+				filename = retryFilename
+			}
+
+			// 下载图片
+			if err := crawlerInstance.DownloadImage(image.URL, filename, task.ID, func(url, filename string, downloaded, total int64, percent float64) {
+				// 进度回调（可选）
+			}); err != nil {
+				logger.Warnf("下载图片失败 %s: %v", image.URL, err)
+				reporter.Log("warning", fmt.Sprintf("下载失败: %s (%v)", image.Title, err))
+				// 记录失败条目，供"重试失败条目"使用
+				reporter.ItemFailed(image.URL, filename, err)
+			} else {
+				downloadedCount++
+				// 记录断点
+				reporter.ItemDone(image.URL)
+				if taskImagesDir != "" {
+					reporter.Artifact(filepath.Join(taskImagesDir, filename), ArtifactRoleImage)
+				}
+				reporter.CrawlResult(newCrawlResult(task.ID, filename, image))
+				// 实时更新下载计数
+				reporter.ImagesDownloaded(downloadedCount)
+				// 每10张或最后一张图片发送成功日志
+				if downloadedCount%10 == 0 || downloadedCount == len(results) {
+					reporter.Log("info", fmt.Sprintf("已下载 %d/%d 张图片", downloadedCount, len(results)))
+				}
+			}
+		}
+		reporter.Log("info", fmt.Sprintf("图片下载完成，成功下载 %d/%d 张", downloadedCount, len(results)))
+	}
+
+	// 更新进度到95%
+	reporter.Progress(95)
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// 任务完成
+	reporter.Progress(100)
+	reporter.Log("info", "爬虫任务完成！")
+
+	// 保存任务结果信息（包括预期下载数量）
+	result := map[string]interface{}{
+		"expected_images": len(results), // 预期下载的图片数量
+		"has_limit":       maxImages > 0,
+		"failed_images":   reporter.FailedItemCount(), // 下载失败的图片数量
+	}
+	if cfg.RetryOf != "" {
+		result["retry_of"] = cfg.RetryOf
+	}
+	reporter.Result(result)
+
+	return nil
+}
+
+// Cleanup 爬虫任务无需额外清理
+func (e *crawlTaskExecutor) Cleanup(task *repository.Task) {}
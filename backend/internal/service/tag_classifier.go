@@ -23,6 +23,43 @@ const (
 	CategoryGeneral    TagCategory = "general"    // 通用标签
 )
 
+// tagCategoryOrder 按类别排序标签时的类别顺序（角色标签在前）
+var tagCategoryOrder = []TagCategory{
+	CategoryCharacter,
+	CategoryPerson,
+	CategoryFace,
+	CategoryHair,
+	CategoryBody,
+	CategoryClothing,
+	CategoryAccessory,
+	CategoryAction,
+	CategoryBackground,
+	CategoryStyle,
+	CategoryGeneral,
+	CategoryQuality,
+}
+
+// isTagCategory 判断是否为已知的标签类别
+func isTagCategory(name string) bool {
+	for _, c := range tagCategoryOrder {
+		if string(c) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// TagCategoryRank 标签所属类别在类别顺序中的位置（数值越小越靠前）
+func TagCategoryRank(tag string) int {
+	category := ClassifyTag(tag)
+	for i, c := range tagCategoryOrder {
+		if c == category {
+			return i
+		}
+	}
+	return len(tagCategoryOrder)
+}
+
 // ClassifyTag 分类标签
 func ClassifyTag(tag string) TagCategory {
 	tagLower := strings.ToLower(tag)
@@ -0,0 +1,539 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"pixiv-tailor/backend/internal/logger"
+	"pixiv-tailor/backend/internal/tagger"
+	"pixiv-tailor/backend/pkg/paths"
+
+	"github.com/google/uuid"
+)
+
+// 标签编辑操作
+const (
+	TagEditRename = "rename" // 重命名标签：from 匹配的标签替换为 to（/正则/ 时按正则替换，to 中可使用 $1）
+	TagEditAdd    = "add"    // 添加标签：tags 中不存在的标签按 position 追加或插入到开头
+	TagEditRemove = "remove" // 移除标签：移除匹配 tags 中任一规则的标签
+	TagEditMerge  = "merge"  // 合并同义标签：匹配 tags 中任一规则的标签合并为 to，保留第一次出现的位置
+	TagEditFront  = "front"  // 移到最前：tags 中的标签（如触发词）按顺序移到最前面
+)
+
+// TagEditRequest 标签编辑请求，作用于目录中的 TXT 标签文件
+type TagEditRequest struct {
+	Dir       string   `json:"dir"`                 // 标签目录，为空时为 tags/，相对路径按 tags/ 解析
+	Operation string   `json:"operation"`           // 操作：rename、add、remove、merge、front
+	Tags      []string `json:"tags,omitempty"`      // add 为要添加的标签，remove、merge 为匹配规则，front 为要移到最前的标签
+	From      string   `json:"from,omitempty"`      // rename 的源标签（支持 * ? 通配符和 /正则表达式/）
+	To        string   `json:"to,omitempty"`        // rename、merge 的目标标签
+	Position  string   `json:"position,omitempty"`  // add 的位置：append（默认）或 prepend
+	Query     []string `json:"query,omitempty"`     // 只编辑匹配查询的文件：每条规则至少匹配一个标签，以 - 开头的规则不能匹配任何标签
+	Recursive bool     `json:"recursive,omitempty"` // 包含子目录
+	DryRun    bool     `json:"dry_run,omitempty"`   // 只预览修改，不写入文件和日志
+}
+
+// TagFileChange 单个标签文件的修改
+type TagFileChange struct {
+	Path   string `json:"path"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// TagEditResult 标签编辑（或撤销）结果
+type TagEditResult struct {
+	JournalID string          `json:"journal_id,omitempty"` // 编辑日志ID，用于撤销
+	Operation string          `json:"operation"`
+	DryRun    bool            `json:"dry_run"`
+	Scanned   int             `json:"scanned"` // 扫描的标签文件数
+	Matched   int             `json:"matched"` // 匹配查询的标签文件数
+	Changed   int             `json:"changed"` // 修改的标签文件数
+	Changes   []TagFileChange `json:"changes"`
+	Conflicts []string        `json:"conflicts,omitempty"` // 撤销时已被再次修改而未恢复的文件
+}
+
+// TagEditJournal 标签编辑日志：记录每个文件修改前后的内容，用于撤销
+type TagEditJournal struct {
+	ID        string          `json:"id"`
+	Request   TagEditRequest  `json:"request"`
+	Files     int             `json:"files"` // 修改的文件数
+	Changes   []TagFileChange `json:"changes,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	UndoneAt  *time.Time      `json:"undone_at,omitempty"`
+}
+
+// TagEditService 标签编辑服务接口
+type TagEditService interface {
+	// Edit 对目录中的标签文件执行编辑操作，非预览时写入编辑日志
+	Edit(request *TagEditRequest) (*TagEditResult, error)
+
+	// Undo 撤销编辑，journalID 为空时撤销最近一次未撤销的编辑
+	Undo(journalID string) (*TagEditResult, error)
+
+	// ListJournals 列出编辑日志（最新的在前，不包含修改内容）
+	ListJournals() ([]*TagEditJournal, error)
+}
+
+// tagEditServiceImpl 标签编辑服务实现
+type tagEditServiceImpl struct {
+	journalDir string
+}
+
+// NewTagEditService 创建标签编辑服务，journalDir 为空时使用 data/tag_edits
+func NewTagEditService(journalDir string) TagEditService {
+	if journalDir == "" {
+		if pathManager := paths.GetPathManager(); pathManager != nil {
+			journalDir = filepath.Join(pathManager.GetDataDir(), "tag_edits")
+		} else {
+			journalDir = "backend/data/tag_edits"
+		}
+	}
+	return &tagEditServiceImpl{journalDir: journalDir}
+}
+
+// tagEditor 编译后的编辑操作
+type tagEditor struct {
+	request  *TagEditRequest
+	patterns []*regexp.Regexp // remove、merge 的匹配规则，rename 的源标签
+	include  []*regexp.Regexp // 查询中必须匹配的规则
+	exclude  []*regexp.Regexp // 查询中不能匹配的规则
+}
+
+// newTagEditor 校验请求并编译匹配规则
+func newTagEditor(request *TagEditRequest) (*tagEditor, error) {
+	editor := &tagEditor{request: request}
+	var err error
+
+	switch request.Operation {
+	case TagEditRename:
+		if strings.TrimSpace(request.From) == "" || strings.TrimSpace(request.To) == "" {
+			return nil, fmt.Errorf("重命名需要指定 from 和 to")
+		}
+		if editor.patterns, err = tagger.CompileTagPatterns([]string{request.From}); err != nil {
+			return nil, err
+		}
+	case TagEditMerge:
+		if strings.TrimSpace(request.To) == "" {
+			return nil, fmt.Errorf("合并需要指定目标标签 to")
+		}
+		fallthrough
+	case TagEditRemove:
+		if editor.patterns, err = tagger.CompileTagPatterns(request.Tags); err != nil {
+			return nil, err
+		}
+		if len(editor.patterns) == 0 {
+			return nil, fmt.Errorf("%s 需要指定标签 tags", request.Operation)
+		}
+	case TagEditAdd, TagEditFront:
+		if len(splitTagList(strings.Join(request.Tags, ","))) == 0 {
+			return nil, fmt.Errorf("%s 需要指定标签 tags", request.Operation)
+		}
+		if request.Position != "" && request.Position != tagger.ExtendPositionAppend && request.Position != tagger.ExtendPositionPrepend {
+			return nil, fmt.Errorf("未知的添加位置: %s", request.Position)
+		}
+	default:
+		return nil, fmt.Errorf("未知的标签编辑操作: %s", request.Operation)
+	}
+
+	var include, exclude []string
+	for _, query := range request.Query {
+		query = strings.TrimSpace(query)
+		if strings.HasPrefix(query, "-") {
+			exclude = append(exclude, query[1:])
+		} else if query != "" {
+			include = append(include, query)
+		}
+	}
+	for _, query := range include {
+		pattern, err := tagger.CompileTagPatterns([]string{query})
+		if err != nil {
+			return nil, fmt.Errorf("查询无效: %v", err)
+		}
+		editor.include = append(editor.include, pattern...)
+	}
+	if editor.exclude, err = tagger.CompileTagPatterns(exclude); err != nil {
+		return nil, fmt.Errorf("查询无效: %v", err)
+	}
+	return editor, nil
+}
+
+// matches 判断标签文件是否匹配查询
+func (e *tagEditor) matches(tags []string) bool {
+	for _, pattern := range e.include {
+		found := false
+		for _, tag := range tags {
+			if tagger.MatchTagPatterns(tag, []*regexp.Regexp{pattern}) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, tag := range tags {
+		if tagger.MatchTagPatterns(tag, e.exclude) {
+			return false
+		}
+	}
+	return true
+}
+
+// apply 对一个文件的标签执行编辑操作，返回去重后的新标签列表
+func (e *tagEditor) apply(tags []string) []string {
+	request := e.request
+	var edited []string
+
+	switch request.Operation {
+	case TagEditRename:
+		pattern := e.patterns[0]
+		isRegexp := len(request.From) > 2 && strings.HasPrefix(request.From, "/") && strings.HasSuffix(request.From, "/")
+		for _, tag := range tags {
+			if !tagger.MatchTagPatterns(tag, e.patterns) {
+				edited = append(edited, tag)
+			} else if isRegexp {
+				edited = append(edited, strings.TrimSpace(pattern.ReplaceAllString(tag, request.To)))
+			} else {
+				edited = append(edited, strings.TrimSpace(request.To))
+			}
+		}
+	case TagEditRemove:
+		for _, tag := range tags {
+			if !tagger.MatchTagPatterns(tag, e.patterns) {
+				edited = append(edited, tag)
+			}
+		}
+	case TagEditMerge:
+		for _, tag := range tags {
+			if tagger.MatchTagPatterns(tag, e.patterns) {
+				tag = strings.TrimSpace(request.To)
+			}
+			edited = append(edited, tag)
+		}
+	case TagEditAdd:
+		added := splitTagList(strings.Join(request.Tags, ","))
+		if request.Position == tagger.ExtendPositionPrepend {
+			edited = append(added, tags...)
+		} else {
+			edited = append(append(edited, tags...), added...)
+		}
+	case TagEditFront:
+		// 移动的标签保留文件中的写法
+		present := make(map[string]string, len(tags))
+		for _, tag := range tags {
+			present[tagger.NormalizeTag(tag)] = tag
+		}
+		moved := make(map[string]bool)
+		for _, tag := range splitTagList(strings.Join(request.Tags, ",")) {
+			if original, ok := present[tagger.NormalizeTag(tag)]; ok {
+				edited = append(edited, original)
+				moved[tagger.NormalizeTag(tag)] = true
+			}
+		}
+		for _, tag := range tags {
+			if !moved[tagger.NormalizeTag(tag)] {
+				edited = append(edited, tag)
+			}
+		}
+	}
+
+	// 去重（空格与下划线、大小写视为相同），保留第一次出现的位置
+	result := make([]string, 0, len(edited))
+	seen := make(map[string]bool, len(edited))
+	for _, tag := range edited {
+		key := tagger.NormalizeTag(tag)
+		if tag == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, tag)
+	}
+	return result
+}
+
+// Edit 对目录中的标签文件执行编辑操作
+func (s *tagEditServiceImpl) Edit(request *TagEditRequest) (*TagEditResult, error) {
+	editor, err := newTagEditor(request)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := resolveTagEditDir(request.Dir)
+	if err != nil {
+		return nil, err
+	}
+	files, err := listTagFiles(dir, request.Recursive)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &TagEditResult{Operation: request.Operation, DryRun: request.DryRun, Scanned: len(files), Changes: []TagFileChange{}}
+	var changes []TagFileChange
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取标签文件失败: %v", err)
+		}
+		before := string(data)
+		tags := splitTagList(before)
+		if !editor.matches(tags) {
+			continue
+		}
+		result.Matched++
+
+		after := strings.Join(editor.apply(tags), ", ")
+		if strings.HasSuffix(before, "\n") {
+			after += "\n" // 保留末尾换行
+		}
+		if after != before {
+			changes = append(changes, TagFileChange{Path: path, Before: before, After: after})
+		}
+	}
+
+	if request.DryRun {
+		result.Changed = len(changes)
+		result.Changes = append(result.Changes, changes...)
+		return result, nil
+	}
+
+	// 写入修改的文件，写入失败时已修改的文件仍记录到编辑日志中，可以撤销
+	var writeErr error
+	for _, change := range changes {
+		if err := writeTagFile(change.Path, change.After); err != nil {
+			writeErr = err
+			break
+		}
+		result.Changes = append(result.Changes, change)
+	}
+	result.Changed = len(result.Changes)
+
+	if result.Changed > 0 {
+		journal := &TagEditJournal{
+			ID:        time.Now().Format("20060102-150405") + "-" + uuid.New().String()[:8],
+			Request:   *request,
+			Files:     result.Changed,
+			Changes:   result.Changes,
+			CreatedAt: time.Now(),
+		}
+		if err := s.saveJournal(journal); err != nil {
+			return nil, err
+		}
+		result.JournalID = journal.ID
+		logger.Infof("标签编辑完成: 操作=%s, 目录=%s, 修改 %d 个文件, 日志=%s", request.Operation, dir, result.Changed, journal.ID)
+	}
+
+	if writeErr != nil {
+		return result, writeErr
+	}
+	return result, nil
+}
+
+// Undo 撤销编辑：文件内容仍是编辑后的内容时恢复为编辑前的内容，已被再次修改的文件记为冲突
+func (s *tagEditServiceImpl) Undo(journalID string) (*TagEditResult, error) {
+	if journalID == "" {
+		journals, err := s.ListJournals()
+		if err != nil {
+			return nil, err
+		}
+		for _, journal := range journals {
+			if journal.UndoneAt == nil {
+				journalID = journal.ID
+				break
+			}
+		}
+		if journalID == "" {
+			return nil, fmt.Errorf("没有可以撤销的标签编辑")
+		}
+	}
+
+	journal, err := s.loadJournal(journalID)
+	if err != nil {
+		return nil, err
+	}
+	if journal.UndoneAt != nil {
+		return nil, fmt.Errorf("标签编辑 %s 已于 %s 撤销", journal.ID, journal.UndoneAt.Format(time.RFC3339))
+	}
+
+	result := &TagEditResult{JournalID: journal.ID, Operation: "undo", Scanned: len(journal.Changes), Changes: []TagFileChange{}}
+	for _, change := range journal.Changes {
+		data, err := os.ReadFile(change.Path)
+		if err != nil || string(data) != change.After {
+			result.Conflicts = append(result.Conflicts, change.Path)
+			continue
+		}
+		result.Matched++
+		if err := writeTagFile(change.Path, change.Before); err != nil {
+			return nil, err
+		}
+		result.Changes = append(result.Changes, TagFileChange{Path: change.Path, Before: change.After, After: change.Before})
+	}
+	result.Changed = len(result.Changes)
+
+	now := time.Now()
+	journal.UndoneAt = &now
+	if err := s.saveJournal(journal); err != nil {
+		return nil, err
+	}
+	logger.Infof("撤销标签编辑 %s: 恢复 %d 个文件，冲突 %d 个", journal.ID, result.Changed, len(result.Conflicts))
+	return result, nil
+}
+
+// ListJournals 列出编辑日志（最新的在前，不包含修改内容）
+func (s *tagEditServiceImpl) ListJournals() ([]*TagEditJournal, error) {
+	entries, err := os.ReadDir(s.journalDir)
+	if os.IsNotExist(err) {
+		return []*TagEditJournal{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取编辑日志目录失败: %v", err)
+	}
+
+	journals := make([]*TagEditJournal, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		journal, err := s.loadJournal(strings.TrimSuffix(entry.Name(), ".json"))
+		if err != nil {
+			logger.Warnf("跳过无法读取的编辑日志 %s: %v", entry.Name(), err)
+			continue
+		}
+		journal.Changes = nil
+		journals = append(journals, journal)
+	}
+	sort.Slice(journals, func(i, j int) bool {
+		return journals[i].CreatedAt.After(journals[j].CreatedAt)
+	})
+	return journals, nil
+}
+
+// saveJournal 保存编辑日志
+func (s *tagEditServiceImpl) saveJournal(journal *TagEditJournal) error {
+	if err := os.MkdirAll(s.journalDir, 0755); err != nil {
+		return fmt.Errorf("创建编辑日志目录失败: %v", err)
+	}
+	data, err := json.MarshalIndent(journal, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化编辑日志失败: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(s.journalDir, journal.ID+".json"), data, 0644); err != nil {
+		return fmt.Errorf("保存编辑日志失败: %v", err)
+	}
+	return nil
+}
+
+// loadJournal 读取编辑日志
+func (s *tagEditServiceImpl) loadJournal(id string) (*TagEditJournal, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) || strings.Contains(id, "..") {
+		return nil, fmt.Errorf("无效的编辑日志ID: %s", id)
+	}
+	data, err := os.ReadFile(filepath.Join(s.journalDir, id+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("编辑日志不存在: %s", id)
+	}
+	var journal TagEditJournal
+	if err := json.Unmarshal(data, &journal); err != nil {
+		return nil, fmt.Errorf("解析编辑日志失败: %v", err)
+	}
+	return &journal, nil
+}
+
+// resolveTagEditDir 解析标签目录：为空时为 tags/，不以 tags/、images/ 开头的相对路径按 tags/ 解析；
+// 解析后的路径（包括符号链接）必须位于数据目录的 tags/ 或 images/ 之内，避免通过绝对路径或 .. 修改其它文件
+func resolveTagEditDir(dir string) (string, error) {
+	requested := dir
+	if dir == "" {
+		dir = "tags/"
+	} else if !filepath.IsAbs(dir) && !strings.HasPrefix(dir, "tags/") && !strings.HasPrefix(dir, "images/") {
+		dir = "tags/" + dir
+	}
+
+	roots := []string{"tags", "images"}
+	if pathManager := paths.GetPathManager(); pathManager != nil {
+		dir = pathManager.ResolvePath(dir)
+		roots = []string{pathManager.GetTagsDir(), pathManager.GetImagesDir()}
+	}
+
+	resolved, err := realPath(dir)
+	if err != nil {
+		return "", fmt.Errorf("解析标签目录失败: %v", err)
+	}
+	for _, root := range roots {
+		if root, err := realPath(root); err == nil && isWithinDir(root, resolved) {
+			return resolved, nil
+		}
+	}
+	return "", fmt.Errorf("标签目录必须位于数据目录的 tags/ 或 images/ 之内: %s", requested)
+}
+
+// realPath 转换为绝对路径并解析符号链接（路径不存在时只做清理）
+func realPath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		return resolved, nil
+	}
+	return abs, nil
+}
+
+// listTagFiles 列出目录中的 TXT 标签文件（按路径排序）
+// 描述模式的 .caption 文件是自然语言描述而不是逗号分隔的标签列表，不参与批量编辑
+func listTagFiles(dir string, recursive bool) ([]string, error) {
+	info, err := os.Stat(dir)
+	if err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("标签目录不存在: %s", dir)
+	}
+
+	var files []string
+	err = filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != dir && !recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.EqualFold(filepath.Ext(path), ".txt") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("读取标签目录失败: %v", err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// splitTagList 拆分逗号分隔的标签，去掉空白和空标签
+func splitTagList(text string) []string {
+	var tags []string
+	for _, tag := range strings.Split(text, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// writeTagFile 写入标签文件，保留原有的文件权限
+func writeTagFile(path, content string) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.WriteFile(path, []byte(content), mode); err != nil {
+		return fmt.Errorf("写入标签文件失败: %v", err)
+	}
+	return nil
+}
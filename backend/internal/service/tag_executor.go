@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"pixiv-tailor/backend/internal/repository"
+	"pixiv-tailor/backend/internal/tagger"
+	"pixiv-tailor/backend/pkg/models"
+)
+
+// tagTaskExecutor 标签任务执行器
+type tagTaskExecutor struct{}
+
+// NewTagTaskExecutor 创建标签任务执行器
+func NewTagTaskExecutor() TaskExecutor {
+	return &tagTaskExecutor{}
+}
+
+// Schema 标签任务配置 Schema
+func (e *tagTaskExecutor) Schema() *ConfigSchema {
+	return TagConfigSchema()
+}
+
+// Validate 校验标签任务配置
+func (e *tagTaskExecutor) Validate(config map[string]interface{}) error {
+	var cfg TagTaskConfig
+	if err := DecodeConfig(config, &cfg); err != nil {
+		return err
+	}
+	if len(cfg.InputDir) == 0 {
+		return newFieldError("input_dir", "输入目录不能为空")
+	}
+	if _, err := tagger.CompileTagPatterns(cfg.SkipTags); err != nil {
+		return newFieldError("skip_tags", err.Error())
+	}
+	if cfg.OutputMode == tagger.OutputModeDataset && tagger.IsAggregateSaveType(cfg.SaveType) {
+		return newFieldError("save_type", "数据集导出需要每张图片一个标签文件，不支持 csv、jsonl")
+	}
+	if cfg.Caption && cfg.CaptionFile && tagger.IsAggregateSaveType(cfg.SaveType) {
+		return newFieldError("caption_file", "csv、jsonl 将描述写入汇总文件，不支持单独保存 .caption 文件")
+	}
+	if strings.ContainsAny(cfg.DatasetConcept, `/\`) || strings.Contains(cfg.DatasetConcept, "..") {
+		return newFieldError("dataset_concept", "概念名不能包含路径分隔符")
+	}
+	for category, threshold := range cfg.CategoryThresholds {
+		if !isTagCategory(category) {
+			return newFieldError("category_thresholds", fmt.Sprintf("未知的标签类别: %s", category))
+		}
+		if threshold < 0 || threshold > 1 {
+			return newFieldError("category_thresholds", fmt.Sprintf("类别 %s 的阈值必须在 0 到 1 之间", category))
+		}
+	}
+	return nil
+}
+
+// Execute 执行标签任务
+func (e *tagTaskExecutor) Execute(ctx context.Context, task *repository.Task, config map[string]interface{}, reporter TaskReporter) error {
+	reporter.Log("info", "开始执行标签任务")
+
+	var cfg TagTaskConfig
+	if err := DecodeConfig(config, &cfg); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// 更新进度到10% - 初始化阶段
+	reporter.Stage(10, "初始化")
+	reporter.Log("info", "标签任务初始化完成 (10%)")
+
+	// 创建 tagger 实例
+	wd14Tagger, err := tagger.NewWD14Tagger()
+	if err != nil {
+		return fmt.Errorf("创建标签器失败: %v", err)
+	}
+
+	inputDirs := []string(cfg.InputDir)
+	if len(inputDirs) == 0 {
+		return fmt.Errorf("输入目录不能为空")
+	}
+
+	// 默认值已在创建任务时按 Schema 填充
+	outputDir := cfg.OutputDir
+	tagRequest := &models.TagRequest{
+		InputDir:           inputDirs, // 使用数组
+		OutputDir:          outputDir,
+		Analyzer:           cfg.Analyzer,
+		TagOrder:           cfg.TagOrder,
+		SaveType:           cfg.SaveType,
+		Limit:              cfg.Limit,
+		SkipTags:           append([]string{}, cfg.SkipTags...),
+		ExtendTags:         append([]string{}, cfg.ExtendTags...),
+		ExtendPosition:     cfg.ExtendPosition,
+		Threshold:          cfg.Threshold,
+		CharacterThreshold: cfg.CharacterThreshold,
+		CategoryThresholds: cfg.CategoryThresholds,
+		Concurrency:        cfg.Concurrency,
+		Force:              cfg.Force,
+		OutputMode:         cfg.OutputMode,
+		DatasetRepeats:     cfg.DatasetRepeats,
+		DatasetConcept:     cfg.DatasetConcept,
+		ReplaceUnderscore:  cfg.ReplaceUnderscore,
+		EscapeParentheses:  cfg.EscapeParentheses,
+		Caption:            cfg.Caption,
+		CaptionBackend:     cfg.CaptionBackend,
+		CaptionFile:        cfg.CaptionFile,
+		CaptionTemplate:    cfg.CaptionTemplate,
+		ExportName:         "tags_" + task.ID, // CSV、JSONL 每个任务一个文件
+		ImageFiles:         cfg.ImageFiles,    // 重试失败条目时只处理指定的图片
+	}
+
+	reporter.Log("info", fmt.Sprintf("配置完成: 输入目录数量=%d, 输出目录=%s, 限制=%d", len(inputDirs), outputDir, tagRequest.Limit))
+
+	// 更新进度到20% - 配置完成
+	reporter.Stage(20, "配置完成")
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// 执行标签生成
+	reporter.Stage(30, "开始生成标签")
+	reporter.Log("info", "开始调用 WD14 Tagger 生成标签...")
+
+	// 创建进度回调函数，使用内部变量缓存上次进度，减少不必要的更新
+	lastProgress := -1
+	progressCallback := func(current int, total int) {
+		// 计算进度：30%-95% (生成标签占65%)
+		progress := 30 + int(float64(current)/float64(total)*65)
+
+		// 只在进度变化超过5%或完成时更新，减少WebSocket消息频率
+		if progress != lastProgress && (progress-lastProgress >= 5 || progress == 100 || current == total) {
+			reporter.Stage(progress, fmt.Sprintf("生成标签 (%d/%d)", current, total))
+			lastProgress = progress
+		}
+	}
+
+	// 断点续传：跳过已完成打标的图片，并记录每张完成的图片
+	if checkpoints := reporter.Checkpoints(); len(checkpoints) > 0 {
+		wd14Tagger.SetCompletedImages(checkpoints)
+	}
+	wd14Tagger.SetTagCategoryFunc(TagCategoryRank)
+	wd14Tagger.SetTagClassifyFunc(func(tag string) string {
+		return string(ClassifyTag(tag))
+	})
+	wd14Tagger.SetOutputCallback(func(imagePath, outputPath, kind string) {
+		if kind == tagger.OutputKindImage {
+			reporter.Artifact(outputPath, ArtifactRoleImage)
+			return
+		}
+		reporter.Artifact(outputPath, ArtifactRoleTag)
+	})
+	wd14Tagger.SetItemCallback(func(imagePath string, itemErr error) {
+		if itemErr != nil {
+			// 记录失败条目，供"重试失败条目"使用
+			reporter.ItemFailed(imagePath, filepath.Base(imagePath), itemErr)
+			return
+		}
+		reporter.ItemDone(imagePath)
+	})
+
+	// 保存任务结果信息（包括失败条目数量和处理统计）
+	saveResult := func() {
+		tagResult := map[string]interface{}{
+			"failed_images":  reporter.FailedItemCount(),
+			"skipped_images": wd14Tagger.Stats().UpToDate, // 标签文件已是最新而跳过的图片数量
+			"stats":          wd14Tagger.Stats(),
+			"thresholds":     wd14Tagger.Thresholds(), // 使用的置信度阈值，便于复现结果
+		}
+		if cfg.RetryOf != "" {
+			tagResult["retry_of"] = cfg.RetryOf
+		}
+		reporter.Result(tagResult)
+	}
+
+	if err := wd14Tagger.GenerateTagsWithContext(ctx, tagRequest, progressCallback, reporter.Log); err != nil {
+		if ctx.Err() != nil {
+			// 任务被停止或取消：保留已处理部分的结果，已完成的图片可通过断点续传跳过
+			saveResult()
+			return ctx.Err()
+		}
+		return fmt.Errorf("生成标签失败: %v", err)
+	}
+
+	// 更新进度到100% - 任务完成
+	reporter.Stage(100, "任务完成")
+	reporter.Log("info", "标签生成完成 (100%)")
+
+	saveResult()
+
+	reporter.Log("info", "标签任务完成！")
+	return nil
+}
+
+// Cleanup 标签任务无需额外清理
+func (e *tagTaskExecutor) Cleanup(task *repository.Task) {}
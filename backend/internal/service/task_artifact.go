@@ -0,0 +1,132 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"pixiv-tailor/backend/internal/repository"
+	"pixiv-tailor/backend/pkg/paths"
+)
+
+// 产出文件的用途
+const (
+	ArtifactRoleImage   = "image"   // 图片（爬取或生成）
+	ArtifactRoleTag     = "tag"     // 标签文件
+	ArtifactRoleSidecar = "sidecar" // 附属文件（如生成参数）
+)
+
+// recordArtifact 计算文件大小、哈希和类型并记录为任务的产出文件
+func (s *taskServiceImpl) recordArtifact(taskID, path, role string) error {
+	sum, size, err := fileSHA256(path)
+	if err != nil {
+		return err
+	}
+
+	artifact := &repository.TaskArtifact{
+		TaskID:    taskID,
+		Path:      artifactRelPath(path),
+		Size:      size,
+		SHA256:    sum,
+		MimeType:  artifactMimeType(path),
+		Role:      role,
+		CreatedAt: time.Now(),
+	}
+	if err := s.storage.AddTaskArtifact(artifact); err != nil {
+		return fmt.Errorf("记录产出文件失败: %v", err)
+	}
+	return nil
+}
+
+// fileSHA256 计算文件的 sha256（十六进制）和大小
+func fileSHA256(path string) (string, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", 0, fmt.Errorf("打开文件失败: %v", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return "", 0, fmt.Errorf("读取文件失败: %v", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), size, nil
+}
+
+// ListTaskArtifacts 列出任务的产出文件，role 为空时列出全部
+func (s *taskServiceImpl) ListTaskArtifacts(taskID, role string) ([]*repository.TaskArtifact, error) {
+	if _, err := s.GetTask(taskID); err != nil {
+		return nil, fmt.Errorf("获取任务失败: %v", err)
+	}
+	artifacts, err := s.storage.ListTaskArtifacts(taskID, role)
+	if err != nil {
+		return nil, fmt.Errorf("获取产出文件失败: %v", err)
+	}
+	return artifacts, nil
+}
+
+// GetTaskArtifact 获取任务的单个产出文件记录及其在磁盘上的绝对路径
+func (s *taskServiceImpl) GetTaskArtifact(taskID string, id int64) (*repository.TaskArtifact, string, error) {
+	artifact, err := s.storage.GetTaskArtifact(taskID, id)
+	if err != nil {
+		return nil, "", fmt.Errorf("产出文件不存在: %v", err)
+	}
+
+	path := ResolveArtifactPath(artifact.Path)
+	if _, err := os.Stat(path); err != nil {
+		return artifact, "", fmt.Errorf("产出文件已被删除: %s", artifact.Path)
+	}
+	return artifact, path, nil
+}
+
+// ResolveArtifactPath 将产出文件记录中的路径转换为绝对路径
+func ResolveArtifactPath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	pathManager := paths.GetPathManager()
+	if pathManager == nil {
+		return filepath.FromSlash(path)
+	}
+	return filepath.Join(pathManager.GetDataDir(), filepath.FromSlash(path))
+}
+
+// artifactRelPath 转换为相对于数据目录的路径（使用 / 分隔），数据目录之外的文件保留绝对路径
+func artifactRelPath(path string) string {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	pathManager := paths.GetPathManager()
+	if pathManager == nil {
+		return filepath.ToSlash(absPath)
+	}
+
+	rel, err := filepath.Rel(pathManager.GetDataDir(), absPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return filepath.ToSlash(absPath)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// artifactMimeType 根据扩展名判断文件类型
+func artifactMimeType(path string) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	switch ext {
+	case ".txt", ".caption":
+		return "text/plain; charset=utf-8"
+	case ".json":
+		return "application/json"
+	}
+	if mimeType := mime.TypeByExtension(ext); mimeType != "" {
+		return mimeType
+	}
+	return "application/octet-stream"
+}
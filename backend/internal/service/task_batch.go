@@ -0,0 +1,227 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"pixiv-tailor/backend/internal/logger"
+	"pixiv-tailor/backend/internal/repository"
+)
+
+// maxBatchTasks 单次批量操作最多处理的任务数量
+const maxBatchTasks = 1000
+
+// 批量操作支持的动作
+const (
+	BatchActionStart      = "start"
+	BatchActionResume     = "resume"
+	BatchActionStop       = "stop"
+	BatchActionCancel     = "cancel"
+	BatchActionDelete     = "delete"
+	BatchActionPrioritize = "prioritize"
+)
+
+// BatchTaskResult 批量操作中单个任务的处理结果
+type BatchTaskResult struct {
+	TaskID  string `json:"task_id"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// CreateTasks 批量创建同类型的任务（每个配置一个任务）
+// 先校验全部配置，任意一个无效时不创建任何任务；字段错误带有配置序号前缀，如 [2].user_id
+func (s *taskServiceImpl) CreateTasks(taskType string, configs []string, priority int) ([]*repository.Task, error) {
+	return s.createTaskBatch(taskType, configs, priority, "")
+}
+
+// createTaskBatch 校验全部配置后逐个创建任务，groupID 不为空时任务属于同一任务组
+func (s *taskServiceImpl) createTaskBatch(taskType string, configs []string, priority int, groupID string) ([]*repository.Task, error) {
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("至少需要一个任务配置")
+	}
+	if len(configs) > maxBatchTasks {
+		return nil, fmt.Errorf("单次最多创建 %d 个任务", maxBatchTasks)
+	}
+
+	executor, exists := s.getExecutor(taskType)
+	if !exists {
+		return nil, fmt.Errorf("不支持的任务类型: %s", taskType)
+	}
+
+	var fieldErrors []FieldError
+	for i, config := range configs {
+		var configMap map[string]interface{}
+		if err := json.Unmarshal([]byte(config), &configMap); err != nil {
+			fieldErrors = append(fieldErrors, FieldError{Field: fmt.Sprintf("[%d]", i), Message: fmt.Sprintf("配置格式无效: %v", err)})
+			continue
+		}
+		err := validateTaskConfig(executor, configMap)
+		if err == nil {
+			continue
+		}
+		var validationErr *ConfigValidationError
+		if !errors.As(err, &validationErr) {
+			fieldErrors = append(fieldErrors, FieldError{Field: fmt.Sprintf("[%d]", i), Message: err.Error()})
+			continue
+		}
+		for _, fieldErr := range validationErr.Errors {
+			fieldErr.Field = fmt.Sprintf("[%d].%s", i, fieldErr.Field)
+			fieldErrors = append(fieldErrors, fieldErr)
+		}
+	}
+	if len(fieldErrors) > 0 {
+		return nil, &ConfigValidationError{Errors: fieldErrors}
+	}
+
+	tasks := make([]*repository.Task, 0, len(configs))
+	for _, config := range configs {
+		task, err := s.createTask(taskType, config, priority, groupID)
+		if err != nil {
+			return tasks, fmt.Errorf("已创建 %d 个任务，创建第 %d 个任务失败: %v", len(tasks), len(tasks)+1, err)
+		}
+		tasks = append(tasks, task)
+	}
+	logger.Infof("批量创建 %d 个 %s 任务", len(tasks), taskType)
+	return tasks, nil
+}
+
+// FindTaskIDs 按筛选条件查找任务ID，用于批量操作
+// 筛选条件不能为空，匹配的任务超过上限时返回错误，避免误操作大量任务
+func (s *taskServiceImpl) FindTaskIDs(filter *repository.TaskFilter) ([]string, error) {
+	if filter == nil || (filter.Status == "" && filter.Type == "" && len(filter.Labels) == 0 &&
+		filter.Search == "" && filter.CreatedFrom == nil && filter.CreatedTo == nil && filter.GroupID == "") {
+		return nil, fmt.Errorf("筛选条件不能为空")
+	}
+
+	tasks, err := s.storage.SearchTasks(filter, maxBatchTasks+1, 0)
+	if err != nil {
+		return nil, fmt.Errorf("查询任务失败: %v", err)
+	}
+	if len(tasks) > maxBatchTasks {
+		return nil, fmt.Errorf("匹配的任务超过 %d 个，请缩小筛选范围", maxBatchTasks)
+	}
+
+	ids := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+	}
+	return ids, nil
+}
+
+// BatchTasks 对一组任务执行相同的操作，单个任务失败不影响其他任务
+// priority 只在 prioritize 动作中使用；purgeFiles 只在 delete 动作中使用，默认保留产出文件
+func (s *taskServiceImpl) BatchTasks(action string, ids []string, priority int, purgeFiles bool) ([]*BatchTaskResult, error) {
+	var apply func(id string) error
+	switch action {
+	case BatchActionStart:
+		apply = s.StartTask
+	case BatchActionResume:
+		apply = s.ResumeTask
+	case BatchActionStop:
+		apply = s.StopTask
+	case BatchActionCancel:
+		apply = s.CancelTask
+	case BatchActionDelete:
+		apply = func(id string) error {
+			_, err := s.DeleteTaskWithOptions(id, purgeFiles)
+			return err
+		}
+	case BatchActionPrioritize:
+		apply = func(id string) error {
+			return s.SetTaskPriority(id, priority)
+		}
+	default:
+		return nil, fmt.Errorf("不支持的批量操作: %s", action)
+	}
+
+	if len(ids) > maxBatchTasks {
+		return nil, fmt.Errorf("单次最多操作 %d 个任务", maxBatchTasks)
+	}
+
+	results := make([]*BatchTaskResult, 0, len(ids))
+	seen := make(map[string]bool)
+	succeeded := 0
+	for _, id := range ids {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		result := &BatchTaskResult{TaskID: id, Success: true}
+		if err := apply(id); err != nil {
+			result.Success = false
+			result.Error = err.Error()
+		} else {
+			succeeded++
+		}
+		results = append(results, result)
+	}
+	logger.Infof("批量操作 %s: %d/%d 个任务成功", action, succeeded, len(results))
+	return results, nil
+}
+
+// SetTaskPriority 设置任务优先级，任务在等待队列中时按新的优先级重新排队
+func (s *taskServiceImpl) SetTaskPriority(id string, priority int) error {
+	task, err := s.GetTask(id)
+	if err != nil {
+		return fmt.Errorf("获取任务失败: %v", err)
+	}
+	if err := s.storage.UpdateTaskPriority(id, priority); err != nil {
+		return fmt.Errorf("更新任务优先级失败: %v", err)
+	}
+	task.Priority = priority
+
+	s.queueMutex.Lock()
+	defer s.queueMutex.Unlock()
+	for _, waitingID := range s.waitingTasksByType[task.Type] {
+		if waitingID == id {
+			s.enqueueWaitingTaskInLock(task)
+			break
+		}
+	}
+	return nil
+}
+
+// enqueueWaitingTaskInLock 按优先级将任务加入对应类型的等待队列（优先级相同时先进先出）
+// 注意：调用此方法时必须持有 queueMutex
+func (s *taskServiceImpl) enqueueWaitingTaskInLock(task *repository.Task) {
+	queue := removeTaskID(s.waitingTasksByType[task.Type], task.ID)
+
+	position := len(queue)
+	for i, waitingID := range queue {
+		waitingPriority := 0
+		if waitingTask, err := s.storage.GetTask(waitingID); err == nil {
+			waitingPriority = waitingTask.Priority
+		}
+		if waitingPriority < task.Priority {
+			position = i
+			break
+		}
+	}
+
+	queue = append(queue, "")
+	copy(queue[position+1:], queue[position:])
+	queue[position] = task.ID
+	s.waitingTasksByType[task.Type] = queue
+}
+
+// removeFromWaitingQueue 从等待队列中移除任务
+func (s *taskServiceImpl) removeFromWaitingQueue(id string) {
+	s.queueMutex.Lock()
+	defer s.queueMutex.Unlock()
+	for taskType, queue := range s.waitingTasksByType {
+		s.waitingTasksByType[taskType] = removeTaskID(queue, id)
+	}
+}
+
+// removeTaskID 返回去掉指定任务ID后的队列
+func removeTaskID(queue []string, id string) []string {
+	result := make([]string, 0, len(queue))
+	for _, waitingID := range queue {
+		if waitingID != id {
+			result = append(result, waitingID)
+		}
+	}
+	return result
+}
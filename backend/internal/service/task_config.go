@@ -0,0 +1,389 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"pixiv-tailor/backend/internal/config"
+	"pixiv-tailor/backend/internal/tagger"
+)
+
+// ============================================================================
+// 配置 Schema（JSON Schema 子集）
+// ============================================================================
+
+// ConfigSchema 任务配置 Schema，序列化后兼容 JSON Schema，供前端和脚本生成表单
+type ConfigSchema struct {
+	Title                string                     `json:"title,omitempty"`
+	Type                 string                     `json:"type"`
+	Properties           map[string]*SchemaProperty `json:"properties"`
+	Required             []string                   `json:"required,omitempty"`
+	AdditionalProperties bool                       `json:"additionalProperties"`
+}
+
+// SchemaProperty 配置字段定义
+type SchemaProperty struct {
+	Type        string            `json:"type,omitempty"` // string, integer, number, boolean, array, object
+	Description string            `json:"description,omitempty"`
+	Default     interface{}       `json:"default,omitempty"`
+	Enum        []string          `json:"enum,omitempty"`
+	Minimum     *float64          `json:"minimum,omitempty"`
+	Maximum     *float64          `json:"maximum,omitempty"`
+	Items       *SchemaProperty   `json:"items,omitempty"`
+	AnyOf       []*SchemaProperty `json:"anyOf,omitempty"` // 满足任意一个即可（如字符串或字符串数组）
+}
+
+// FieldError 字段级校验错误
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ConfigValidationError 任务配置校验错误，包含所有字段级错误
+type ConfigValidationError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+// Error 实现 error 接口
+func (e *ConfigValidationError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, fieldErr := range e.Errors {
+		messages = append(messages, fmt.Sprintf("%s: %s", fieldErr.Field, fieldErr.Message))
+	}
+	return fmt.Sprintf("任务配置无效: %s", strings.Join(messages, "; "))
+}
+
+// newFieldError 创建只包含一个字段错误的校验错误
+func newFieldError(field, message string) *ConfigValidationError {
+	return &ConfigValidationError{Errors: []FieldError{{Field: field, Message: message}}}
+}
+
+// Apply 按 Schema 校验配置并填充默认值（直接修改 config）
+// 值为 null 的字段视为未设置；带默认值的字符串字段为空字符串时也使用默认值
+func (schema *ConfigSchema) Apply(config map[string]interface{}) error {
+	var fieldErrors []FieldError
+
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		prop := schema.Properties[name]
+		value, exists := config[name]
+		if str, ok := value.(string); ok && str == "" && prop.Default != nil {
+			exists = false
+		}
+		if !exists || value == nil {
+			if prop.Default != nil {
+				config[name] = prop.Default
+			} else if contains(schema.Required, name) {
+				fieldErrors = append(fieldErrors, FieldError{Field: name, Message: "必填字段"})
+			}
+			continue
+		}
+		if message := prop.check(value); message != "" {
+			fieldErrors = append(fieldErrors, FieldError{Field: name, Message: message})
+		}
+	}
+
+	if !schema.AdditionalProperties {
+		unknown := make([]string, 0)
+		for name := range config {
+			if _, exists := schema.Properties[name]; !exists {
+				unknown = append(unknown, name)
+			}
+		}
+		sort.Strings(unknown)
+		for _, name := range unknown {
+			fieldErrors = append(fieldErrors, FieldError{Field: name, Message: "未知字段"})
+		}
+	}
+
+	if len(fieldErrors) > 0 {
+		return &ConfigValidationError{Errors: fieldErrors}
+	}
+	return nil
+}
+
+// check 校验单个字段的值，返回空字符串表示通过
+func (prop *SchemaProperty) check(value interface{}) string {
+	if len(prop.AnyOf) > 0 {
+		expected := make([]string, 0, len(prop.AnyOf))
+		for _, candidate := range prop.AnyOf {
+			if candidate.check(value) == "" {
+				return ""
+			}
+			expected = append(expected, candidate.Type)
+		}
+		return fmt.Sprintf("类型必须是 %s 之一", strings.Join(expected, "/"))
+	}
+
+	switch prop.Type {
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return "必须是字符串"
+		}
+		if len(prop.Enum) > 0 && !contains(prop.Enum, str) {
+			return fmt.Sprintf("必须是以下值之一: %s", strings.Join(prop.Enum, ", "))
+		}
+	case "integer", "number":
+		number, ok := value.(float64)
+		if !ok {
+			return "必须是数字"
+		}
+		if prop.Type == "integer" && number != math.Trunc(number) {
+			return "必须是整数"
+		}
+		if prop.Minimum != nil && number < *prop.Minimum {
+			return fmt.Sprintf("不能小于 %v", *prop.Minimum)
+		}
+		if prop.Maximum != nil && number > *prop.Maximum {
+			return fmt.Sprintf("不能大于 %v", *prop.Maximum)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return "必须是布尔值"
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return "必须是数组"
+		}
+		if prop.Items != nil {
+			for i, item := range items {
+				if message := prop.Items.check(item); message != "" {
+					return fmt.Sprintf("第 %d 项%s", i+1, message)
+				}
+			}
+		}
+	case "object":
+		if _, ok := value.(map[string]interface{}); !ok {
+			return "必须是对象"
+		}
+	}
+	return ""
+}
+
+// DecodeConfig 将已校验的配置转换为类型化的配置结构（供执行器使用）
+func DecodeConfig(config map[string]interface{}, out interface{}) error {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("序列化配置失败: %v", err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("解析配置失败: %v", err)
+	}
+	return nil
+}
+
+// float64Ptr 返回 float64 指针（用于 Schema 的最小/最大值）
+func float64Ptr(v float64) *float64 {
+	return &v
+}
+
+// stringListDefault 字符串列表字段的默认值，列表为空时没有默认值
+func stringListDefault(values []string) interface{} {
+	if len(values) == 0 {
+		return nil
+	}
+	return append([]string{}, values...)
+}
+
+// StringList 字符串列表，JSON 中既可以是单个字符串也可以是字符串数组
+type StringList []string
+
+// UnmarshalJSON 支持字符串或字符串数组，自动忽略空字符串
+func (l *StringList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*l = nil
+		if single != "" {
+			*l = StringList{single}
+		}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*l = nil
+	for _, item := range list {
+		if item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// ============================================================================
+// 爬虫任务配置
+// ============================================================================
+
+// CrawlTaskConfig 爬虫任务配置
+type CrawlTaskConfig struct {
+	Type         string           `json:"type"` // 爬取类型：tag, user, illust
+	Query        string           `json:"query"`
+	Order        string           `json:"order"`
+	Mode         string           `json:"mode"`
+	Limit        int              `json:"limit"`
+	UserID       int              `json:"user_id"`
+	IllustID     int              `json:"illust_id"`
+	MaxImages    int              `json:"max_images"`
+	Delay        float64          `json:"delay"`
+	Cookie       string           `json:"cookie"`
+	ProxyEnabled bool             `json:"proxy_enabled"`
+	ProxyURL     string           `json:"proxy_url"`
+	RetryItems   []CrawlRetryItem `json:"retry_items,omitempty"` // 重试失败条目时要下载的图片
+	RetryOf      string           `json:"retry_of,omitempty"`    // 重试来源任务ID
+}
+
+// CrawlRetryItem 重试下载的图片
+type CrawlRetryItem struct {
+	URL      string `json:"url"`
+	Filename string `json:"filename"`
+}
+
+// CrawlConfigSchema 爬虫任务配置 Schema
+func CrawlConfigSchema() *ConfigSchema {
+	return &ConfigSchema{
+		Title: "爬虫任务",
+		Type:  "object",
+		Properties: map[string]*SchemaProperty{
+			"type":          {Type: "string", Description: "爬取类型（重试失败条目时可省略）", Enum: []string{"tag", "user", "illust"}},
+			"query":         {Type: "string", Description: "搜索标签（type=tag 时必填）"},
+			"order":         {Type: "string", Description: "排序方式", Default: "date_d"},
+			"mode":          {Type: "string", Description: "搜索模式", Default: "all", Enum: []string{"all", "safe", "r18"}},
+			"limit":         {Type: "integer", Description: "爬取数量", Default: 100, Minimum: float64Ptr(0)},
+			"user_id":       {Type: "integer", Description: "用户ID（type=user 时必填）", Minimum: float64Ptr(1)},
+			"illust_id":     {Type: "integer", Description: "作品ID（type=illust 时必填）", Minimum: float64Ptr(1)},
+			"max_images":    {Type: "integer", Description: "最多下载图片数量，0 表示不限制", Minimum: float64Ptr(0)},
+			"delay":         {Type: "number", Description: "请求间隔（秒）", Minimum: float64Ptr(0)},
+			"cookie":        {Type: "string", Description: "Pixiv Cookie，default 表示使用配置文件中的Cookie"},
+			"proxy_enabled": {Type: "boolean", Description: "是否启用代理"},
+			"proxy_url":     {Type: "string", Description: "代理地址"},
+			"retry_items":   {Type: "array", Description: "重试失败条目时要下载的图片", Items: &SchemaProperty{Type: "object"}},
+			"retry_of":      {Type: "string", Description: "重试来源任务ID"},
+		},
+		AdditionalProperties: true,
+	}
+}
+
+// ============================================================================
+// 标签任务配置
+// ============================================================================
+
+// maxTagConcurrency 标签任务同时识别的图片数量上限
+const maxTagConcurrency = 16
+
+// TagTaskConfig 标签任务配置
+type TagTaskConfig struct {
+	InputDir           StringList         `json:"input_dir"`
+	OutputDir          string             `json:"output_dir"`
+	SaveType           string             `json:"save_type"`
+	Analyzer           string             `json:"analyzer"` // 标签后端，为空时使用配置
+	Limit              int                `json:"limit"`
+	SkipTags           []string           `json:"skip_tags"`
+	ExtendTags         []string           `json:"extend_tags"`
+	ExtendPosition     string             `json:"extend_position"`
+	TagOrder           string             `json:"tag_order"`
+	Threshold          float64            `json:"threshold"`             // 置信度阈值，0 表示使用配置
+	CharacterThreshold float64            `json:"character_threshold"`   // 角色标签阈值，0 表示使用配置
+	CategoryThresholds map[string]float64 `json:"category_thresholds"`   // 按标签类别设置的阈值
+	Concurrency        int                `json:"concurrency"`           // 同时识别的图片数量，0 表示使用配置
+	Force              bool               `json:"force"`                 // 强制重新生成已是最新的标签文件
+	OutputMode         string             `json:"output_mode"`           // 输出方式：flat、mirror、beside、dataset
+	DatasetRepeats     int                `json:"dataset_repeats"`       // 数据集导出时的重复次数
+	DatasetConcept     string             `json:"dataset_concept"`       // 数据集导出时的概念名
+	ReplaceUnderscore  bool               `json:"replace_underscore"`    // 下划线替换为空格
+	EscapeParentheses  bool               `json:"escape_parentheses"`    // 转义括号
+	Caption            bool               `json:"caption"`               // 描述模式
+	CaptionBackend     string             `json:"caption_backend"`       // 描述后端
+	CaptionFile        bool               `json:"caption_file"`          // 描述单独保存为 .caption 文件
+	CaptionTemplate    string             `json:"caption_template"`      // 描述与标签的组合模板
+	ImageFiles         []string           `json:"image_files,omitempty"` // 重试失败条目时只处理这些图片
+	RetryOf            string             `json:"retry_of,omitempty"`
+}
+
+// TagConfigSchema 标签任务配置 Schema（tag_order、skip_tags、extend_tags 的默认值取自配置中的 wd14tagger）
+func TagConfigSchema() *ConfigSchema {
+	stringItems := &SchemaProperty{Type: "string"}
+	taggerConfig := config.GetAIConfig().WD14Tagger
+	tagOrder := taggerConfig.TagOrder
+	if tagOrder == "" {
+		tagOrder = tagger.TagOrderScore
+	}
+	return &ConfigSchema{
+		Title: "标签任务",
+		Type:  "object",
+		Properties: map[string]*SchemaProperty{
+			"input_dir": {Description: "输入目录，可以是单个路径或路径数组", AnyOf: []*SchemaProperty{
+				{Type: "string"},
+				{Type: "array", Items: stringItems},
+			}},
+			"output_dir":          {Type: "string", Description: "输出目录", Default: "tags/"},
+			"analyzer":            {Type: "string", Description: "标签后端，为空时使用配置中的 wd14tagger.backend", Enum: tagger.TaggerNames()},
+			"save_type":           {Type: "string", Description: "保存格式：每张图片一个 TXT 或 JSON 文件，或整个任务一个 CSV、JSONL 文件", Default: "txt", Enum: []string{"txt", "json", "csv", "jsonl"}},
+			"limit":               {Type: "integer", Description: "最多处理图片数量", Default: 100, Minimum: float64Ptr(0)},
+			"skip_tags":           {Type: "array", Description: "跳过的标签，支持 * ? 通配符和 /正则表达式/，未指定时使用配置，[] 表示不跳过", Default: stringListDefault(taggerConfig.SkipTags), Items: stringItems},
+			"extend_tags":         {Type: "array", Description: "追加的标签，未指定时使用配置，[] 表示不追加", Default: stringListDefault(taggerConfig.ExtendTags), Items: stringItems},
+			"extend_position":     {Type: "string", Description: "追加标签的位置", Default: "append", Enum: []string{"append", "prepend"}},
+			"threshold":           {Type: "number", Description: "置信度阈值，0 表示使用配置", Minimum: float64Ptr(0), Maximum: float64Ptr(1)},
+			"character_threshold": {Type: "number", Description: "角色标签置信度阈值，0 表示使用配置", Minimum: float64Ptr(0), Maximum: float64Ptr(1)},
+			"concurrency":         {Type: "integer", Description: "同时识别的图片数量，0 表示使用配置", Minimum: float64Ptr(0), Maximum: float64Ptr(maxTagConcurrency)},
+			"force":               {Type: "boolean", Description: "强制重新生成标签（默认跳过标签文件已是最新的图片）", Default: false},
+			"output_mode":         {Type: "string", Description: "输出方式：全部写入输出目录、按输入目录结构输出、写在图片旁边或导出为 kohya 数据集", Default: "flat", Enum: []string{"flat", "mirror", "beside", "dataset"}},
+			"dataset_repeats":     {Type: "integer", Description: "数据集导出时每张图片的重复次数（文件夹名 N_概念名 中的 N）", Default: tagger.DefaultDatasetRepeats, Minimum: float64Ptr(1)},
+			"dataset_concept":     {Type: "string", Description: "数据集导出时的概念名，为空时使用输入目录名"},
+			"replace_underscore":  {Type: "boolean", Description: "标签中的下划线替换为空格（颜文字除外）", Default: false},
+			"escape_parentheses":  {Type: "boolean", Description: "转义标签中的括号（如 \\(vocaloid\\)），便于直接用作提示词", Default: false},
+			"caption":             {Type: "boolean", Description: "描述模式：另外生成自然语言描述，保存在 JSON 输出中并按模板与标签组合", Default: false},
+			"caption_backend":     {Type: "string", Description: "描述后端：WebUI 内置 CLIP 或配置中的描述服务", Default: tagger.BackendCLIP, Enum: tagger.CaptionBackendNames()},
+			"caption_file":        {Type: "boolean", Description: "描述单独保存为 .caption 文件（否则按模板写入 TXT 标签文件）", Default: false},
+			"caption_template":    {Type: "string", Description: "描述与标签的组合模板，{caption} 为描述，{tags} 为标签", Default: tagger.DefaultCaptionTemplate},
+			"category_thresholds": {Type: "object", Description: "按标签类别设置的置信度阈值（如 {\"hair\": 0.5}），优先于其他阈值"},
+			"tag_order":           {Type: "string", Description: "标签排序：置信度、字母顺序或按类别（角色标签在前），未指定时使用配置", Default: tagOrder, Enum: []string{"score", "alphabet", "character"}},
+			"image_files":         {Type: "array", Description: "只处理指定的图片（重试失败条目时使用）", Items: stringItems},
+			"retry_of":            {Type: "string", Description: "重试来源任务ID"},
+		},
+		Required:             []string{"input_dir"},
+		AdditionalProperties: true,
+	}
+}
+
+// ============================================================================
+// AI生成任务配置
+// ============================================================================
+
+// GenerateTaskConfig AI生成任务配置（其余生成参数原样转发给 WebUI）
+type GenerateTaskConfig struct {
+	LoopCount int `json:"loop_count"`
+}
+
+// GenerateConfigSchema AI生成任务配置 Schema
+func GenerateConfigSchema() *ConfigSchema {
+	return &ConfigSchema{
+		Title: "AI生成任务",
+		Type:  "object",
+		Properties: map[string]*SchemaProperty{
+			"prompt":          {Type: "string", Description: "正向提示词"},
+			"negative_prompt": {Type: "string", Description: "反向提示词"},
+			"steps":           {Type: "integer", Description: "采样步数"},
+			"cfg_scale":       {Type: "number", Description: "CFG Scale"},
+			"width":           {Type: "integer", Description: "图片宽度"},
+			"height":          {Type: "integer", Description: "图片高度"},
+			"batch_size":      {Type: "integer", Description: "每批数量"},
+			"batch_count":     {Type: "integer", Description: "批次数"},
+			"loop_count":      {Type: "integer", Description: "循环发包次数", Default: 1, Minimum: float64Ptr(1)},
+			"sampler":         {Type: "string", Description: "采样器"},
+			"seed":            {Type: "integer", Description: "随机种子，-1 表示随机"},
+			"model":           {Type: "string", Description: "模型名称"},
+		},
+		AdditionalProperties: true,
+	}
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"pixiv-tailor/backend/internal/logger"
+	"pixiv-tailor/backend/internal/repository"
+	"pixiv-tailor/backend/pkg/paths"
+)
+
+// TaskDeleteReport 删除任务的结果
+type TaskDeleteReport struct {
+	TaskID       string                       `json:"task_id"`
+	PurgeFiles   bool                         `json:"purge_files"`   // 是否删除磁盘上的产出文件
+	Records      *repository.TaskDeleteResult `json:"records"`       // 从各表删除的记录数量
+	RemovedFiles []string                     `json:"removed_files"` // 删除的产出文件
+	KeptFiles    []string                     `json:"kept_files"`    // 已被修改或仍被其它任务记录而保留的产出文件
+	RemovedDirs  []string                     `json:"removed_dirs"`  // 删除产出文件后已为空而删除的任务图片目录
+	FreedSize    int64                        `json:"freed_size"`    // 释放的磁盘空间（字节）
+	Errors       []string                     `json:"errors,omitempty"`
+}
+
+// DeleteTaskWithOptions 删除任务：在一个事务中删除任务记录和相关记录，purgeFiles 为 true 时再删除产出文件和任务图片目录
+// 数据库删除失败时不会删除任何文件；文件删除失败不影响结果，记录在 Errors 中
+func (s *taskServiceImpl) DeleteTaskWithOptions(id string, purgeFiles bool) (*TaskDeleteReport, error) {
+	task, err := s.GetTask(id)
+	if err != nil {
+		return nil, fmt.Errorf("获取任务失败: %v", err)
+	}
+
+	// 如果任务正在运行，先停止它
+	if task.Status == "running" {
+		if err := s.StopTask(id); err != nil {
+			logger.Infof("停止运行中的任务失败: %v", err)
+			// 即使停止失败，也继续删除
+		}
+	}
+	s.removeFromWaitingQueue(id)
+
+	// 删除数据库记录前获取产出文件列表
+	var artifacts []*repository.TaskArtifact
+	if purgeFiles {
+		if artifacts, err = s.storage.ListTaskArtifacts(id, ""); err != nil {
+			return nil, fmt.Errorf("获取产出文件失败: %v", err)
+		}
+	}
+
+	records, err := s.storage.DeleteTaskRecords(id)
+	if err != nil {
+		return nil, fmt.Errorf("删除任务失败: %v", err)
+	}
+
+	report := &TaskDeleteReport{
+		TaskID:       id,
+		PurgeFiles:   purgeFiles,
+		Records:      records,
+		RemovedFiles: []string{},
+		KeptFiles:    []string{},
+		RemovedDirs:  []string{},
+	}
+	if purgeFiles {
+		s.removeTaskFiles(task, artifacts, report)
+	}
+
+	logger.Infof("任务 %s 已删除（删除 %d 个文件、%d 个目录，释放 %d 字节）", id, len(report.RemovedFiles), len(report.RemovedDirs), report.FreedSize)
+	return report, nil
+}
+
+// removeTaskFiles 删除任务的产出文件，之后删除已为空的任务图片目录（包括旧格式 task_{id} 目录）
+// 产出文件只有仍归该任务所有时才删除：内容与记录的 sha256 一致，且没有其它任务记录同一路径；
+// 目录中保留的文件（其它任务记录的文件或用户放入的文件）不会被删除，目录也随之保留
+func (s *taskServiceImpl) removeTaskFiles(task *repository.Task, artifacts []*repository.TaskArtifact, report *TaskDeleteReport) {
+	for _, artifact := range artifacts {
+		path := ResolveArtifactPath(artifact.Path)
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		owned, err := s.ownsArtifact(task.ID, artifact, path)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("检查产出文件失败 %s: %v", artifact.Path, err))
+		}
+		if !owned {
+			report.KeptFiles = append(report.KeptFiles, artifact.Path)
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("删除产出文件失败 %s: %v", artifact.Path, err))
+			continue
+		}
+		report.RemovedFiles = append(report.RemovedFiles, artifact.Path)
+		report.FreedSize += info.Size()
+	}
+
+	pathManager := paths.GetPathManager()
+	if pathManager == nil {
+		return
+	}
+	dirs := []string{
+		pathManager.GetTaskImagesDir(task.ID, task.Type, task.CreatedAt),
+		filepath.Join(pathManager.GetImagesDir(), fmt.Sprintf("task_%s", task.ID)),
+	}
+	for _, dir := range dirs {
+		if removeEmptyDirs(dir) {
+			report.RemovedDirs = append(report.RemovedDirs, dir)
+		}
+	}
+}
+
+// removeEmptyDirs 自底向上删除目录树中的空目录，返回顶层目录是否已被删除
+func removeEmptyDirs(dir string) bool {
+	if _, err := os.Stat(dir); err != nil {
+		return false
+	}
+	var subdirs []string
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.IsDir() {
+			subdirs = append(subdirs, path)
+		}
+		return nil
+	})
+	// Walk 按字典序先返回父目录，倒序遍历即先处理子目录；非空目录删除失败时保留
+	for i := len(subdirs) - 1; i >= 0; i-- {
+		os.Remove(subdirs[i])
+	}
+	_, err := os.Stat(dir)
+	return os.IsNotExist(err)
+}
+
+// ownsArtifact 判断产出文件是否仍归任务所有：没有其它任务记录同一路径，且当前内容的 sha256 与记录一致
+func (s *taskServiceImpl) ownsArtifact(taskID string, artifact *repository.TaskArtifact, path string) (bool, error) {
+	others, err := s.storage.CountArtifactsByPath(artifact.Path, taskID)
+	if err != nil {
+		return false, err
+	}
+	if others > 0 || artifact.SHA256 == "" {
+		return false, nil
+	}
+	sum, _, err := fileSHA256(path)
+	if err != nil {
+		return false, err
+	}
+	return sum == artifact.SHA256, nil
+}
@@ -0,0 +1,387 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"pixiv-tailor/backend/internal/logger"
+	"pixiv-tailor/backend/internal/repository"
+	pkgerrors "pixiv-tailor/backend/pkg/errors"
+)
+
+// TaskExecutor 任务执行器接口，所有任务类型（包括插件类型）都通过 RegisterExecutor 注册
+type TaskExecutor interface {
+	// Schema 任务配置 Schema，创建任务时据此校验字段类型并填充默认值；返回 nil 表示不做 Schema 校验
+	Schema() *ConfigSchema
+	// Validate 在 Schema 校验之后做跨字段校验，返回错误则拒绝创建（建议返回 *ConfigValidationError）
+	Validate(config map[string]interface{}) error
+	// Execute 执行任务，通过 reporter 上报进度和日志；返回 nil 表示成功完成
+	// 任务的最终状态（completed/failed/cancelled）由 TaskService 根据返回值统一设置
+	Execute(ctx context.Context, task *repository.Task, config map[string]interface{}, reporter TaskReporter) error
+	// Cleanup 任务结束后（无论成功、失败或取消）释放执行器占用的资源
+	Cleanup(task *repository.Task)
+}
+
+// TaskReporter 任务执行上报接口
+type TaskReporter interface {
+	// Progress 更新进度 (0-100)
+	Progress(progress int)
+	// Stage 更新进度并附带阶段信息
+	Stage(progress int, stage string)
+	// Log 发送任务日志
+	Log(level, message string)
+	// ImagesFound 更新发现的图片数量
+	ImagesFound(count int)
+	// ImagesDownloaded 更新已处理的图片数量
+	ImagesDownloaded(count int)
+	// Result 保存任务结果
+	Result(result map[string]interface{})
+	// Checkpoints 获取已完成的条目（断点续传）
+	Checkpoints() []string
+	// ItemDone 记录条目处理成功（写入断点并清除其失败记录）
+	ItemDone(item string)
+	// ItemFailed 记录条目处理失败，供"重试失败条目"使用
+	ItemFailed(item, name string, err error)
+	// FailedItemCount 当前失败条目数量
+	FailedItemCount() int
+	// Artifact 记录产出的文件（role 为 image、tag 或 sidecar），失败时只记录警告
+	Artifact(path, role string)
+	// CrawlResult 记录爬取结果（task_id 设置为当前任务），失败时只记录警告
+	CrawlResult(result *repository.CrawlResult)
+	// GeneratedImage 记录生成的图像（task_id 设置为当前任务），失败时只记录警告
+	GeneratedImage(image *repository.GeneratedImage)
+	// Heartbeat 只刷新心跳，用于长时间没有其它上报的阶段
+	Heartbeat()
+}
+
+// KeepAlive 在长时间阻塞的调用期间定期刷新心跳，调用返回的函数停止刷新
+func KeepAlive(reporter TaskReporter) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(heartbeatPersistInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				reporter.Heartbeat()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+// taskReporterImpl 任务执行上报实现，每次上报都会刷新任务心跳
+type taskReporterImpl struct {
+	service *taskServiceImpl
+	taskID  string
+	run     *taskRun
+}
+
+// heartbeat 刷新任务心跳
+func (r *taskReporterImpl) heartbeat() {
+	if r.run != nil {
+		r.service.touchHeartbeat(r.taskID, r.run)
+	}
+}
+
+// Heartbeat 只刷新心跳
+func (r *taskReporterImpl) Heartbeat() {
+	r.heartbeat()
+}
+
+// Progress 更新进度
+func (r *taskReporterImpl) Progress(progress int) {
+	r.heartbeat()
+	if err := r.service.UpdateTaskProgress(r.taskID, progress); err != nil {
+		logger.Warnf("更新任务进度失败 %s: %v", r.taskID, err)
+	}
+}
+
+// Stage 更新进度并附带阶段信息
+func (r *taskReporterImpl) Stage(progress int, stage string) {
+	r.heartbeat()
+	if err := r.service.UpdateTaskProgressWithStage(r.taskID, progress, stage); err != nil {
+		logger.Warnf("更新任务进度失败 %s: %v", r.taskID, err)
+	}
+}
+
+// Log 发送任务日志
+func (r *taskReporterImpl) Log(level, message string) {
+	r.heartbeat()
+	r.service.sendLog(r.taskID, level, message)
+}
+
+// ImagesFound 更新发现的图片数量
+func (r *taskReporterImpl) ImagesFound(count int) {
+	r.heartbeat()
+	if err := r.service.UpdateTaskImagesFound(r.taskID, count); err != nil {
+		logger.Warnf("更新任务图片数量失败 %s: %v", r.taskID, err)
+	}
+}
+
+// ImagesDownloaded 更新已处理的图片数量
+func (r *taskReporterImpl) ImagesDownloaded(count int) {
+	r.heartbeat()
+	if err := r.service.UpdateTaskImagesDownloaded(r.taskID, count); err != nil {
+		logger.Warnf("更新任务下载数量失败 %s: %v", r.taskID, err)
+	}
+}
+
+// Result 保存任务结果
+func (r *taskReporterImpl) Result(result map[string]interface{}) {
+	r.heartbeat()
+	if err := r.service.UpdateTaskResult(r.taskID, result); err != nil {
+		logger.Warnf("保存任务结果失败 %s: %v", r.taskID, err)
+	}
+}
+
+// Checkpoints 获取已完成的条目
+func (r *taskReporterImpl) Checkpoints() []string {
+	checkpoints, err := r.service.storage.GetTaskCheckpoints(r.taskID)
+	if err != nil {
+		logger.Warnf("获取任务断点失败 %s: %v", r.taskID, err)
+		return nil
+	}
+	return checkpoints
+}
+
+// ItemDone 记录条目处理成功
+func (r *taskReporterImpl) ItemDone(item string) {
+	r.heartbeat()
+	if err := r.service.storage.AddTaskCheckpoint(r.taskID, item); err != nil {
+		logger.Warnf("记录任务断点失败 %s: %v", r.taskID, err)
+	}
+	r.service.clearFailedItem(r.taskID, item)
+}
+
+// ItemFailed 记录条目处理失败
+func (r *taskReporterImpl) ItemFailed(item, name string, err error) {
+	r.heartbeat()
+	r.service.recordFailedItem(r.taskID, item, name, err)
+}
+
+// FailedItemCount 当前失败条目数量
+func (r *taskReporterImpl) FailedItemCount() int {
+	return r.service.countFailedItems(r.taskID)
+}
+
+// Artifact 记录产出的文件
+func (r *taskReporterImpl) Artifact(path, role string) {
+	r.heartbeat()
+	if err := r.service.recordArtifact(r.taskID, path, role); err != nil {
+		logger.Warnf("记录任务产出文件失败 %s (%s): %v", r.taskID, path, err)
+	}
+}
+
+// CrawlResult 记录爬取结果
+func (r *taskReporterImpl) CrawlResult(result *repository.CrawlResult) {
+	r.heartbeat()
+	result.TaskID = r.taskID
+	if result.CreatedAt.IsZero() {
+		result.CreatedAt = time.Now()
+	}
+	if err := r.service.storage.AddCrawlResult(result); err != nil {
+		logger.Warnf("记录爬取结果失败 %s (%s): %v", r.taskID, result.ID, err)
+	}
+}
+
+// GeneratedImage 记录生成的图像
+func (r *taskReporterImpl) GeneratedImage(image *repository.GeneratedImage) {
+	r.heartbeat()
+	image.TaskID = r.taskID
+	if image.CreatedAt.IsZero() {
+		image.CreatedAt = time.Now()
+	}
+	if err := r.service.storage.AddGeneratedImage(image); err != nil {
+		logger.Warnf("记录生成图像失败 %s (%s): %v", r.taskID, image.ID, err)
+	}
+}
+
+// RegisterExecutor 注册任务执行器（同一类型重复注册时覆盖）
+func (s *taskServiceImpl) RegisterExecutor(taskType string, executor TaskExecutor) {
+	s.executorMutex.Lock()
+	s.executors[taskType] = executor
+	s.executorMutex.Unlock()
+	logger.Infof("注册了 %s 任务的执行器", taskType)
+
+	// 启动后才注册的执行器（如 generate）不必等到下一次看门狗检查
+	go s.requeueDueRetries(time.Now())
+}
+
+// getExecutor 获取任务类型对应的执行器
+func (s *taskServiceImpl) getExecutor(taskType string) (TaskExecutor, bool) {
+	s.executorMutex.RLock()
+	defer s.executorMutex.RUnlock()
+	executor, exists := s.executors[taskType]
+	return executor, exists
+}
+
+// takeReservedFields 校验并取出所有任务类型通用的字段（timeout_seconds、retry），这些字段由 TaskService 处理
+func takeReservedFields(config map[string]interface{}) (map[string]interface{}, error) {
+	reserved := make(map[string]interface{})
+	if timeout, exists := config[TaskTimeoutConfigKey]; exists {
+		seconds, ok := timeout.(float64)
+		if !ok || seconds < 0 {
+			return nil, newFieldError(TaskTimeoutConfigKey, "必须是非负数")
+		}
+		reserved[TaskTimeoutConfigKey] = timeout
+	}
+	if retry, exists := config[TaskRetryConfigKey]; exists {
+		if _, err := decodeRetryPolicy(retry); err != nil {
+			return nil, newFieldError(TaskRetryConfigKey, err.Error())
+		}
+		reserved[TaskRetryConfigKey] = retry
+	}
+
+	for name := range reserved {
+		delete(config, name)
+	}
+	return reserved, nil
+}
+
+// restoreReservedFields 将取出的通用字段放回配置
+func restoreReservedFields(config, reserved map[string]interface{}) {
+	for name, value := range reserved {
+		config[name] = value
+	}
+}
+
+// validateTaskConfig 按执行器的 Schema 校验配置、填充默认值并做跨字段校验
+// 通用字段不交给执行器校验
+func validateTaskConfig(executor TaskExecutor, config map[string]interface{}) error {
+	reserved, err := takeReservedFields(config)
+	if err != nil {
+		return err
+	}
+	defer restoreReservedFields(config, reserved)
+
+	if schema := executor.Schema(); schema != nil {
+		if err := schema.Apply(config); err != nil {
+			return err
+		}
+	}
+	if err := executor.Validate(config); err != nil {
+		var validationErr *ConfigValidationError
+		if errors.As(err, &validationErr) {
+			return validationErr
+		}
+		return newFieldError("config", err.Error())
+	}
+	return nil
+}
+
+// GetConfigSchemas 获取所有已注册任务类型的配置 Schema
+func (s *taskServiceImpl) GetConfigSchemas() map[string]*ConfigSchema {
+	s.executorMutex.RLock()
+	defer s.executorMutex.RUnlock()
+
+	schemas := make(map[string]*ConfigSchema, len(s.executors))
+	for taskType, executor := range s.executors {
+		schema := executor.Schema()
+		if schema == nil {
+			// 未提供 Schema 的执行器接受任意配置
+			schema = &ConfigSchema{Type: "object", Properties: map[string]*SchemaProperty{}, AdditionalProperties: true}
+		}
+		schemas[taskType] = withReservedProperties(schema)
+	}
+	return schemas
+}
+
+// withReservedProperties 在 Schema 副本中加入所有任务类型通用的字段
+func withReservedProperties(schema *ConfigSchema) *ConfigSchema {
+	copied := *schema
+	copied.Properties = make(map[string]*SchemaProperty, len(schema.Properties)+2)
+	for name, property := range schema.Properties {
+		copied.Properties[name] = property
+	}
+	copied.Properties[TaskTimeoutConfigKey] = &SchemaProperty{
+		Type:        "number",
+		Description: "任务执行超时（秒），覆盖按类型配置的超时，0 表示使用默认配置",
+		Minimum:     float64Ptr(0),
+	}
+	copied.Properties[TaskRetryConfigKey] = &SchemaProperty{
+		Type:        "object",
+		Description: "失败后的自动重试策略（max_attempts、backoff_seconds、backoff_factor、max_backoff_seconds、retry_on），覆盖按类型配置的策略",
+	}
+	return &copied
+}
+
+// runExecutor 使用执行器执行任务，统一处理最终状态、资源清理和等待队列
+func (s *taskServiceImpl) runExecutor(ctx context.Context, task *repository.Task, executor TaskExecutor, config map[string]interface{}, run *taskRun) {
+	defer func() {
+		executor.Cleanup(task)
+
+		// 从运行任务列表中移除
+		s.removeRun(task.ID, run)
+		logger.Infof("任务 %s 执行结束，从运行列表移除", task.ID)
+
+		// 延迟一点再处理下一个任务，确保状态完全更新
+		go func() {
+			time.Sleep(1 * time.Second)
+			s.processNextTask()
+		}()
+	}()
+
+	err := s.safeExecute(ctx, task, executor, config, run)
+
+	// 已被看门狗终止的任务状态已经更新过，执行器返回后不再覆盖
+	s.taskMutex.RLock()
+	aborted := run.aborted
+	s.taskMutex.RUnlock()
+	if aborted {
+		logger.Infof("任务 %s 已被看门狗终止，跳过状态更新", task.ID)
+		return
+	}
+	s.finishTask(ctx, task.ID, run, err)
+}
+
+// safeExecute 调用执行器，执行器 panic 时转换为任务错误，避免影响整个服务
+func (s *taskServiceImpl) safeExecute(ctx context.Context, task *repository.Task, executor TaskExecutor, config map[string]interface{}, run *taskRun) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Errorf("任务 %s 执行器异常: %v", task.ID, r)
+			err = fmt.Errorf("执行器异常: %v", r)
+		}
+	}()
+	return executor.Execute(ctx, task, config, &taskReporterImpl{service: s, taskID: task.ID, run: run})
+}
+
+// finishTask 根据执行结果设置任务最终状态并记录执行历史，失败时按重试策略自动重试
+func (s *taskServiceImpl) finishTask(ctx context.Context, id string, run *taskRun, execErr error) {
+	task, err := s.GetTask(id)
+	if err != nil {
+		// 任务在执行期间已被删除
+		logger.Infof("任务 %s 已不存在，跳过状态更新", id)
+		return
+	}
+
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		// 执行超时：执行器已响应取消，标记为失败
+		if task.Status == "running" {
+			s.failTask(task, run, "任务执行超时", pkgerrors.ClassTimeout)
+		}
+	case ctx.Err() != nil:
+		// 停止任务时已经更新过状态，这里只处理仍为 running 的情况
+		s.recordAttempt(id, run, "cancelled", "", "")
+		if task.Status == "running" {
+			s.UpdateTaskStatus(id, "cancelled")
+			s.sendLog(id, "info", "任务已被取消")
+		}
+	case execErr != nil:
+		s.failTask(task, run, execErr.Error(), pkgerrors.Classify(execErr))
+	default:
+		s.recordAttempt(id, run, "completed", "", "")
+		s.UpdateTaskStatus(id, "completed")
+		logger.Infof("任务 %s (类型: %s) 已完成", id, task.Type)
+	}
+}
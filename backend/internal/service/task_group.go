@@ -0,0 +1,176 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"pixiv-tailor/backend/internal/logger"
+	"pixiv-tailor/backend/internal/repository"
+)
+
+// TaskGroupProgress 任务组的汇总进度
+type TaskGroupProgress struct {
+	GroupID      string             `json:"group_id"`
+	Status       string             `json:"status"`   // 汇总状态：有运行中的任务为 running，其次 pending，全部完成为 completed，否则 failed 或 cancelled
+	Progress     int                `json:"progress"` // 所有任务的平均进度（已完成的任务按 100 计算）
+	Total        int                `json:"total"`
+	StatusCounts map[string]int     `json:"status_counts"`
+	Tasks        []*repository.Task `json:"tasks"`
+}
+
+// CloneTask 复制任务配置创建新任务，overrides 中的字段覆盖原配置（值为 null 时删除该字段）
+// 新任务继承原任务的标签和优先级，不属于原任务的任务组
+func (s *taskServiceImpl) CloneTask(id string, overrides map[string]interface{}) (*repository.Task, error) {
+	source, err := s.GetTask(id)
+	if err != nil {
+		return nil, fmt.Errorf("获取任务失败: %v", err)
+	}
+
+	var config map[string]interface{}
+	if err := json.Unmarshal([]byte(source.Config), &config); err != nil {
+		return nil, fmt.Errorf("解析原任务配置失败: %v", err)
+	}
+	if config == nil {
+		config = make(map[string]interface{})
+	}
+	for key, value := range overrides {
+		if value == nil {
+			delete(config, key)
+			continue
+		}
+		config[key] = value
+	}
+
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("序列化配置失败: %v", err)
+	}
+
+	task, err := s.createTask(source.Type, string(configJSON), source.Priority, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(source.Labels) > 0 {
+		if err := s.storage.SetTaskLabels(task.ID, source.Labels); err != nil {
+			logger.Warnf("复制任务标签失败 %s: %v", task.ID, err)
+		} else {
+			task.Labels = source.Labels
+		}
+	}
+
+	logger.Infof("任务 %s 已复制为 %s", id, task.ID)
+	return task, nil
+}
+
+// CreateSweep 参数扫描：将基础配置按每个字段的取值列表展开（笛卡尔积），每种组合创建一个任务
+// 创建的任务属于同一个任务组，返回任务组ID和任务列表
+func (s *taskServiceImpl) CreateSweep(taskType string, base map[string]interface{}, sweep map[string][]interface{}, priority int) (string, []*repository.Task, error) {
+	combinations, err := expandSweep(base, sweep)
+	if err != nil {
+		return "", nil, err
+	}
+
+	configs := make([]string, 0, len(combinations))
+	for _, config := range combinations {
+		configJSON, err := json.Marshal(config)
+		if err != nil {
+			return "", nil, fmt.Errorf("序列化配置失败: %v", err)
+		}
+		configs = append(configs, string(configJSON))
+	}
+
+	groupID := generateShortTaskID()
+	tasks, err := s.createTaskBatch(taskType, configs, priority, groupID)
+	if err != nil {
+		return "", tasks, err
+	}
+	logger.Infof("参数扫描创建任务组 %s: %d 个 %s 任务", groupID, len(tasks), taskType)
+	return groupID, tasks, nil
+}
+
+// expandSweep 展开参数扫描，字段按名称排序，排在前面的字段变化最慢
+func expandSweep(base map[string]interface{}, sweep map[string][]interface{}) ([]map[string]interface{}, error) {
+	if len(sweep) == 0 {
+		return nil, fmt.Errorf("至少需要一个扫描字段")
+	}
+
+	fields := make([]string, 0, len(sweep))
+	total := 1
+	for field, values := range sweep {
+		if len(values) == 0 {
+			return nil, fmt.Errorf("扫描字段 %s 没有取值", field)
+		}
+		total *= len(values)
+		if total > maxBatchTasks {
+			return nil, fmt.Errorf("参数组合超过 %d 个", maxBatchTasks)
+		}
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	combinations := make([]map[string]interface{}, 0, total)
+	for i := 0; i < total; i++ {
+		config := make(map[string]interface{}, len(base)+len(fields))
+		for key, value := range base {
+			config[key] = value
+		}
+		// 按混合进制拆分组合序号，最后一个字段变化最快
+		index := i
+		for j := len(fields) - 1; j >= 0; j-- {
+			values := sweep[fields[j]]
+			config[fields[j]] = values[index%len(values)]
+			index /= len(values)
+		}
+		combinations = append(combinations, config)
+	}
+	return combinations, nil
+}
+
+// GetTaskGroup 获取任务组的任务列表和汇总进度
+func (s *taskServiceImpl) GetTaskGroup(groupID string) (*TaskGroupProgress, error) {
+	if groupID == "" {
+		return nil, fmt.Errorf("任务组ID不能为空")
+	}
+
+	filter := &repository.TaskFilter{GroupID: groupID, SortBy: "created_at", SortOrder: "asc"}
+	tasks, err := s.storage.SearchTasks(filter, maxBatchTasks, 0)
+	if err != nil {
+		return nil, fmt.Errorf("获取任务组失败: %v", err)
+	}
+	if len(tasks) == 0 {
+		return nil, fmt.Errorf("任务组不存在: %s", groupID)
+	}
+	s.checkAndFixZombieTasks(tasks)
+
+	group := &TaskGroupProgress{
+		GroupID:      groupID,
+		Total:        len(tasks),
+		StatusCounts: make(map[string]int),
+		Tasks:        tasks,
+	}
+	progressSum := 0
+	for _, task := range tasks {
+		group.StatusCounts[task.Status]++
+		if task.Status == "completed" {
+			progressSum += 100
+		} else {
+			progressSum += task.Progress
+		}
+	}
+	group.Progress = progressSum / len(tasks)
+
+	switch {
+	case group.StatusCounts["running"] > 0:
+		group.Status = "running"
+	case group.StatusCounts["pending"] > 0:
+		group.Status = "pending"
+	case group.StatusCounts["completed"] == len(tasks):
+		group.Status = "completed"
+	case group.StatusCounts["failed"] > 0:
+		group.Status = "failed"
+	default:
+		group.Status = "cancelled"
+	}
+	return group, nil
+}
@@ -0,0 +1,288 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"pixiv-tailor/backend/internal/logger"
+	"pixiv-tailor/backend/internal/repository"
+)
+
+const (
+	// RetentionConfigModule 任务产出保留策略所在的配置模块
+	RetentionConfigModule = "retention"
+
+	// janitorCheckInterval 定时清理检查间隔（实际清理间隔由保留策略决定）
+	janitorCheckInterval = time.Minute
+	// retentionPageSize 计算保留策略时每次读取的任务数量
+	retentionPageSize = 500
+)
+
+// 任务被清理的原因
+const (
+	RetentionReasonMaxAge    = "max_age"
+	RetentionReasonKeepLast  = "keep_last_per_type"
+	RetentionReasonTotalSize = "max_total_size"
+)
+
+// RetentionPolicy 任务产出保留策略（保存在配置模块 "retention" 中）
+// 只清理已结束（completed、failed、cancelled）的任务，清理时同时删除任务记录和产出文件
+type RetentionPolicy struct {
+	Enabled         bool `json:"enabled"`            // 是否启用定时清理
+	IntervalMinutes int  `json:"interval_minutes"`   // 定时清理间隔（分钟）
+	MaxAgeDays      int  `json:"max_age_days"`       // 清理创建超过该天数的任务，0 表示不限
+	MaxTotalSizeMB  int  `json:"max_total_size_mb"`  // 任务产出总大小上限（MB），超出时从最旧的任务开始清理，0 表示不限
+	KeepLastPerType int  `json:"keep_last_per_type"` // 每种类型只保留最新的 N 个已结束任务，0 表示不限
+	KeepStarred     bool `json:"keep_starred"`       // 不清理加星标的任务
+}
+
+// DefaultRetentionPolicy 默认保留策略（不启用定时清理，不限制）
+func DefaultRetentionPolicy() *RetentionPolicy {
+	return &RetentionPolicy{
+		Enabled:         false,
+		IntervalMinutes: 60,
+		KeepStarred:     true,
+	}
+}
+
+// Validate 校验保留策略
+func (p *RetentionPolicy) Validate() error {
+	if p.IntervalMinutes < 1 {
+		return fmt.Errorf("interval_minutes 不能小于 1")
+	}
+	if p.MaxAgeDays < 0 {
+		return fmt.Errorf("max_age_days 不能为负数")
+	}
+	if p.MaxTotalSizeMB < 0 {
+		return fmt.Errorf("max_total_size_mb 不能为负数")
+	}
+	if p.KeepLastPerType < 0 {
+		return fmt.Errorf("keep_last_per_type 不能为负数")
+	}
+	return nil
+}
+
+// RetentionItem 保留策略选中清理的任务
+type RetentionItem struct {
+	TaskID    string    `json:"task_id"`
+	Type      string    `json:"type"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	Size      int64     `json:"size"`   // 产出文件大小（字节）
+	Reason    string    `json:"reason"` // max_age、keep_last_per_type、max_total_size
+	Error     string    `json:"error,omitempty"`
+}
+
+// RetentionReport 保留策略的执行（或预览）结果
+type RetentionReport struct {
+	DryRun    bool             `json:"dry_run"`
+	Policy    *RetentionPolicy `json:"policy"`
+	TotalSize int64            `json:"total_size"` // 清理前所有任务的产出总大小（字节）
+	FreedSize int64            `json:"freed_size"` // 清理（预计）释放的大小（字节）
+	Deleted   int              `json:"deleted"`    // 实际删除的任务数量，预览时为 0
+	Items     []*RetentionItem `json:"items"`
+}
+
+// loadRetentionPolicy 读取保留策略，未配置或配置无效时使用默认值
+func (s *taskServiceImpl) loadRetentionPolicy() *RetentionPolicy {
+	policy := DefaultRetentionPolicy()
+
+	data, err := s.storage.GetConfig(RetentionConfigModule)
+	if err != nil || data == "" {
+		return policy
+	}
+	if err := json.Unmarshal([]byte(data), policy); err != nil {
+		logger.Warnf("解析保留策略失败，使用默认策略: %v", err)
+		return DefaultRetentionPolicy()
+	}
+	if err := policy.Validate(); err != nil {
+		logger.Warnf("保留策略无效，使用默认策略: %v", err)
+		return DefaultRetentionPolicy()
+	}
+	return policy
+}
+
+// RunRetention 按保留策略清理任务，policy 为空时使用已保存的策略，dryRun 时只返回将被清理的任务
+func (s *taskServiceImpl) RunRetention(policy *RetentionPolicy, dryRun bool) (*RetentionReport, error) {
+	if policy == nil {
+		policy = s.loadRetentionPolicy()
+	} else if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.retentionMutex.Lock()
+	defer s.retentionMutex.Unlock()
+
+	report, candidates, err := s.planRetention(policy)
+	if err != nil {
+		return nil, err
+	}
+	report.DryRun = dryRun
+	if dryRun {
+		return report, nil
+	}
+
+	report.FreedSize = 0
+	for i, item := range report.Items {
+		if err := s.purgeTask(candidates[i]); err != nil {
+			item.Error = err.Error()
+			logger.Warnf("保留策略清理任务失败 %s: %v", item.TaskID, err)
+			continue
+		}
+		report.Deleted++
+		report.FreedSize += item.Size
+	}
+	logger.Infof("保留策略清理了 %d 个任务，释放 %d 字节", report.Deleted, report.FreedSize)
+	return report, nil
+}
+
+// planRetention 计算保留策略选中清理的任务（按创建时间从新到旧排列）
+func (s *taskServiceImpl) planRetention(policy *RetentionPolicy) (*RetentionReport, []*repository.Task, error) {
+	filter := &repository.TaskFilter{SortBy: "created_at", SortOrder: "desc"}
+	var tasks []*repository.Task
+	for offset := 0; ; offset += retentionPageSize {
+		page, err := s.storage.SearchTasks(filter, retentionPageSize, offset)
+		if err != nil {
+			return nil, nil, fmt.Errorf("获取任务列表失败: %v", err)
+		}
+		tasks = append(tasks, page...)
+		if len(page) < retentionPageSize {
+			break
+		}
+	}
+
+	report := &RetentionReport{Policy: policy, Items: []*RetentionItem{}}
+	sizes := make(map[string]int64, len(tasks))
+	for _, task := range tasks {
+		sizes[task.ID] = s.taskOutputSize(task)
+		report.TotalSize += sizes[task.ID]
+	}
+
+	now := time.Now()
+	reasons := make(map[string]string)
+	var kept []*repository.Task // 未被前两条规则选中、可按总大小清理的任务（从新到旧）
+	perType := make(map[string]int)
+	for _, task := range tasks {
+		if !isTerminalTaskStatus(task.Status) || (policy.KeepStarred && task.Starred) {
+			continue
+		}
+		perType[task.Type]++
+		switch {
+		case policy.KeepLastPerType > 0 && perType[task.Type] > policy.KeepLastPerType:
+			reasons[task.ID] = RetentionReasonKeepLast
+		case policy.MaxAgeDays > 0 && now.Sub(task.CreatedAt) > time.Duration(policy.MaxAgeDays)*24*time.Hour:
+			reasons[task.ID] = RetentionReasonMaxAge
+		default:
+			kept = append(kept, task)
+		}
+	}
+
+	if policy.MaxTotalSizeMB > 0 {
+		remaining := report.TotalSize
+		for _, task := range tasks {
+			if reasons[task.ID] != "" {
+				remaining -= sizes[task.ID]
+			}
+		}
+		limit := int64(policy.MaxTotalSizeMB) * 1024 * 1024
+		for i := len(kept) - 1; i >= 0 && remaining > limit; i-- {
+			reasons[kept[i].ID] = RetentionReasonTotalSize
+			remaining -= sizes[kept[i].ID]
+		}
+	}
+
+	var candidates []*repository.Task
+	for _, task := range tasks {
+		reason := reasons[task.ID]
+		if reason == "" {
+			continue
+		}
+		candidates = append(candidates, task)
+		report.Items = append(report.Items, &RetentionItem{
+			TaskID:    task.ID,
+			Type:      task.Type,
+			Status:    task.Status,
+			CreatedAt: task.CreatedAt,
+			Size:      sizes[task.ID],
+			Reason:    reason,
+		})
+		report.FreedSize += sizes[task.ID]
+	}
+	return report, candidates, nil
+}
+
+// isTerminalTaskStatus 判断任务是否已结束
+func isTerminalTaskStatus(status string) bool {
+	return status == "completed" || status == "failed" || status == "cancelled"
+}
+
+// taskOutputSize 任务产出的总大小：仍存在的产出文件（删除任务时只删除产出文件，任务图片目录中的其它文件会保留）
+func (s *taskServiceImpl) taskOutputSize(task *repository.Task) int64 {
+	var size int64
+	artifacts, err := s.storage.ListTaskArtifacts(task.ID, "")
+	if err != nil {
+		return size
+	}
+	for _, artifact := range artifacts {
+		path := ResolveArtifactPath(artifact.Path)
+		// 其它任务也记录了的文件删除时会保留，不计入（不在这里计算哈希，预览时开销太大）
+		if others, err := s.storage.CountArtifactsByPath(artifact.Path, task.ID); err != nil || others > 0 {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil {
+			size += info.Size()
+		}
+	}
+	return size
+}
+
+// purgeTask 删除任务记录和产出文件
+func (s *taskServiceImpl) purgeTask(task *repository.Task) error {
+	report, err := s.DeleteTaskWithOptions(task.ID, true)
+	if err != nil {
+		return err
+	}
+	for _, message := range report.Errors {
+		logger.Warnf("保留策略清理任务 %s: %s", task.ID, message)
+	}
+	return nil
+}
+
+// isWithinDir 判断路径是否在目录之内
+func isWithinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// runRetentionJanitor 定时清理：按保留策略中的间隔执行清理，策略未启用时跳过
+func (s *taskServiceImpl) runRetentionJanitor() {
+	ticker := time.NewTicker(janitorCheckInterval)
+	defer ticker.Stop()
+
+	var lastRun time.Time
+	for now := range ticker.C {
+		policy := s.loadRetentionPolicy()
+		if !policy.Enabled || now.Sub(lastRun) < time.Duration(policy.IntervalMinutes)*time.Minute {
+			continue
+		}
+		lastRun = now
+		if _, err := s.RunRetention(policy, false); err != nil {
+			logger.Warnf("定时清理任务失败: %v", err)
+		}
+	}
+}
+
+// SetTaskStarred 设置任务星标
+func (s *taskServiceImpl) SetTaskStarred(id string, starred bool) error {
+	if _, err := s.GetTask(id); err != nil {
+		return fmt.Errorf("获取任务失败: %v", err)
+	}
+	if err := s.storage.UpdateTaskStarred(id, starred); err != nil {
+		return fmt.Errorf("更新任务星标失败: %v", err)
+	}
+	return nil
+}
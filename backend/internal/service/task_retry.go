@@ -0,0 +1,247 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"pixiv-tailor/backend/internal/logger"
+	"pixiv-tailor/backend/internal/repository"
+	pkgerrors "pixiv-tailor/backend/pkg/errors"
+)
+
+// TaskRetryConfigKey 任务配置中的单任务重试策略字段，优先于按类型的重试策略
+const TaskRetryConfigKey = "retry"
+
+// RetryPolicy 任务失败后的自动重试策略
+type RetryPolicy struct {
+	MaxAttempts       int      `json:"max_attempts"`        // 最多尝试次数（包括第一次），1 表示不自动重试
+	BackoffSeconds    float64  `json:"backoff_seconds"`     // 第一次重试前的等待时间（秒）
+	BackoffFactor     float64  `json:"backoff_factor"`      // 之后每次重试的等待时间倍数（指数退避）
+	MaxBackoffSeconds float64  `json:"max_backoff_seconds"` // 等待时间上限（秒）
+	RetryOn           []string `json:"retry_on"`            // 只在这些错误分类时重试（network、timeout、auth、invalid、internal、unknown）
+}
+
+// DefaultRetryPolicy 默认重试策略（不自动重试）
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:       1,
+		BackoffSeconds:    30,
+		BackoffFactor:     2,
+		MaxBackoffSeconds: 600,
+		RetryOn:           []string{string(pkgerrors.ClassNetwork), string(pkgerrors.ClassTimeout)},
+	}
+}
+
+// withDefaults 未设置的字段使用默认值
+func (p *RetryPolicy) withDefaults() *RetryPolicy {
+	policy := *p
+	defaults := DefaultRetryPolicy()
+	if policy.MaxAttempts == 0 {
+		policy.MaxAttempts = defaults.MaxAttempts
+	}
+	if policy.BackoffSeconds == 0 {
+		policy.BackoffSeconds = defaults.BackoffSeconds
+	}
+	if policy.BackoffFactor == 0 {
+		policy.BackoffFactor = defaults.BackoffFactor
+	}
+	if policy.MaxBackoffSeconds == 0 {
+		policy.MaxBackoffSeconds = defaults.MaxBackoffSeconds
+	}
+	if len(policy.RetryOn) == 0 {
+		policy.RetryOn = defaults.RetryOn
+	}
+	return &policy
+}
+
+// Validate 校验重试策略
+func (p *RetryPolicy) Validate() error {
+	if p.MaxAttempts < 0 {
+		return fmt.Errorf("max_attempts 不能为负数")
+	}
+	if p.BackoffSeconds < 0 || p.MaxBackoffSeconds < 0 {
+		return fmt.Errorf("等待时间不能为负数")
+	}
+	if p.BackoffFactor != 0 && p.BackoffFactor < 1 {
+		return fmt.Errorf("backoff_factor 不能小于 1")
+	}
+	for _, class := range p.RetryOn {
+		if !isErrorClass(class) {
+			return fmt.Errorf("retry_on 包含未知的错误分类: %s", class)
+		}
+	}
+	return nil
+}
+
+// ShouldRetry 判断该分类的错误是否需要重试
+func (p *RetryPolicy) ShouldRetry(class pkgerrors.ErrorClass) bool {
+	return contains(p.RetryOn, string(class))
+}
+
+// Delay 第 attempt 次尝试失败后，下一次重试前的等待时间
+func (p *RetryPolicy) Delay(attempt int) time.Duration {
+	seconds := p.BackoffSeconds * math.Pow(p.BackoffFactor, float64(attempt-1))
+	if p.MaxBackoffSeconds > 0 && seconds > p.MaxBackoffSeconds {
+		seconds = p.MaxBackoffSeconds
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// isErrorClass 检查是否是已知的错误分类
+func isErrorClass(class string) bool {
+	for _, known := range pkgerrors.Classes() {
+		if string(known) == class {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeRetryPolicy 解析任务配置中的重试策略
+func decodeRetryPolicy(value interface{}) (*RetryPolicy, error) {
+	config, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("必须是对象")
+	}
+	policy := &RetryPolicy{}
+	if err := DecodeConfig(config, policy); err != nil {
+		return nil, err
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// retryPolicy 计算任务的重试策略：任务配置 > 类型配置 > 默认（不重试）
+func (s *taskServiceImpl) retryPolicy(task *repository.Task) *RetryPolicy {
+	var config map[string]interface{}
+	if err := json.Unmarshal([]byte(task.Config), &config); err == nil {
+		if value, exists := config[TaskRetryConfigKey]; exists {
+			if policy, err := decodeRetryPolicy(value); err == nil {
+				return policy.withDefaults()
+			}
+		}
+	}
+
+	if policy, exists := s.loadRuntimeConfig().RetryPolicies[task.Type]; exists && policy != nil {
+		return policy.withDefaults()
+	}
+	return DefaultRetryPolicy()
+}
+
+// recordAttempt 记录本次执行的结果
+func (s *taskServiceImpl) recordAttempt(taskID string, run *taskRun, status, message string, class pkgerrors.ErrorClass) {
+	attempt := &repository.TaskAttempt{
+		TaskID:     taskID,
+		Attempt:    run.attempt,
+		Status:     status,
+		Error:      message,
+		ErrorClass: string(class),
+		StartedAt:  run.startedAt,
+		FinishedAt: time.Now(),
+	}
+	if err := s.storage.AddTaskAttempt(attempt); err != nil {
+		logger.Warnf("记录任务执行历史失败 %s: %v", taskID, err)
+	}
+}
+
+// failTask 记录失败的执行，按重试策略安排自动重试或将任务标记为失败
+func (s *taskServiceImpl) failTask(task *repository.Task, run *taskRun, message string, class pkgerrors.ErrorClass) {
+	s.recordAttempt(task.ID, run, "failed", message, class)
+	s.sendLog(task.ID, "error", message)
+	s.UpdateTaskError(task.ID, message)
+
+	policy := run.retry
+	if policy == nil || run.attempt >= policy.MaxAttempts || !policy.ShouldRetry(class) {
+		s.UpdateTaskStatus(task.ID, "failed")
+		return
+	}
+
+	delay := policy.Delay(run.attempt)
+	nextRetryAt := time.Now().Add(delay)
+	if err := s.storage.UpdateTaskNextRetry(task.ID, &nextRetryAt); err != nil {
+		logger.Warnf("更新任务重试时间失败 %s: %v", task.ID, err)
+	}
+	s.UpdateTaskStatus(task.ID, "pending")
+	s.sendLog(task.ID, "warning", fmt.Sprintf("第 %d/%d 次尝试失败（%s），%v 后自动重试", run.attempt, policy.MaxAttempts, class, delay))
+
+	attempt := run.attempt
+	time.AfterFunc(delay, func() {
+		s.retryTask(task.ID, attempt)
+	})
+}
+
+// retryTask 自动重试任务（保留断点，只处理剩余部分）
+// 等待期间任务被手动启动、取消或删除时放弃本次重试；先清除 next_retry_at 认领本次重试，避免重复执行
+func (s *taskServiceImpl) retryTask(id string, failedAttempt int) {
+	s.retryMutex.Lock()
+	task, err := s.GetTask(id)
+	if err != nil {
+		s.retryMutex.Unlock()
+		logger.Infof("任务 %s 已不存在，取消自动重试", id)
+		return
+	}
+	if task.Status != "pending" || task.Attempt != failedAttempt || task.NextRetryAt == nil {
+		s.retryMutex.Unlock()
+		logger.Infof("任务 %s 状态已变化 (%s, 第 %d 次尝试)，取消自动重试", id, task.Status, task.Attempt)
+		return
+	}
+	if err := s.storage.UpdateTaskNextRetry(id, nil); err != nil {
+		s.retryMutex.Unlock()
+		logger.Warnf("清除任务重试时间失败 %s: %v", id, err)
+		return
+	}
+	s.retryMutex.Unlock()
+
+	if err := s.UpdateTaskError(id, ""); err != nil {
+		logger.Warnf("清理任务错误信息失败: %v", err)
+	}
+	if err := s.scheduleTask(task); err != nil {
+		logger.Warnf("自动重试任务失败 %s: %v", id, err)
+		s.UpdateTaskError(id, fmt.Sprintf("自动重试失败: %v", err))
+		s.UpdateTaskStatus(id, "failed")
+	}
+}
+
+// requeueDueRetries 重新安排重试时间已到的任务
+// 自动重试的定时器只在内存中，进程重启后由启动时和看门狗的检查接手；执行器尚未注册的类型等注册后再安排
+func (s *taskServiceImpl) requeueDueRetries(now time.Time) {
+	tasks, err := s.storage.ListDueRetryTasks(now)
+	if err != nil {
+		logger.Warnf("获取待重试任务失败: %v", err)
+		return
+	}
+	for _, task := range tasks {
+		if _, exists := s.getExecutor(task.Type); !exists {
+			continue
+		}
+		logger.Infof("任务 %s 的重试时间已到 (%s)，重新安排自动重试", task.ID, task.NextRetryAt.Format(time.RFC3339))
+		s.retryTask(task.ID, task.Attempt)
+	}
+}
+
+// resetAttempts 手动启动或恢复任务时重新开始计算尝试次数
+func (s *taskServiceImpl) resetAttempts(task *repository.Task) {
+	if err := s.storage.UpdateTaskAttempt(task.ID, 0, s.retryPolicy(task).MaxAttempts); err != nil {
+		logger.Warnf("重置任务尝试次数失败 %s: %v", task.ID, err)
+	}
+	if err := s.storage.UpdateTaskNextRetry(task.ID, nil); err != nil {
+		logger.Warnf("清除任务重试时间失败 %s: %v", task.ID, err)
+	}
+	task.Attempt = 0
+}
+
+// GetTaskAttempts 获取任务的执行历史
+func (s *taskServiceImpl) GetTaskAttempts(id string) ([]*repository.TaskAttempt, error) {
+	if _, err := s.GetTask(id); err != nil {
+		return nil, fmt.Errorf("获取任务失败: %v", err)
+	}
+	attempts, err := s.storage.ListTaskAttempts(id)
+	if err != nil {
+		return nil, fmt.Errorf("获取任务执行历史失败: %v", err)
+	}
+	return attempts, nil
+}
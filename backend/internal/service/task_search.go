@@ -0,0 +1,152 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"pixiv-tailor/backend/internal/repository"
+)
+
+const (
+	// maxTaskLabels 单个任务最多的标签数量
+	maxTaskLabels = 20
+	// maxTaskLabelLength 单个标签的最大长度（字符）
+	maxTaskLabelLength = 50
+	// maxTaskNotesLength 备注的最大长度（字符）
+	maxTaskNotesLength = 2000
+)
+
+// SearchTasks 按筛选条件分页查询任务（标签、关键字、创建时间范围和排序）
+func (s *taskServiceImpl) SearchTasks(filter *repository.TaskFilter, page, pageSize int32) ([]*repository.Task, int, error) {
+	if filter == nil {
+		filter = &repository.TaskFilter{}
+	}
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 20 // 默认限制
+	}
+	if pageSize > 100 {
+		pageSize = 100 // 最大限制
+	}
+
+	offset := int((page - 1) * pageSize)
+	tasks, err := s.storage.SearchTasks(filter, int(pageSize), offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 检查并修复僵尸任务（数据库中是running状态但实际没有运行的任务）
+	s.checkAndFixZombieTasks(tasks)
+
+	// 获取总数
+	total, err := s.storage.CountTasksByFilter(filter)
+	if err != nil {
+		return tasks, 0, err
+	}
+
+	return tasks, total, nil
+}
+
+// UpdateTaskLabels 替换任务的标签（去除首尾空白、去重）
+func (s *taskServiceImpl) UpdateTaskLabels(id string, labels []string) error {
+	if _, err := s.GetTask(id); err != nil {
+		return fmt.Errorf("获取任务失败: %v", err)
+	}
+
+	normalized, err := normalizeTaskLabels(labels)
+	if err != nil {
+		return err
+	}
+	if err := s.storage.SetTaskLabels(id, normalized); err != nil {
+		return fmt.Errorf("更新任务标签失败: %v", err)
+	}
+	return nil
+}
+
+// UpdateTaskNotes 更新任务备注
+func (s *taskServiceImpl) UpdateTaskNotes(id, notes string) error {
+	if _, err := s.GetTask(id); err != nil {
+		return fmt.Errorf("获取任务失败: %v", err)
+	}
+	if utf8.RuneCountInString(notes) > maxTaskNotesLength {
+		return fmt.Errorf("备注不能超过 %d 个字符", maxTaskNotesLength)
+	}
+	if err := s.storage.UpdateTaskNotes(id, notes); err != nil {
+		return fmt.Errorf("更新任务备注失败: %v", err)
+	}
+	return nil
+}
+
+// ListTaskLabels 列出所有标签及使用该标签的任务数量
+func (s *taskServiceImpl) ListTaskLabels() (map[string]int, error) {
+	labels, err := s.storage.ListTaskLabels()
+	if err != nil {
+		return nil, fmt.Errorf("获取任务标签失败: %v", err)
+	}
+	return labels, nil
+}
+
+// normalizeTaskLabels 去除标签首尾空白、忽略空标签并去重
+func normalizeTaskLabels(labels []string) ([]string, error) {
+	normalized := make([]string, 0, len(labels))
+	seen := make(map[string]bool)
+	for _, label := range labels {
+		label = strings.TrimSpace(label)
+		if label == "" || seen[label] {
+			continue
+		}
+		if utf8.RuneCountInString(label) > maxTaskLabelLength {
+			return nil, fmt.Errorf("标签 %q 超过 %d 个字符", label, maxTaskLabelLength)
+		}
+		seen[label] = true
+		normalized = append(normalized, label)
+	}
+	if len(normalized) > maxTaskLabels {
+		return nil, fmt.Errorf("单个任务最多 %d 个标签", maxTaskLabels)
+	}
+	return normalized, nil
+}
+
+// ParseTaskTimeRange 解析创建时间范围，支持 RFC3339 和 YYYY-MM-DD
+// 只有日期的上限包含当天（转换为第二天零点，查询时不包含上限）
+func ParseTaskTimeRange(from, to string) (*time.Time, *time.Time, error) {
+	var createdFrom, createdTo *time.Time
+
+	if from != "" {
+		t, _, err := parseTaskTime(from)
+		if err != nil {
+			return nil, nil, fmt.Errorf("created_from 格式无效: %v", err)
+		}
+		createdFrom = &t
+	}
+	if to != "" {
+		t, dateOnly, err := parseTaskTime(to)
+		if err != nil {
+			return nil, nil, fmt.Errorf("created_to 格式无效: %v", err)
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		createdTo = &t
+	}
+	if createdFrom != nil && createdTo != nil && !createdFrom.Before(*createdTo) {
+		return nil, nil, fmt.Errorf("created_from 必须早于 created_to")
+	}
+	return createdFrom, createdTo, nil
+}
+
+// parseTaskTime 解析时间，返回是否只有日期
+func parseTaskTime(value string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("应为 RFC3339 或 YYYY-MM-DD: %s", value)
+	}
+	return t, true, nil
+}
@@ -6,17 +6,12 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
 	"sync"
 	"time"
 
-	"pixiv-tailor/backend/internal/crawler"
+	"pixiv-tailor/backend/internal/events"
 	"pixiv-tailor/backend/internal/logger"
 	"pixiv-tailor/backend/internal/repository"
-	"pixiv-tailor/backend/internal/tagger"
-	"pixiv-tailor/backend/pkg/models"
-	"pixiv-tailor/backend/pkg/paths"
 
 	"github.com/google/uuid"
 )
@@ -24,6 +19,10 @@ import (
 // TaskService 任务服务接口
 type TaskService interface {
 	CreateTask(taskType, config string) (*repository.Task, error)
+	CreateTasks(taskType string, configs []string, priority int) ([]*repository.Task, error)
+	CloneTask(id string, overrides map[string]interface{}) (*repository.Task, error)
+	CreateSweep(taskType string, base map[string]interface{}, sweep map[string][]interface{}, priority int) (string, []*repository.Task, error)
+	GetTaskGroup(groupID string) (*TaskGroupProgress, error)
 	GetTask(id string) (*repository.Task, error)
 	UpdateTaskStatus(id, status string) error
 	UpdateTaskProgress(id string, progress int) error
@@ -33,47 +32,76 @@ type TaskService interface {
 	UpdateTaskImagesDownloaded(id string, count int) error
 	UpdateTaskResult(id string, result map[string]interface{}) error
 	ListTasks(page, pageSize int32, status, taskType string) ([]*repository.Task, int, error)
+	SearchTasks(filter *repository.TaskFilter, page, pageSize int32) ([]*repository.Task, int, error)
+	UpdateTaskLabels(id string, labels []string) error
+	UpdateTaskNotes(id, notes string) error
+	ListTaskLabels() (map[string]int, error)
+	SetTaskPriority(id string, priority int) error
+	FindTaskIDs(filter *repository.TaskFilter) ([]string, error)
+	BatchTasks(action string, ids []string, priority int, purgeFiles bool) ([]*BatchTaskResult, error)
 	StartTask(id string) error
+	ResumeTask(id string) error
 	StopTask(id string) error
 	CancelTask(id string) error
 	DeleteTask(id string) error
+	DeleteTaskWithOptions(id string, purgeFiles bool) (*TaskDeleteReport, error)
+	GetTaskFailedItems(id string) ([]*repository.TaskFailedItem, error)
+	GetTaskAttempts(id string) ([]*repository.TaskAttempt, error)
+	ListTaskArtifacts(taskID, role string) ([]*repository.TaskArtifact, error)
+	SetTaskStarred(id string, starred bool) error
+	RunRetention(policy *RetentionPolicy, dryRun bool) (*RetentionReport, error)
+	GetTaskArtifact(taskID string, id int64) (*repository.TaskArtifact, string, error)
+	RetryFailedItems(id string) (*repository.Task, error)
+	GetTaskLogs(id, level string, page, pageSize int32) ([]*repository.TaskLog, int, error)
 	CleanupTasks(cleanupType string) (int, error)
-	SetLogCallback(callback func(taskID, level, message string))
-	SetStatusCallback(callback func(taskID, status string, progress int))
+	EventBus() events.Bus
 	RegisterExecutor(taskType string, executor TaskExecutor)
-}
-
-// TaskExecutor 任务执行器接口（用于 handler 注册自己的执行逻辑）
-type TaskExecutor interface {
-	ExecuteGenerateTask(ctx context.Context, taskID string, config map[string]interface{})
+	GetConfigSchemas() map[string]*ConfigSchema
 }
 
 // taskServiceImpl 任务服务实现
 type taskServiceImpl struct {
-	storage        repository.Storage
-	logCallback    func(taskID, level, message string)
-	statusCallback func(taskID, status string, progress int)
+	storage repository.Storage
+	// 事件总线 - 发布任务状态、进度和日志事件，WebSocket、gRPC 等各自订阅
+	bus events.Bus
 	// 任务上下文管理
-	runningTasks map[string]context.CancelFunc
+	runningTasks map[string]*taskRun
 	taskMutex    sync.RWMutex
+	// 看门狗检查间隔 - 终止超时后仍未退出或长时间没有心跳的任务
+	watchdogInterval time.Duration
 	// 任务队列管理 - 按类型管理，确保同类型任务串行执行
 	waitingTasksByType map[string][]string // 按任务类型分组的等待队列
 	queueMutex         sync.Mutex
-	// 任务执行器映射 - 用于不同任务类型的自定义执行器
-	executors map[string]TaskExecutor
+	// 任务执行器映射 - 每种任务类型对应一个执行器，未注册的类型无法创建
+	executors     map[string]TaskExecutor
+	executorMutex sync.RWMutex
+	// 保留策略清理 - 手动和定时清理不同时执行
+	retentionMutex sync.Mutex
+	// 自动重试 - 定时器和看门狗可能同时认领同一次重试，只允许其中一个执行
+	retryMutex sync.Mutex
 }
 
 // NewTaskService 创建任务服务实例
 func NewTaskService(storage repository.Storage) TaskService {
 	service := &taskServiceImpl{
 		storage:            storage,
-		runningTasks:       make(map[string]context.CancelFunc),
+		runningTasks:       make(map[string]*taskRun),
+		watchdogInterval:   defaultWatchdogInterval,
 		waitingTasksByType: make(map[string][]string),
 		executors:          make(map[string]TaskExecutor),
+		bus:                events.NewBus(),
 	}
 
+	// 注册内置任务执行器（generate 由 AIHandler 注册）
+	service.RegisterExecutor("crawl", NewCrawlTaskExecutor())
+	service.RegisterExecutor("tag", NewTagTaskExecutor())
+
 	// 启动后台监控 goroutine，循环检查等待队列
 	go service.monitorWaitingQueue()
+	// 启动看门狗，释放超时或卡死任务占用的队列
+	go service.monitorRunningTasks()
+	// 启动定时清理，按保留策略删除过期任务及其产出文件
+	go service.runRetentionJanitor()
 
 	return service
 }
@@ -92,6 +120,11 @@ func generateShortTaskID() string {
 
 // CreateTask 创建任务
 func (s *taskServiceImpl) CreateTask(taskType, config string) (*repository.Task, error) {
+	return s.createTask(taskType, config, 0, "")
+}
+
+// createTask 创建指定优先级（和任务组）的任务，同类型有任务运行时按优先级加入等待队列
+func (s *taskServiceImpl) createTask(taskType, config string, priority int, groupID string) (*repository.Task, error) {
 	logger.Infof("CreateTask 开始执行: taskType=%s, config=%s", taskType, config)
 
 	// 验证配置JSON格式
@@ -100,11 +133,20 @@ func (s *taskServiceImpl) CreateTask(taskType, config string) (*repository.Task,
 		return nil, fmt.Errorf("配置格式无效: %v", err)
 	}
 
-	// 验证任务类型
-	validTypes := []string{"crawl", "generate", "train", "tag", "classify"}
-	if !contains(validTypes, taskType) {
+	// 验证任务类型：只有注册了执行器的类型才能创建
+	executor, exists := s.getExecutor(taskType)
+	if !exists {
 		return nil, fmt.Errorf("不支持的任务类型: %s", taskType)
 	}
+	// 校验配置并填充默认值，保存的是填充后的配置
+	if err := validateTaskConfig(executor, configMap); err != nil {
+		return nil, err
+	}
+	filledConfig, err := json.Marshal(configMap)
+	if err != nil {
+		return nil, fmt.Errorf("序列化配置失败: %v", err)
+	}
+	config = string(filledConfig)
 
 	task := &repository.Task{
 		ID:        generateShortTaskID(),
@@ -112,6 +154,8 @@ func (s *taskServiceImpl) CreateTask(taskType, config string) (*repository.Task,
 		Status:    "pending",
 		Config:    config,
 		Progress:  0,
+		Priority:  priority,
+		GroupID:   groupID,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
@@ -161,10 +205,7 @@ func (s *taskServiceImpl) CreateTask(taskType, config string) (*repository.Task,
 
 			// 将任务加入等待队列
 			s.queueMutex.Lock()
-			if s.waitingTasksByType[taskType] == nil {
-				s.waitingTasksByType[taskType] = make([]string, 0)
-			}
-			s.waitingTasksByType[taskType] = append(s.waitingTasksByType[taskType], task.ID)
+			s.enqueueWaitingTaskInLock(task)
 			logger.Infof("任务 %s 已加入 %s 类型等待队列（当前队列长度: %d）", task.ID, taskType, len(s.waitingTasksByType[taskType]))
 			s.queueMutex.Unlock()
 		}
@@ -202,13 +243,9 @@ func (s *taskServiceImpl) UpdateTaskStatus(id, status string) error {
 		return err
 	}
 
-	// 发送WebSocket状态更新消息
-	if s.statusCallback != nil {
-		// 获取当前任务以获取进度信息
-		task, err := s.GetTask(id)
-		if err == nil {
-			s.statusCallback(id, status, task.Progress)
-		}
+	// 发布任务状态事件（获取当前任务以获取进度信息）
+	if updatedTask, err := s.GetTask(id); err == nil {
+		s.notifyStatus(id, status, updatedTask.Progress, "")
 	}
 
 	return nil
@@ -225,19 +262,17 @@ func (s *taskServiceImpl) UpdateTaskProgress(id string, progress int) error {
 		return err
 	}
 
-	// 发送WebSocket进度更新消息
+	// 发布任务进度事件
 	// 使用异步方式确保获取到最新状态
-	if s.statusCallback != nil {
-		go func(taskID string, prog int) {
-			// 等待一小段时间确保数据库更新完成
-			time.Sleep(50 * time.Millisecond)
-			// 获取最新的任务状态
-			task, err := s.GetTask(taskID)
-			if err == nil {
-				s.statusCallback(taskID, task.Status, task.Progress)
-			}
-		}(id, progress)
-	}
+	go func(taskID string, prog int) {
+		// 等待一小段时间确保数据库更新完成
+		time.Sleep(50 * time.Millisecond)
+		// 获取最新的任务状态
+		task, err := s.GetTask(taskID)
+		if err == nil {
+			s.notifyStatus(taskID, task.Status, task.Progress, "")
+		}
+	}(id, progress)
 
 	return nil
 }
@@ -254,23 +289,19 @@ func (s *taskServiceImpl) UpdateTaskProgressWithStage(id string, progress int, s
 	}
 
 	// 发送阶段信息日志
-	if s.logCallback != nil {
-		s.logCallback(id, "info", fmt.Sprintf("阶段: %s - 进度: %d%%", stage, progress))
-	}
+	s.sendLog(id, "info", fmt.Sprintf("阶段: %s - 进度: %d%%", stage, progress))
 
-	// 发送WebSocket进度更新消息
+	// 发布任务进度事件
 	// 使用异步方式确保获取到最新状态
-	if s.statusCallback != nil {
-		go func(taskID string, prog int) {
-			// 等待一小段时间确保数据库更新完成
-			time.Sleep(50 * time.Millisecond)
-			// 获取最新的任务状态
-			task, err := s.GetTask(taskID)
-			if err == nil {
-				s.statusCallback(taskID, task.Status, task.Progress)
-			}
-		}(id, progress)
-	}
+	go func(taskID string, prog int) {
+		// 等待一小段时间确保数据库更新完成
+		time.Sleep(50 * time.Millisecond)
+		// 获取最新的任务状态
+		task, err := s.GetTask(taskID)
+		if err == nil {
+			s.notifyStatus(taskID, task.Status, task.Progress, stage)
+		}
+	}(id, progress)
 
 	return nil
 }
@@ -282,32 +313,10 @@ func (s *taskServiceImpl) UpdateTaskError(id, errorMsg string) error {
 
 // ListTasks 列出任务
 func (s *taskServiceImpl) ListTasks(page, pageSize int32, status, taskType string) ([]*repository.Task, int, error) {
-	if pageSize <= 0 {
-		pageSize = 20 // 默认限制
-	}
-	if pageSize > 100 {
-		pageSize = 100 // 最大限制
-	}
-
-	offset := int((page - 1) * pageSize)
-	tasks, err := s.storage.ListTasks(status, taskType, int(pageSize), offset)
-	if err != nil {
-		return nil, 0, err
-	}
-
-	// 检查并修复僵尸任务（数据库中是running状态但实际没有运行的任务）
-	s.checkAndFixZombieTasks(tasks)
-
-	// 获取总数
-	total, err := s.storage.CountTasks(status, taskType)
-	if err != nil {
-		return tasks, 0, err
-	}
-
-	return tasks, total, nil
+	return s.SearchTasks(&repository.TaskFilter{Status: status, Type: taskType}, page, pageSize)
 }
 
-// StartTask 启动任务
+// StartTask 启动任务（对已结束的任务为重新开始，会清除断点从头执行）
 func (s *taskServiceImpl) StartTask(id string) error {
 	logger.Infof("StartTask 开始执行: %s", id)
 
@@ -351,6 +360,57 @@ func (s *taskServiceImpl) StartTask(id string) error {
 		}
 	}
 
+	// 重新开始时清除断点和失败条目，所有条目重新处理
+	if task.Status != "pending" {
+		if err := s.storage.ClearTaskCheckpoints(id); err != nil {
+			logger.Warnf("清除任务断点失败: %v", err)
+		}
+		if err := s.storage.ClearTaskFailedItems(id); err != nil {
+			logger.Warnf("清除任务失败条目失败: %v", err)
+		}
+	}
+
+	// 手动启动时重新开始计算尝试次数
+	s.resetAttempts(task)
+
+	return s.scheduleTask(task)
+}
+
+// ResumeTask 从断点恢复任务（保留已完成的条目，只处理剩余部分）
+func (s *taskServiceImpl) ResumeTask(id string) error {
+	logger.Infof("ResumeTask 开始执行: %s", id)
+
+	task, err := s.GetTask(id)
+	if err != nil {
+		return fmt.Errorf("获取任务失败: %v", err)
+	}
+
+	if task.Status != "failed" && task.Status != "cancelled" {
+		return fmt.Errorf("只有失败或取消的任务可以恢复，当前状态: %s", task.Status)
+	}
+
+	checkpoints, err := s.storage.GetTaskCheckpoints(id)
+	if err != nil {
+		return fmt.Errorf("获取任务断点失败: %v", err)
+	}
+
+	logger.Infof("恢复任务 %s (状态: %s)，已完成 %d 个条目", id, task.Status, len(checkpoints))
+	// 只清理错误信息，保留进度和计数
+	if err := s.UpdateTaskError(id, ""); err != nil {
+		logger.Warnf("清理任务错误信息失败: %v", err)
+	}
+	s.sendLog(id, "info", fmt.Sprintf("从断点恢复任务，已完成 %d 个条目", len(checkpoints)))
+
+	// 手动恢复时重新开始计算尝试次数
+	s.resetAttempts(task)
+
+	return s.scheduleTask(task)
+}
+
+// scheduleTask 启动任务，若同类型任务正在运行则加入等待队列
+func (s *taskServiceImpl) scheduleTask(task *repository.Task) error {
+	id := task.ID
+
 	// 检查同类型任务是否正在运行
 	hasRunningTaskOfSameType := s.hasRunningTaskOfType(task.Type, id)
 
@@ -361,10 +421,7 @@ func (s *taskServiceImpl) StartTask(id string) error {
 			return fmt.Errorf("更新任务状态失败: %v", err)
 		}
 		s.queueMutex.Lock()
-		if s.waitingTasksByType[task.Type] == nil {
-			s.waitingTasksByType[task.Type] = make([]string, 0)
-		}
-		s.waitingTasksByType[task.Type] = append(s.waitingTasksByType[task.Type], id)
+		s.enqueueWaitingTaskInLock(task)
 		logger.Infof("StartTask: 任务 %s 已加入 %s 类型等待队列（当前队列长度: %d）", id, task.Type, len(s.waitingTasksByType[task.Type]))
 		s.queueMutex.Unlock()
 		s.sendLog(id, "info", "任务等待中...")
@@ -384,22 +441,43 @@ func (s *taskServiceImpl) startTaskExecution(id string) error {
 		return fmt.Errorf("更新任务状态失败: %v", err)
 	}
 
-	// 创建任务上下文
-	ctx, cancel := context.WithCancel(context.Background())
-
-	// 保存取消函数
-	s.taskMutex.Lock()
-	s.runningTasks[id] = cancel
-	s.taskMutex.Unlock()
-
 	// 获取任务
 	task, err := s.GetTask(id)
 	if err != nil {
 		return fmt.Errorf("获取任务失败: %v", err)
 	}
 
+	// 记录本次尝试
+	policy := s.retryPolicy(task)
+	attempt := task.Attempt + 1
+	if err := s.storage.UpdateTaskAttempt(id, attempt, policy.MaxAttempts); err != nil {
+		logger.Warnf("更新任务尝试次数失败 %s: %v", id, err)
+	}
+	if err := s.storage.UpdateTaskNextRetry(id, nil); err != nil {
+		logger.Warnf("清除任务重试时间失败 %s: %v", id, err)
+	}
+	if policy.MaxAttempts > 1 {
+		s.sendLog(id, "info", fmt.Sprintf("开始第 %d/%d 次尝试", attempt, policy.MaxAttempts))
+	}
+
+	// 创建任务上下文，配置了超时的任务到期后自动取消
+	now := time.Now()
+	run := &taskRun{startedAt: now, heartbeat: now, attempt: attempt, retry: policy}
+	var ctx context.Context
+	if timeout := s.taskTimeout(task); timeout > 0 {
+		ctx, run.cancel = context.WithTimeout(context.Background(), timeout)
+		run.deadline = now.Add(timeout)
+	} else {
+		ctx, run.cancel = context.WithCancel(context.Background())
+	}
+
+	// 保存执行信息
+	s.taskMutex.Lock()
+	s.runningTasks[id] = run
+	s.taskMutex.Unlock()
+
 	// 启动实际的任务执行逻辑
-	go s.executeTaskWithContext(ctx, task)
+	go s.executeTaskWithContext(ctx, task, run)
 
 	// 启动任务完成后继续处理等待队列
 	go s.processNextTask()
@@ -567,8 +645,8 @@ func (s *taskServiceImpl) StopTask(id string) error {
 
 	// 取消任务上下文
 	s.taskMutex.Lock()
-	if cancel, exists := s.runningTasks[id]; exists {
-		cancel() // 取消任务执行
+	if run, exists := s.runningTasks[id]; exists {
+		run.cancel() // 取消任务执行
 		delete(s.runningTasks, id)
 	}
 	s.taskMutex.Unlock()
@@ -593,69 +671,33 @@ func (s *taskServiceImpl) CancelTask(id string) error {
 		return fmt.Errorf("任务已完成，无法取消")
 	}
 
+	// 从等待队列中移除，避免之后被自动启动
+	s.removeFromWaitingQueue(id)
+
+	// 停止正在执行的任务
+	s.taskMutex.Lock()
+	if run, exists := s.runningTasks[id]; exists {
+		run.cancel()
+		delete(s.runningTasks, id)
+	}
+	s.taskMutex.Unlock()
+
 	// 更新状态为cancelled
 	if err := s.UpdateTaskStatus(id, "cancelled"); err != nil {
 		return fmt.Errorf("更新任务状态失败: %v", err)
 	}
 
-	// TODO: 这里应该停止实际的任务执行逻辑
-
 	return nil
 }
 
-// DeleteTask 删除任务
+// DeleteTask 删除任务记录，保留磁盘上的产出文件（需要删除文件时使用 DeleteTaskWithOptions）
 func (s *taskServiceImpl) DeleteTask(id string) error {
-	// 检查任务是否存在
-	task, err := s.GetTask(id)
-	if err != nil {
-		return fmt.Errorf("获取任务失败: %v", err)
-	}
-
-	// 如果任务正在运行，先停止它
-	if task.Status == "running" {
-		if err := s.StopTask(id); err != nil {
-			logger.Infof("停止运行中的任务失败: %v", err)
-			// 即使停止失败，也继续删除
-		}
-	}
-
-	// 删除任务相关的图片文件（在删除数据库记录之前获取任务信息）
-	pathManager := paths.GetPathManager()
-	if pathManager != nil {
-		var taskDir string
-		if task != nil {
-			// 使用新格式：[时间]_任务类型_哈希值
-			taskDir = pathManager.GetTaskImagesDir(id, task.Type, task.CreatedAt)
-		} else {
-			// 回退到旧格式：task_{id}
-			taskDir = filepath.Join(pathManager.GetImagesDir(), fmt.Sprintf("task_%s", id))
-		}
-
-		// 尝试删除新格式
-		if err := os.RemoveAll(taskDir); err != nil {
-			logger.Infof("删除任务图片目录失败: %v", err)
-		}
-
-		// 也尝试删除旧格式（向后兼容）
-		oldTaskDir := filepath.Join(pathManager.GetImagesDir(), fmt.Sprintf("task_%s", id))
-		if oldTaskDir != taskDir {
-			if err := os.RemoveAll(oldTaskDir); err != nil {
-				logger.Infof("删除旧格式任务图片目录失败: %v", err)
-			}
-		}
-	}
-
-	// 从数据库中删除任务
-	if err := s.storage.DeleteTask(id); err != nil {
-		return fmt.Errorf("删除任务失败: %v", err)
-	}
-
-	logger.Infof("任务 %s 已删除", id)
-	return nil
+	_, err := s.DeleteTaskWithOptions(id, false)
+	return err
 }
 
 // executeTask 执行任务
-func (s *taskServiceImpl) executeTaskWithContext(ctx context.Context, task *repository.Task) {
+func (s *taskServiceImpl) executeTaskWithContext(ctx context.Context, task *repository.Task, run *taskRun) {
 	// 检查上下文是否已被取消
 	select {
 	case <-ctx.Done():
@@ -666,10 +708,10 @@ func (s *taskServiceImpl) executeTaskWithContext(ctx context.Context, task *repo
 	}
 
 	// 执行任务
-	s.executeTask(ctx, task)
+	s.executeTask(ctx, task, run)
 }
 
-func (s *taskServiceImpl) executeTask(ctx context.Context, task *repository.Task) {
+func (s *taskServiceImpl) executeTask(ctx context.Context, task *repository.Task, run *taskRun) {
 	logger.Infof("executeTask 开始执行: %s", task.ID)
 
 	// 解析任务配置
@@ -680,24 +722,33 @@ func (s *taskServiceImpl) executeTask(ctx context.Context, task *repository.Task
 		return
 	}
 
-	// 根据任务类型执行不同的逻辑
-	switch task.Type {
-	case "tag":
-		// 标签任务使用专门的执行方法
-		s.executeTagTask(ctx, task, config)
-	case "crawl":
-		// 爬虫任务使用专门的执行方法
-		s.executeCrawlTask(ctx, task, config)
-	case "generate":
-		// AI生成任务使用专门的执行方法
-		s.executeGenerateTask(ctx, task, config)
-	case "train", "classify":
-		// 这些类型的任务将在这里处理
-		s.UpdateTaskError(task.ID, fmt.Sprintf("任务类型 %s 暂未实现", task.Type))
+	// 查找该任务类型注册的执行器
+	executor, exists := s.getExecutor(task.Type)
+	if !exists {
+		// 任务创建后执行器被移除（例如旧任务的类型已不再支持）
+		logger.Warnf("executeTask: 未找到 %s 任务的执行器 (任务ID: %s)", task.Type, task.ID)
+		s.UpdateTaskError(task.ID, fmt.Sprintf("未找到任务类型 %s 的执行器", task.Type))
 		s.UpdateTaskStatus(task.ID, "failed")
-	default:
-		logger.Warnf("executeTask 收到未处理的任务类型 %s (任务ID: %s)，这可能不应该发生", task.Type, task.ID)
+		s.removeRun(task.ID, run)
+		return
 	}
+
+	// 旧任务的配置可能缺少默认值，执行前再按 Schema 填充一次
+	if schema := executor.Schema(); schema != nil {
+		reserved, err := takeReservedFields(config)
+		if err == nil {
+			err = schema.Apply(config)
+		}
+		if err != nil {
+			s.UpdateTaskError(task.ID, err.Error())
+			s.UpdateTaskStatus(task.ID, "failed")
+			s.removeRun(task.ID, run)
+			return
+		}
+		restoreReservedFields(config, reserved)
+	}
+
+	s.runExecutor(ctx, task, executor, config, run)
 }
 
 // CleanupTasks 清理任务
@@ -734,23 +785,79 @@ func (s *taskServiceImpl) CleanupTasks(cleanupType string) (int, error) {
 	return cleanedCount, nil
 }
 
-// SetLogCallback 设置日志回调函数
-func (s *taskServiceImpl) SetLogCallback(callback func(taskID, level, message string)) {
-	s.logCallback = callback
+// EventBus 获取任务事件总线
+func (s *taskServiceImpl) EventBus() events.Bus {
+	return s.bus
 }
 
-func (s *taskServiceImpl) SetStatusCallback(callback func(taskID, status string, progress int)) {
-	s.statusCallback = callback
-}
-
-// sendLog 发送日志消息
+// sendLog 发送日志消息（先同步写入数据库，再发布日志事件供实时订阅者使用）
+// 事件总线在订阅者缓冲区已满时会丢弃事件，因此持久化不能依赖总线
 func (s *taskServiceImpl) sendLog(taskID, level, message string) {
-	if s.logCallback != nil {
-		s.logCallback(taskID, level, message)
+	taskLog := &repository.TaskLog{
+		TaskID:    taskID,
+		Level:     level,
+		Message:   message,
+		CreatedAt: time.Now(),
 	}
+	if err := s.storage.AddTaskLog(taskLog); err != nil {
+		logger.Warnf("保存任务日志失败 %s: %v", taskID, err)
+	}
+
+	s.bus.Publish(&events.Event{
+		Type:   events.TaskLog,
+		TaskID: taskID,
+		Time:   taskLog.CreatedAt,
+		Data: &events.TaskLogData{
+			ID:      taskLog.ID,
+			Level:   level,
+			Message: message,
+		},
+	})
 }
 
-// executeGenerateTask 已删除 - AI生成任务已迁移到 ai_handler.go
+// notifyStatus 发布任务状态事件
+func (s *taskServiceImpl) notifyStatus(taskID, status string, progress int, stage string) {
+	s.bus.Publish(&events.Event{
+		Type:   events.TaskStatus,
+		TaskID: taskID,
+		Data: &events.TaskStatusData{
+			Status:   status,
+			Progress: progress,
+			Stage:    stage,
+		},
+	})
+}
+
+// GetTaskLogs 分页获取任务日志（pageSize <= 0 表示返回全部）
+func (s *taskServiceImpl) GetTaskLogs(id, level string, page, pageSize int32) ([]*repository.TaskLog, int, error) {
+	if _, err := s.GetTask(id); err != nil {
+		return nil, 0, fmt.Errorf("获取任务失败: %v", err)
+	}
+
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize > 1000 {
+		pageSize = 1000 // 最大限制
+	}
+
+	offset := 0
+	if pageSize > 0 {
+		offset = int((page - 1) * pageSize)
+	}
+
+	logs, err := s.storage.ListTaskLogs(id, level, int(pageSize), offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("获取任务日志失败: %v", err)
+	}
+
+	total, err := s.storage.CountTaskLogs(id, level)
+	if err != nil {
+		return logs, 0, fmt.Errorf("统计任务日志失败: %v", err)
+	}
+
+	return logs, total, nil
+}
 
 // UpdateTaskCompletedAt 更新任务完成时间
 func (s *taskServiceImpl) UpdateTaskCompletedAt(id string, completedAt time.Time) error {
@@ -766,13 +873,11 @@ func (s *taskServiceImpl) UpdateTaskImagesFound(id string, count int) error {
 		return err
 	}
 
-	// 发送WebSocket更新消息
-	if s.statusCallback != nil {
-		// 获取当前任务以获取状态和进度信息
-		task, err := s.GetTask(id)
-		if err == nil {
-			s.statusCallback(id, task.Status, task.Progress)
-		}
+	// 发布任务状态事件
+	// 获取当前任务以获取状态和进度信息
+	task, err := s.GetTask(id)
+	if err == nil {
+		s.notifyStatus(id, task.Status, task.Progress, "")
 	}
 
 	return nil
@@ -785,19 +890,17 @@ func (s *taskServiceImpl) UpdateTaskImagesDownloaded(id string, count int) error
 		return err
 	}
 
-	// 发送WebSocket更新消息（异步获取最新状态）
-	if s.statusCallback != nil {
-		go func(taskID string, downloadedCount int) {
-			// 等待一小段时间确保数据库更新完成
-			time.Sleep(50 * time.Millisecond)
-			// 获取最新的任务状态
-			task, err := s.GetTask(taskID)
-			if err == nil {
-				// 发送状态更新，这样会触发完整的任务更新（包括下载计数）
-				s.statusCallback(taskID, task.Status, task.Progress)
-			}
-		}(id, count)
-	}
+	// 发布任务状态事件（异步获取最新状态）
+	go func(taskID string, downloadedCount int) {
+		// 等待一小段时间确保数据库更新完成
+		time.Sleep(50 * time.Millisecond)
+		// 获取最新的任务状态
+		task, err := s.GetTask(taskID)
+		if err == nil {
+			// 发送状态更新，这样会触发完整的任务更新（包括下载计数）
+			s.notifyStatus(taskID, task.Status, task.Progress, "")
+		}
+	}(id, count)
 
 	return nil
 }
@@ -816,513 +919,109 @@ func (s *taskServiceImpl) UpdateTaskResult(id string, result map[string]interfac
 		return err
 	}
 
-	// 发送WebSocket更新消息
-	if s.statusCallback != nil {
-		// 获取当前任务以获取状态和进度信息
-		task, err := s.GetTask(id)
-		if err == nil {
-			s.statusCallback(id, task.Status, task.Progress)
-		}
+	// 发布任务状态事件
+	// 获取当前任务以获取状态和进度信息
+	task, err := s.GetTask(id)
+	if err == nil {
+		s.notifyStatus(id, task.Status, task.Progress, "")
 	}
 
 	return nil
 }
 
-// executeTagTask 执行标签任务
-func (s *taskServiceImpl) executeTagTask(ctx context.Context, task *repository.Task, config map[string]interface{}) {
-	logger.Infof("开始执行标签任务: %s", task.ID)
-	s.sendLog(task.ID, "info", "开始执行标签任务")
-
-	// 检查上下文是否已被取消
-	select {
-	case <-ctx.Done():
-		s.UpdateTaskStatus(task.ID, "cancelled")
-		s.sendLog(task.ID, "info", "任务已被取消")
-		return
-	default:
+// GetTaskFailedItems 获取任务中处理失败的条目
+func (s *taskServiceImpl) GetTaskFailedItems(id string) ([]*repository.TaskFailedItem, error) {
+	if _, err := s.GetTask(id); err != nil {
+		return nil, fmt.Errorf("获取任务失败: %v", err)
 	}
+	return s.storage.GetTaskFailedItems(id)
+}
 
-	// 更新进度到10% - 初始化阶段
-	s.UpdateTaskProgressWithStage(task.ID, 10, "初始化")
-	s.sendLog(task.ID, "info", "标签任务初始化完成 (10%)")
-
-	// 创建 tagger 实例
-	tagger, err := tagger.NewWD14Tagger()
+// RetryFailedItems 创建一个只重试原任务失败条目的新任务
+func (s *taskServiceImpl) RetryFailedItems(id string) (*repository.Task, error) {
+	task, err := s.GetTask(id)
 	if err != nil {
-		s.sendLog(task.ID, "error", fmt.Sprintf("创建标签器失败: %v", err))
-		s.UpdateTaskError(task.ID, fmt.Sprintf("创建标签器失败: %v", err))
-		s.UpdateTaskStatus(task.ID, "failed")
-		// 从运行任务列表中移除
-		s.taskMutex.Lock()
-		delete(s.runningTasks, task.ID)
-		s.taskMutex.Unlock()
-		return
-	}
-
-	// 解析配置参数（支持单个路径或路径数组）
-	inputDir, ok := config["input_dir"]
-	if !ok || inputDir == nil {
-		s.sendLog(task.ID, "error", "输入目录不能为空")
-		s.UpdateTaskError(task.ID, "输入目录不能为空")
-		s.UpdateTaskStatus(task.ID, "failed")
-		// 从运行任务列表中移除
-		s.taskMutex.Lock()
-		delete(s.runningTasks, task.ID)
-		s.taskMutex.Unlock()
-		return
-	}
-
-	// 转换为字符串数组以获取输入目录列表
-	var inputDirs []string
-	switch v := inputDir.(type) {
-	case string:
-		if v != "" {
-			inputDirs = []string{v}
-		}
-	case []interface{}:
-		for _, dir := range v {
-			if dirStr, ok := dir.(string); ok && dirStr != "" {
-				inputDirs = append(inputDirs, dirStr)
-			}
-		}
-	case []string:
-		for _, dir := range v {
-			if dir != "" {
-				inputDirs = append(inputDirs, dir)
-			}
-		}
-	}
-
-	if len(inputDirs) == 0 {
-		s.sendLog(task.ID, "error", "输入目录不能为空")
-		s.UpdateTaskError(task.ID, "输入目录不能为空")
-		s.UpdateTaskStatus(task.ID, "failed")
-		// 从运行任务列表中移除
-		s.taskMutex.Lock()
-		delete(s.runningTasks, task.ID)
-		s.taskMutex.Unlock()
-		return
-	}
-
-	outputDir, ok := config["output_dir"].(string)
-	if !ok || outputDir == "" {
-		outputDir = "tags/" // 默认输出目录
-	}
-
-	// 设置默认值
-	tagRequest := &models.TagRequest{
-		InputDir:   inputDirs, // 使用数组
-		OutputDir:  outputDir,
-		Analyzer:   "wd14tagger",
-		TagOrder:   "score",
-		SaveType:   "txt",
-		Limit:      100,
-		SkipTags:   []string{},
-		ExtendTags: []string{},
+		return nil, fmt.Errorf("获取任务失败: %v", err)
 	}
 
-	// 读取配置参数
-	if limit, ok := config["limit"].(float64); ok {
-		tagRequest.Limit = int(limit)
-	}
-
-	if saveType, ok := config["save_type"].(string); ok {
-		tagRequest.SaveType = saveType
-	}
-
-	if skipTags, ok := config["skip_tags"].([]interface{}); ok {
-		for _, tag := range skipTags {
-			if tagStr, ok := tag.(string); ok {
-				tagRequest.SkipTags = append(tagRequest.SkipTags, tagStr)
-			}
-		}
-	}
-
-	if extendTags, ok := config["extend_tags"].([]interface{}); ok {
-		for _, tag := range extendTags {
-			if tagStr, ok := tag.(string); ok {
-				tagRequest.ExtendTags = append(tagRequest.ExtendTags, tagStr)
-			}
-		}
-	}
-
-	s.sendLog(task.ID, "info", fmt.Sprintf("配置完成: 输入目录数量=%d, 输出目录=%s, 限制=%d", len(inputDirs), outputDir, tagRequest.Limit))
-
-	// 更新进度到20% - 配置完成
-	s.UpdateTaskProgressWithStage(task.ID, 20, "配置完成")
-
-	// 检查上下文是否已被取消
-	select {
-	case <-ctx.Done():
-		s.UpdateTaskStatus(task.ID, "cancelled")
-		s.sendLog(task.ID, "info", "任务已被取消")
-		// 从运行任务列表中移除
-		s.taskMutex.Lock()
-		delete(s.runningTasks, task.ID)
-		s.taskMutex.Unlock()
-		return
-	default:
-	}
-
-	// 执行标签生成
-	s.UpdateTaskProgressWithStage(task.ID, 30, "开始生成标签")
-	s.sendLog(task.ID, "info", "开始调用 WD14 Tagger 生成标签...")
-
-	// 创建进度回调函数，使用内部变量缓存上次进度，减少不必要的更新
-	lastProgress := -1
-	progressCallback := func(current int, total int) {
-		// 计算进度：30%-95% (生成标签占65%)
-		progress := 30 + int(float64(current)/float64(total)*65)
-
-		// 只在进度变化超过5%或完成时更新，减少WebSocket消息频率
-		if progress != lastProgress && (progress-lastProgress >= 5 || progress == 100 || current == total) {
-			s.UpdateTaskProgressWithStage(task.ID, progress, fmt.Sprintf("生成标签 (%d/%d)", current, total))
-			lastProgress = progress
-		}
-	}
-
-	// 创建日志回调函数
-	logCallback := func(level string, message string) {
-		s.sendLog(task.ID, level, message)
-	}
-
-	if err := tagger.GenerateTagsWithCallback(tagRequest, progressCallback, logCallback); err != nil {
-		s.sendLog(task.ID, "error", fmt.Sprintf("生成标签失败: %v", err))
-		s.UpdateTaskError(task.ID, fmt.Sprintf("生成标签失败: %v", err))
-		s.UpdateTaskStatus(task.ID, "failed")
-		// 从运行任务列表中移除
-		s.taskMutex.Lock()
-		delete(s.runningTasks, task.ID)
-		s.taskMutex.Unlock()
-		return
-	}
-
-	// 更新进度到100% - 任务完成
-	s.UpdateTaskProgressWithStage(task.ID, 100, "任务完成")
-	s.sendLog(task.ID, "info", "标签生成完成 (100%)")
-
-	// 任务完成
-	logger.Infof("executeTagTask: 任务 %s 准备更新为 completed", task.ID)
-	s.UpdateTaskStatus(task.ID, "completed")
-	s.sendLog(task.ID, "info", "标签任务完成！")
-	logger.Infof("标签任务完成: %s", task.ID)
-
-	// 从运行任务列表中移除
-	s.taskMutex.Lock()
-	delete(s.runningTasks, task.ID)
-	s.taskMutex.Unlock()
-	logger.Infof("任务 %s 已完成，从运行列表移除", task.ID)
-
-	// 处理下一个等待的任务
-	logger.Infof("executeTagTask: 任务 %s 完成后，准备处理下一个任务", task.ID)
-	// 延迟一点再处理下一个任务，确保状态完全更新
-	go func() {
-		time.Sleep(1 * time.Second)
-		s.processNextTask()
-	}()
-}
-
-// executeCrawlTask 执行爬虫任务
-func (s *taskServiceImpl) executeCrawlTask(ctx context.Context, task *repository.Task, config map[string]interface{}) {
-	logger.Infof("开始执行爬虫任务: %s", task.ID)
-	s.sendLog(task.ID, "info", "开始执行爬虫任务")
-
-	// 检查上下文是否已被取消
-	select {
-	case <-ctx.Done():
-		s.UpdateTaskStatus(task.ID, "cancelled")
-		s.sendLog(task.ID, "info", "任务已被取消")
-		// 从运行任务列表中移除
-		s.taskMutex.Lock()
-		delete(s.runningTasks, task.ID)
-		s.taskMutex.Unlock()
-		return
-	default:
+	if task.Status == "running" {
+		return nil, fmt.Errorf("任务正在运行中，无法重试失败条目")
 	}
 
-	// 更新进度到5%
-	s.UpdateTaskProgress(task.ID, 5)
-	s.sendLog(task.ID, "info", "爬虫任务初始化完成 (5%)")
-
-	// 创建任务特定的爬虫实例
-	crawlerInstance, err := crawler.NewCrawlerForTask(task.ID, config)
+	failedItems, err := s.storage.GetTaskFailedItems(id)
 	if err != nil {
-		s.sendLog(task.ID, "error", fmt.Sprintf("创建爬虫失败: %v", err))
-		s.UpdateTaskError(task.ID, fmt.Sprintf("创建爬虫失败: %v", err))
-		s.UpdateTaskStatus(task.ID, "failed")
-		// 从运行任务列表中移除
-		s.taskMutex.Lock()
-		delete(s.runningTasks, task.ID)
-		s.taskMutex.Unlock()
-		return
-	}
-
-	// 设置任务信息（用于生成任务文件夹名：[时间]_任务类型_哈希值）
-	crawlerInstance.SetTaskInfo(task.Type, task.CreatedAt)
-
-	// 配置代理设置
-	if proxyEnabled, exists := config["proxy_enabled"].(bool); exists && proxyEnabled {
-		if proxyURL, exists := config["proxy_url"].(string); exists && proxyURL != "" {
-			crawlerInstance.SetProxy(true, proxyURL)
-		}
-	} else {
-		crawlerInstance.SetProxy(false, "")
+		return nil, fmt.Errorf("获取失败条目失败: %v", err)
 	}
-
-	// 配置Cookie设置
-	if cookie, exists := config["cookie"].(string); exists && cookie != "" {
-		if cookie == "default" {
-			// 使用默认Cookie
-		} else {
-			crawlerInstance.SetCookie(cookie)
-		}
+	if len(failedItems) == 0 {
+		return nil, fmt.Errorf("任务没有失败的条目")
 	}
 
-	// 更新进度到20%
-	s.UpdateTaskProgress(task.ID, 20)
-	s.sendLog(task.ID, "info", "爬虫配置完成 (20%)")
-
-	// 检查上下文是否已被取消
-	select {
-	case <-ctx.Done():
-		s.UpdateTaskStatus(task.ID, "cancelled")
-		s.sendLog(task.ID, "info", "任务已被取消")
-		s.taskMutex.Lock()
-		delete(s.runningTasks, task.ID)
-		s.taskMutex.Unlock()
-		return
-	default:
+	var config map[string]interface{}
+	if err := json.Unmarshal([]byte(task.Config), &config); err != nil {
+		return nil, fmt.Errorf("配置解析失败: %v", err)
 	}
 
-	// 根据配置执行爬取
-	crawlType := config["type"].(string)
-	var results []*models.PixivImage
-	var crawlErr error
-
-	switch crawlType {
+	switch task.Type {
+	case "crawl":
+		retryItems := make([]map[string]interface{}, 0, len(failedItems))
+		for _, item := range failedItems {
+			retryItems = append(retryItems, map[string]interface{}{
+				"url":      item.Item,
+				"filename": item.Name,
+			})
+		}
+		config["retry_items"] = retryItems
+		delete(config, "max_images")
 	case "tag":
-		query := config["query"].(string)
-		order := config["order"].(string)
-		mode := config["mode"].(string)
-		limit := int(config["limit"].(float64))
-		results, crawlErr = crawlerInstance.CrawlByTag(query, order, mode, limit)
-
-	case "user":
-		userID := int(config["user_id"].(float64))
-		limit := int(config["limit"].(float64))
-		results, crawlErr = crawlerInstance.CrawlByUser(userID, limit)
-
-	case "illust":
-		illustID := int(config["illust_id"].(float64))
-		illustResults, err := crawlerInstance.CrawlByIllust(illustID)
-		crawlErr = err
-		if illustResults != nil {
-			results = illustResults
+		imageFiles := make([]string, 0, len(failedItems))
+		for _, item := range failedItems {
+			imageFiles = append(imageFiles, item.Item)
 		}
-
-	default:
-		s.sendLog(task.ID, "error", fmt.Sprintf("不支持的类型: %s", crawlType))
-		s.UpdateTaskError(task.ID, fmt.Sprintf("不支持的类型: %s", crawlType))
-		s.UpdateTaskStatus(task.ID, "failed")
-		s.taskMutex.Lock()
-		delete(s.runningTasks, task.ID)
-		s.taskMutex.Unlock()
-		return
-	}
-
-	if crawlErr != nil {
-		s.sendLog(task.ID, "error", fmt.Sprintf("爬取失败: %v", crawlErr))
-		s.UpdateTaskError(task.ID, fmt.Sprintf("爬取失败: %v", crawlErr))
-		s.UpdateTaskStatus(task.ID, "failed")
-		s.taskMutex.Lock()
-		delete(s.runningTasks, task.ID)
-		s.taskMutex.Unlock()
-		return
-	}
-
-	// 保存原始获取到的图片数量
-	originalImagesCount := len(results)
-
-	// 应用图片数量限制
-	maxImages := 0
-	if maxImagesVal, exists := config["max_images"]; exists {
-		maxImages = int(maxImagesVal.(float64))
-	}
-
-	// 如果有设置限制且获取到的图片数量超过限制，只保留前maxImages张
-	if maxImages > 0 && len(results) > maxImages {
-		results = results[:maxImages]
-		s.sendLog(task.ID, "info", fmt.Sprintf("应用图片数量限制：已获取 %d 张，限制下载 %d 张", originalImagesCount, len(results)))
-	}
-
-	// 记录原始获取数量（不包括限制）
-	s.UpdateTaskImagesFound(task.ID, originalImagesCount)
-	s.sendLog(task.ID, "info", fmt.Sprintf("爬取成功，获得 %d 张图片", originalImagesCount))
-
-	// 检查上下文是否已被取消
-	select {
-	case <-ctx.Done():
-		s.UpdateTaskStatus(task.ID, "cancelled")
-		s.sendLog(task.ID, "info", "任务已被取消")
-		s.taskMutex.Lock()
-		delete(s.runningTasks, task.ID)
-		s.taskMutex.Unlock()
-		return
+		config["image_files"] = imageFiles
+		config["limit"] = len(imageFiles)
 	default:
+		return nil, fmt.Errorf("任务类型 %s 不支持重试失败条目", task.Type)
 	}
+	config["retry_of"] = id
 
-	// 开始下载图片
-	if len(results) > 0 {
-		s.sendLog(task.ID, "info", fmt.Sprintf("开始下载 %d 张图片", len(results)))
-		downloadedCount := 0
-		for i, image := range results {
-			// 检查是否被取消
-			select {
-			case <-ctx.Done():
-				s.UpdateTaskStatus(task.ID, "cancelled")
-				s.sendLog(task.ID, "info", "任务已被取消")
-				s.taskMutex.Lock()
-				delete(s.runningTasks, task.ID)
-				s.taskMutex.Unlock()
-				return
-			default:
-			}
-
-			// 更新进度: 50% + 45% * (i / total)
-			progress := 50 + int(45*float64(i)/float64(len(results)))
-			s.UpdateTaskProgress(task.ID, progress)
-
-			// 每10张图片或每张图片发送下载进度日志
-			if (i+1)%10 == 0 || i == 0 || i == len(results)-1 {
-				s.sendLog(task.ID, "info", fmt.Sprintf("正在下载图片 %d/%d", i+1, len(results)))
-			}
-
-			// 生成文件名
-			// 从URL中提取文件扩展名，如果无法提取则使用.jpg
-			fileExt := ".jpg"
-			if ext := filepath.Ext(image.URL); ext != "" {
-				fileExt = ext
-			}
-			// 使用图片ID和索引生成唯一文件名
-			filename := fmt.Sprintf("artworks_%d_p%02d%s", image.ID, i+1, fileExt)
-
-			// 下载图片
-			if err := crawlerInstance.DownloadImage(image.URL, filename, task.ID, func(url, filename string, downloaded, total int64, percent float64) {
-				// 进度回调（可选）
-			}); err != nil {
-				logger.Warnf("下载图片失败 %s: %v", image.URL, err)
-				s.sendLog(task.ID, "warning", fmt.Sprintf("下载失败: %s", image.Title))
-			} else {
-				downloadedCount++
-				// 实时更新下载计数
-				s.UpdateTaskImagesDownloaded(task.ID, downloadedCount)
-				// 每10张或最后一张图片发送成功日志
-				if downloadedCount%10 == 0 || downloadedCount == len(results) {
-					s.sendLog(task.ID, "info", fmt.Sprintf("已下载 %d/%d 张图片", downloadedCount, len(results)))
-				}
-			}
-		}
-		s.sendLog(task.ID, "info", fmt.Sprintf("图片下载完成，成功下载 %d/%d 张", downloadedCount, len(results)))
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("序列化任务配置失败: %v", err)
 	}
 
-	// 更新进度到95%
-	s.UpdateTaskProgress(task.ID, 95)
+	logger.Infof("为任务 %s 创建重试任务，失败条目数: %d", id, len(failedItems))
+	return s.CreateTask(task.Type, string(configJSON))
+}
 
-	// 检查上下文是否已被取消
-	select {
-	case <-ctx.Done():
-		s.UpdateTaskStatus(task.ID, "cancelled")
-		s.sendLog(task.ID, "info", "任务已被取消")
-		s.taskMutex.Lock()
-		delete(s.runningTasks, task.ID)
-		s.taskMutex.Unlock()
-		return
-	default:
+// recordFailedItem 记录任务中处理失败的条目
+func (s *taskServiceImpl) recordFailedItem(taskID, item, name string, itemErr error) {
+	failedItem := &repository.TaskFailedItem{
+		TaskID:    taskID,
+		Item:      item,
+		Name:      name,
+		Error:     itemErr.Error(),
+		CreatedAt: time.Now(),
 	}
-
-	// 任务完成
-	s.UpdateTaskProgress(task.ID, 100)
-	s.sendLog(task.ID, "info", "爬虫任务完成！")
-	logger.Infof("爬虫任务完成: %s", task.ID)
-
-	// 保存任务结果信息（包括预期下载数量）
-	result := map[string]interface{}{
-		"expected_images": len(results), // 预期下载的图片数量
-		"has_limit":       maxImages > 0,
+	if err := s.storage.AddTaskFailedItem(failedItem); err != nil {
+		logger.Warnf("记录失败条目失败 %s: %v", taskID, err)
 	}
-	s.UpdateTaskResult(task.ID, result)
-
-	// 更新状态为completed
-	s.UpdateTaskStatus(task.ID, "completed")
-
-	// 从运行任务列表中移除
-	s.taskMutex.Lock()
-	delete(s.runningTasks, task.ID)
-	s.taskMutex.Unlock()
-	logger.Infof("任务 %s 已完成，从运行列表移除", task.ID)
-
-	// 处理下一个等待的任务
-	logger.Infof("executeCrawlTask: 任务 %s 完成后，准备处理下一个任务", task.ID)
-	// 延迟一点再处理下一个任务，确保状态完全更新
-	go func() {
-		time.Sleep(1 * time.Second)
-		s.processNextTask()
-	}()
 }
 
-// RegisterExecutor 注册任务执行器
-func (s *taskServiceImpl) RegisterExecutor(taskType string, executor TaskExecutor) {
-	if s.executors == nil {
-		s.executors = make(map[string]TaskExecutor)
+// clearFailedItem 条目处理成功后移除其失败记录（恢复任务时之前失败的条目可能已成功）
+func (s *taskServiceImpl) clearFailedItem(taskID, item string) {
+	if err := s.storage.RemoveTaskFailedItem(taskID, item); err != nil {
+		logger.Warnf("移除失败条目失败 %s: %v", taskID, err)
 	}
-	s.executors[taskType] = executor
-	logger.Infof("注册了 %s 任务的执行器", taskType)
 }
 
-// executeGenerateTask 执行AI生成任务
-func (s *taskServiceImpl) executeGenerateTask(ctx context.Context, task *repository.Task, config map[string]interface{}) {
-	logger.Infof("executeGenerateTask: 开始执行AI生成任务 %s", task.ID)
-
-	// 检查上下文是否已被取消
-	select {
-	case <-ctx.Done():
-		s.UpdateTaskStatus(task.ID, "cancelled")
-		s.sendLog(task.ID, "info", "任务已被取消")
-		s.taskMutex.Lock()
-		delete(s.runningTasks, task.ID)
-		s.taskMutex.Unlock()
-		return
-	default:
-	}
-
-	// 查找是否有注册的执行器
-	if executor, exists := s.executors["generate"]; exists {
-		// 使用注册的执行器执行
-		logger.Infof("executeGenerateTask: 使用注册的执行器执行任务 %s", task.ID)
-		executor.ExecuteGenerateTask(ctx, task.ID, config)
-
-		// 任务完成后，从运行任务列表中移除
-		s.taskMutex.Lock()
-		delete(s.runningTasks, task.ID)
-		s.taskMutex.Unlock()
-
-		// 处理下一个等待的任务
-		logger.Infof("executeGenerateTask: 任务 %s 已完成，准备处理下一个任务", task.ID)
-		// 延迟一点再处理下一个任务，确保状态完全更新
-		go func() {
-			time.Sleep(1 * time.Second)
-			s.processNextTask()
-		}()
-	} else {
-		// 如果没有注册的执行器，标记为失败
-		logger.Warnf("executeGenerateTask: 未找到 generate 任务的执行器")
-		s.UpdateTaskError(task.ID, "未找到AI生成任务的执行器")
-		s.UpdateTaskStatus(task.ID, "failed")
-
-		s.taskMutex.Lock()
-		delete(s.runningTasks, task.ID)
-		s.taskMutex.Unlock()
+// countFailedItems 统计任务当前的失败条目数量
+func (s *taskServiceImpl) countFailedItems(taskID string) int {
+	items, err := s.storage.GetTaskFailedItems(taskID)
+	if err != nil {
+		logger.Warnf("获取失败条目失败 %s: %v", taskID, err)
+		return 0
 	}
+	return len(items)
 }
 
 // contains 检查切片是否包含指定元素
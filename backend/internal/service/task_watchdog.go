@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"pixiv-tailor/backend/internal/logger"
+	"pixiv-tailor/backend/internal/repository"
+	pkgerrors "pixiv-tailor/backend/pkg/errors"
+)
+
+const (
+	// TaskRuntimeConfigModule 任务运行配置所在的配置模块
+	TaskRuntimeConfigModule = "task"
+	// TaskTimeoutConfigKey 任务配置中的单任务超时字段（秒），优先于按类型的超时
+	TaskTimeoutConfigKey = "timeout_seconds"
+
+	// heartbeatPersistInterval 心跳写入数据库的最小间隔，避免每次上报都写库
+	heartbeatPersistInterval = 10 * time.Second
+	// timeoutGracePeriod 超时后给执行器响应取消的宽限时间，超过后由看门狗强制终止
+	timeoutGracePeriod = 30 * time.Second
+	// defaultWatchdogInterval 看门狗检查间隔
+	defaultWatchdogInterval = 30 * time.Second
+)
+
+// TaskRuntimeConfig 任务运行配置（保存在配置模块 "task" 中）
+type TaskRuntimeConfig struct {
+	DefaultTimeout   int            `json:"default_timeout"`   // 默认执行超时（秒），0 表示不限时
+	TypeTimeouts     map[string]int `json:"type_timeouts"`     // 按任务类型的执行超时（秒），覆盖默认值
+	HeartbeatTimeout int            `json:"heartbeat_timeout"` // 超过该时长（秒）没有心跳视为卡死，0 表示不检测
+	StallAction      string         `json:"stall_action"`      // 卡死任务的处理方式：fail（标记失败）或 cancel（标记取消）
+	// RetryPolicies 按任务类型的自动重试策略
+	RetryPolicies map[string]*RetryPolicy `json:"retry_policies"`
+}
+
+// DefaultTaskRuntimeConfig 默认任务运行配置
+func DefaultTaskRuntimeConfig() *TaskRuntimeConfig {
+	return &TaskRuntimeConfig{
+		DefaultTimeout:   0,
+		TypeTimeouts:     map[string]int{},
+		HeartbeatTimeout: 0,
+		StallAction:      "fail",
+		RetryPolicies:    map[string]*RetryPolicy{},
+	}
+}
+
+// Validate 校验任务运行配置
+func (c *TaskRuntimeConfig) Validate() error {
+	if c.DefaultTimeout < 0 {
+		return fmt.Errorf("default_timeout 不能为负数")
+	}
+	for taskType, timeout := range c.TypeTimeouts {
+		if timeout < 0 {
+			return fmt.Errorf("type_timeouts.%s 不能为负数", taskType)
+		}
+	}
+	if c.HeartbeatTimeout < 0 {
+		return fmt.Errorf("heartbeat_timeout 不能为负数")
+	}
+	if c.StallAction != "fail" && c.StallAction != "cancel" {
+		return fmt.Errorf("stall_action 必须是 fail 或 cancel")
+	}
+	for taskType, policy := range c.RetryPolicies {
+		if policy == nil {
+			continue
+		}
+		if err := policy.Validate(); err != nil {
+			return fmt.Errorf("retry_policies.%s: %v", taskType, err)
+		}
+	}
+	return nil
+}
+
+// taskRun 运行中任务的执行信息
+type taskRun struct {
+	cancel      context.CancelFunc
+	startedAt   time.Time
+	deadline    time.Time    // 执行截止时间，零值表示不限时
+	heartbeat   time.Time    // 执行器最近一次上报的时间
+	persistedAt time.Time    // 最近一次写入数据库的心跳时间
+	aborted     bool         // 已被看门狗终止，执行器返回后不再更新任务状态
+	attempt     int          // 第几次尝试
+	retry       *RetryPolicy // 本次执行使用的重试策略
+}
+
+// loadRuntimeConfig 读取任务运行配置，未配置或配置无效时使用默认值
+func (s *taskServiceImpl) loadRuntimeConfig() *TaskRuntimeConfig {
+	config := DefaultTaskRuntimeConfig()
+
+	data, err := s.storage.GetConfig(TaskRuntimeConfigModule)
+	if err != nil || data == "" {
+		return config
+	}
+	if err := json.Unmarshal([]byte(data), config); err != nil {
+		logger.Warnf("解析任务运行配置失败，使用默认配置: %v", err)
+		return DefaultTaskRuntimeConfig()
+	}
+	if err := config.Validate(); err != nil {
+		logger.Warnf("任务运行配置无效，使用默认配置: %v", err)
+		return DefaultTaskRuntimeConfig()
+	}
+	return config
+}
+
+// taskTimeout 计算任务的执行超时：任务配置 > 类型配置 > 默认配置，0 表示不限时
+func (s *taskServiceImpl) taskTimeout(task *repository.Task) time.Duration {
+	var config map[string]interface{}
+	if err := json.Unmarshal([]byte(task.Config), &config); err == nil {
+		if seconds, ok := config[TaskTimeoutConfigKey].(float64); ok && seconds > 0 {
+			return time.Duration(seconds * float64(time.Second))
+		}
+	}
+
+	runtimeConfig := s.loadRuntimeConfig()
+	if seconds, exists := runtimeConfig.TypeTimeouts[task.Type]; exists {
+		return time.Duration(seconds) * time.Second
+	}
+	return time.Duration(runtimeConfig.DefaultTimeout) * time.Second
+}
+
+// touchHeartbeat 记录任务心跳（执行器每次上报进度或日志时调用）
+func (s *taskServiceImpl) touchHeartbeat(taskID string, run *taskRun) {
+	now := time.Now()
+
+	s.taskMutex.Lock()
+	run.heartbeat = now
+	persist := now.Sub(run.persistedAt) >= heartbeatPersistInterval
+	if persist {
+		run.persistedAt = now
+	}
+	s.taskMutex.Unlock()
+
+	if persist {
+		if err := s.storage.UpdateTaskHeartbeat(taskID, now); err != nil {
+			logger.Warnf("更新任务心跳失败 %s: %v", taskID, err)
+		}
+	}
+}
+
+// removeRun 从运行任务列表中移除（仅当仍是同一次执行时，避免误删重新启动后的执行）
+func (s *taskServiceImpl) removeRun(taskID string, run *taskRun) {
+	s.taskMutex.Lock()
+	if current, exists := s.runningTasks[taskID]; exists && current == run {
+		delete(s.runningTasks, taskID)
+	}
+	s.taskMutex.Unlock()
+}
+
+// monitorRunningTasks 看门狗：启动时和之后定期检查到期的自动重试，以及超时和卡死的任务
+func (s *taskServiceImpl) monitorRunningTasks() {
+	s.requeueDueRetries(time.Now())
+
+	ticker := time.NewTicker(s.watchdogInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		s.checkStalledTasks(now)
+		s.requeueDueRetries(now)
+	}
+}
+
+// stalledTask 需要被看门狗终止的任务
+type stalledTask struct {
+	id     string
+	run    *taskRun
+	status string
+	reason string
+}
+
+// checkStalledTasks 终止超时后仍未退出或长时间没有心跳的任务，释放其所在类型的队列
+func (s *taskServiceImpl) checkStalledTasks(now time.Time) {
+	runtimeConfig := s.loadRuntimeConfig()
+	heartbeatTimeout := time.Duration(runtimeConfig.HeartbeatTimeout) * time.Second
+
+	var stalled []stalledTask
+	s.taskMutex.Lock()
+	for id, run := range s.runningTasks {
+		if run.aborted {
+			continue
+		}
+
+		switch {
+		case !run.deadline.IsZero() && now.After(run.deadline.Add(timeoutGracePeriod)):
+			stalled = append(stalled, stalledTask{id, run, "failed",
+				fmt.Sprintf("任务执行超时（限时 %v），执行器未响应取消，已强制终止", run.deadline.Sub(run.startedAt))})
+		case heartbeatTimeout > 0 && now.Sub(run.heartbeat) > heartbeatTimeout:
+			status := "failed"
+			if runtimeConfig.StallAction == "cancel" {
+				status = "cancelled"
+			}
+			stalled = append(stalled, stalledTask{id, run, status,
+				fmt.Sprintf("任务超过 %v 没有心跳，已被看门狗终止", heartbeatTimeout)})
+		default:
+			continue
+		}
+
+		run.aborted = true
+		delete(s.runningTasks, id)
+	}
+	s.taskMutex.Unlock()
+
+	for _, item := range stalled {
+		logger.Warnf("看门狗终止任务 %s: %s", item.id, item.reason)
+		item.run.cancel()
+
+		task, err := s.GetTask(item.id)
+		if err != nil {
+			continue
+		}
+		if item.status == "failed" {
+			// 超时和卡死按 timeout 分类，可按重试策略自动重试
+			s.failTask(task, item.run, item.reason, pkgerrors.ClassTimeout)
+			continue
+		}
+		s.recordAttempt(task.ID, item.run, "cancelled", item.reason, pkgerrors.ClassTimeout)
+		s.UpdateTaskError(task.ID, item.reason)
+		s.UpdateTaskStatus(task.ID, "cancelled")
+		s.sendLog(task.ID, "error", item.reason)
+	}
+
+	if len(stalled) > 0 {
+		go s.processNextTask()
+	}
+}
@@ -0,0 +1,114 @@
+package tagger
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// TaggerCapabilities 标签后端的能力，决定核心流程对识别结果的处理方式
+type TaggerCapabilities struct {
+	Confidences bool `json:"confidences"` // 返回每个标签的置信度（按阈值过滤、按置信度排序）
+	Ratings     bool `json:"ratings"`     // 返回评级（general、sensitive 等）
+	Models      bool `json:"models"`      // 支持选择模型
+	Caption     bool `json:"caption"`     // 返回自然语言描述而不是标签列表
+}
+
+// InterrogateOptions 单张图片的识别参数
+type InterrogateOptions struct {
+	Model     string  // 模型名称，后端不支持选择模型时忽略
+	Threshold float64 // 请求阈值，后端支持时在服务端过滤，核心流程再按类别在本地过滤
+}
+
+// Tagger 标签后端接口：识别单张图片，返回原始标签数据（阈值过滤、标签规则和保存由核心流程处理）
+type Tagger interface {
+	// Name 后端名称（即请求中的 analyzer）
+	Name() string
+
+	// Capabilities 后端能力
+	Capabilities() TaggerCapabilities
+
+	// Interrogate 识别单张图片，imageData 为 Base64 编码的图片
+	Interrogate(ctx context.Context, imageData string, options InterrogateOptions) (*InterrogateResult, error)
+}
+
+// TaggerBackendConfig 创建标签后端时使用的连接配置
+type TaggerBackendConfig struct {
+	BaseURL    string       // SD WebUI 地址
+	ServiceURL string       // 独立标签服务地址
+	CaptionURL string       // 描述服务接口地址
+	Client     *http.Client // 共享的 HTTP 客户端（包含超时设置）
+}
+
+// TaggerFactory 标签后端构造函数
+type TaggerFactory func(cfg TaggerBackendConfig) Tagger
+
+// TaggerInfo 已注册的标签后端信息
+type TaggerInfo struct {
+	Name         string             `json:"name"`
+	Description  string             `json:"description"`
+	Capabilities TaggerCapabilities `json:"capabilities"`
+}
+
+type taggerRegistration struct {
+	info    TaggerInfo
+	factory TaggerFactory
+}
+
+var (
+	taggerRegistryMu sync.RWMutex
+	taggerRegistry   = make(map[string]taggerRegistration)
+)
+
+// RegisterTagger 注册标签后端，同名后端会被覆盖
+func RegisterTagger(name, description string, factory TaggerFactory) {
+	capabilities := factory(TaggerBackendConfig{Client: http.DefaultClient}).Capabilities()
+
+	taggerRegistryMu.Lock()
+	defer taggerRegistryMu.Unlock()
+	taggerRegistry[name] = taggerRegistration{
+		info:    TaggerInfo{Name: name, Description: description, Capabilities: capabilities},
+		factory: factory,
+	}
+}
+
+// NewTaggerBackend 按名称创建标签后端
+func NewTaggerBackend(name string, cfg TaggerBackendConfig) (Tagger, error) {
+	taggerRegistryMu.RLock()
+	registration, ok := taggerRegistry[name]
+	taggerRegistryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("未知的标签后端: %s", name)
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	return registration.factory(cfg), nil
+}
+
+// AvailableTaggers 返回已注册的标签后端（按名称排序）
+func AvailableTaggers() []TaggerInfo {
+	taggerRegistryMu.RLock()
+	defer taggerRegistryMu.RUnlock()
+
+	infos := make([]TaggerInfo, 0, len(taggerRegistry))
+	for _, registration := range taggerRegistry {
+		infos = append(infos, registration.info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+// TaggerNames 返回已注册的标签后端名称（按名称排序）
+func TaggerNames() []string {
+	infos := AvailableTaggers()
+	names := make([]string, 0, len(infos))
+	for _, info := range infos {
+		names = append(names, info.Name)
+	}
+	return names
+}
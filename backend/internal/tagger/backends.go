@@ -0,0 +1,288 @@
+package tagger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"pixiv-tailor/backend/internal/logger"
+)
+
+// 内置标签后端名称
+const (
+	BackendWD14Tagger     = "wd14tagger"      // SD WebUI 标签扩展（stable-diffusion-webui-wd14-tagger）
+	BackendDeepBooru      = "deepbooru"       // SD WebUI 内置 interrogate（DeepBooru 模型）
+	BackendCLIP           = "clip"            // SD WebUI 内置 interrogate（CLIP 模型，返回自然语言描述）
+	BackendTaggerService  = "tagger_service"  // 独立部署的标签 HTTP 服务
+	BackendCaptionService = "caption_service" // 独立部署的描述 HTTP 服务（返回自然语言描述）
+)
+
+func init() {
+	RegisterTagger(BackendWD14Tagger, "SD WebUI 标签扩展（WD14 系列模型）", func(cfg TaggerBackendConfig) Tagger {
+		return &webUITaggerBackend{baseURL: cfg.BaseURL, client: cfg.Client}
+	})
+	RegisterTagger(BackendDeepBooru, "SD WebUI 内置 DeepBooru 反推", func(cfg TaggerBackendConfig) Tagger {
+		return &webUIInterrogateBackend{name: BackendDeepBooru, model: "deepbooru", baseURL: cfg.BaseURL, client: cfg.Client}
+	})
+	RegisterTagger(BackendCLIP, "SD WebUI 内置 CLIP 反推（自然语言描述）", func(cfg TaggerBackendConfig) Tagger {
+		return &webUIInterrogateBackend{name: BackendCLIP, model: "clip", baseURL: cfg.BaseURL, client: cfg.Client}
+	})
+	RegisterTagger(BackendTaggerService, "独立标签服务（POST {service_url}/interrogate）", func(cfg TaggerBackendConfig) Tagger {
+		return &taggerServiceBackend{serviceURL: cfg.ServiceURL, client: cfg.Client}
+	})
+	RegisterTagger(BackendCaptionService, "独立描述服务（POST {caption_url}）", func(cfg TaggerBackendConfig) Tagger {
+		return &captionServiceBackend{captionURL: cfg.CaptionURL, client: cfg.Client}
+	})
+}
+
+// webUITaggerBackend SD WebUI 标签扩展：POST /tagger/v1/interrogate
+type webUITaggerBackend struct {
+	baseURL string
+	client  *http.Client
+}
+
+func (b *webUITaggerBackend) Name() string { return BackendWD14Tagger }
+
+func (b *webUITaggerBackend) Capabilities() TaggerCapabilities {
+	return TaggerCapabilities{Confidences: true, Ratings: true, Models: true}
+}
+
+func (b *webUITaggerBackend) Interrogate(ctx context.Context, imageData string, options InterrogateOptions) (*InterrogateResult, error) {
+	url := fmt.Sprintf("%s/tagger/v1/interrogate", strings.TrimRight(b.baseURL, "/"))
+	reqBody := InterrogateRequest{
+		Image:      imageData,
+		Model:      options.Model, // 默认 wd14-convnext-v2，支持 CPU
+		Threshold:  options.Threshold,
+		BatchCount: 1,
+	}
+	logger.Infof("发送 WD14 Tagger 请求: model=%s, threshold=%.2f", options.Model, options.Threshold)
+
+	response, err := postInterrogate(ctx, b.client, url, reqBody, "WD14 Tagger")
+	if err != nil {
+		return nil, err
+	}
+	caption, exists := response["caption"]
+	if !exists {
+		return nil, fmt.Errorf("响应中没有 caption 字段（分析器: %s）: %+v", b.Name(), response)
+	}
+	return parseCaption(caption)
+}
+
+// webUIInterrogateBackend SD WebUI 内置反推：POST /sdapi/v1/interrogate，model 为 deepbooru 或 clip
+type webUIInterrogateBackend struct {
+	name    string
+	model   string
+	baseURL string
+	client  *http.Client
+}
+
+func (b *webUIInterrogateBackend) Name() string { return b.name }
+
+func (b *webUIInterrogateBackend) Capabilities() TaggerCapabilities {
+	return TaggerCapabilities{Caption: b.model == "clip"}
+}
+
+func (b *webUIInterrogateBackend) Interrogate(ctx context.Context, imageData string, options InterrogateOptions) (*InterrogateResult, error) {
+	url := fmt.Sprintf("%s/sdapi/v1/interrogate", strings.TrimRight(b.baseURL, "/"))
+	reqBody := map[string]interface{}{
+		"image": imageData,
+		"model": b.model,
+	}
+	logger.Infof("发送 WebUI interrogate 请求: model=%s", b.model)
+
+	response, err := postInterrogate(ctx, b.client, url, reqBody, "WebUI interrogate")
+	if err != nil {
+		return nil, err
+	}
+	// 不同版本可能返回 "caption" 或 "result"
+	caption, exists := response["caption"]
+	if !exists {
+		caption, exists = response["result"]
+	}
+	if !exists {
+		return nil, fmt.Errorf("响应中没有 caption 字段（分析器: %s）: %+v", b.name, response)
+	}
+	return parseCaption(caption)
+}
+
+// taggerServiceBackend 独立标签服务：POST {service_url}/interrogate，
+// 请求 {"image", "model", "threshold"}，响应 {"tags": {标签: 置信度}, "ratings": {评级: 置信度}}
+type taggerServiceBackend struct {
+	serviceURL string
+	client     *http.Client
+}
+
+func (b *taggerServiceBackend) Name() string { return BackendTaggerService }
+
+func (b *taggerServiceBackend) Capabilities() TaggerCapabilities {
+	return TaggerCapabilities{Confidences: true, Ratings: true, Models: true}
+}
+
+func (b *taggerServiceBackend) Interrogate(ctx context.Context, imageData string, options InterrogateOptions) (*InterrogateResult, error) {
+	if b.serviceURL == "" {
+		return nil, fmt.Errorf("未配置独立标签服务地址（wd14tagger.service_url）")
+	}
+	url := fmt.Sprintf("%s/interrogate", strings.TrimRight(b.serviceURL, "/"))
+	reqBody := map[string]interface{}{
+		"image":     imageData,
+		"model":     options.Model,
+		"threshold": options.Threshold,
+	}
+
+	response, err := postInterrogate(ctx, b.client, url, reqBody, "标签服务")
+	if err != nil {
+		return nil, err
+	}
+	tags, ok := response["tags"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("响应中没有 tags 字段（分析器: %s）: %+v", b.Name(), response)
+	}
+	result := &InterrogateResult{TagsData: tags, RatingData: make(map[string]interface{})}
+	if ratings, ok := response["ratings"].(map[string]interface{}); ok {
+		result.RatingData = ratings
+	}
+	result.TagsString = strings.Join(tagsByConfidence(tags), ", ")
+	return result, nil
+}
+
+// captionServiceBackend 独立描述服务：POST {caption_url}，请求 {"image", "model"}，响应 {"caption": 描述}
+type captionServiceBackend struct {
+	captionURL string
+	client     *http.Client
+}
+
+func (b *captionServiceBackend) Name() string { return BackendCaptionService }
+
+func (b *captionServiceBackend) Capabilities() TaggerCapabilities {
+	return TaggerCapabilities{Models: true, Caption: true}
+}
+
+func (b *captionServiceBackend) Interrogate(ctx context.Context, imageData string, options InterrogateOptions) (*InterrogateResult, error) {
+	if b.captionURL == "" {
+		return nil, fmt.Errorf("未配置描述服务地址（wd14tagger.caption_url）")
+	}
+	reqBody := map[string]interface{}{
+		"image": imageData,
+		"model": options.Model,
+	}
+
+	response, err := postInterrogate(ctx, b.client, b.captionURL, reqBody, "描述服务")
+	if err != nil {
+		return nil, err
+	}
+	caption, ok := response["caption"].(string)
+	if !ok {
+		return nil, fmt.Errorf("响应中没有 caption 字段（分析器: %s）: %+v", b.Name(), response)
+	}
+	return parseCaption(caption)
+}
+
+// postInterrogate 发送识别请求并解析 JSON 响应（ctx 取消时立即中断请求）
+func postInterrogate(ctx context.Context, client *http.Client, url string, body interface{}, apiName string) (map[string]interface{}, error) {
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("序列化请求失败: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("发送请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %v", err)
+	}
+
+	// 记录实际响应内容用于调试
+	logger.Infof("%s API 响应: status=%d, body长度=%d", apiName, resp.StatusCode, len(respBody))
+	if len(respBody) < 500 {
+		logger.Infof("完整响应: %s", string(respBody))
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		// 检查是否是 CUDA/ONNX Runtime 错误
+		if strings.Contains(string(respBody), "ONNX Runtime") || strings.Contains(string(respBody), "CUDA") || strings.Contains(string(respBody), "cuDNN") {
+			return nil, fmt.Errorf("GPU/CUDA 配置错误: %s\n\n"+
+				"解决方案：\n"+
+				"1. 确认已安装 CUDA 12.x 和匹配的 cuDNN 版本\n"+
+				"2. 或在 WebUI 中配置使用 CPU 模式（修改 WebUI 启动参数或扩展配置）\n"+
+				"3. 检查 WebUI 日志了解详细错误信息", string(respBody))
+		}
+		return nil, fmt.Errorf("API 调用失败: status=%d, body=%s", resp.StatusCode, string(respBody))
+	}
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %v", err)
+	}
+	return response, nil
+}
+
+// parseCaption 解析 caption 字段（可能是字符串、对象或数组），不做阈值过滤
+func parseCaption(caption interface{}) (*InterrogateResult, error) {
+	result := &InterrogateResult{
+		TagsData:   make(map[string]interface{}),
+		RatingData: make(map[string]interface{}),
+	}
+
+	switch v := caption.(type) {
+	case string:
+		result.TagsString = v
+	case map[string]interface{}:
+		// 标签名->置信度映射，评级和标签混在一起
+		for key, value := range v {
+			if key == "general" || key == "sensitive" || key == "questionable" || key == "explicit" {
+				result.RatingData[key] = value
+			} else if key != "rating" {
+				result.TagsData[key] = value
+			}
+		}
+		result.TagsString = strings.Join(tagsByConfidence(result.TagsData), ", ")
+		logger.Infof("提取到 %d 个标签，Rating 数据: %+v", len(result.TagsData), result.RatingData)
+	case []interface{}:
+		tagStrings := make([]string, 0, len(v))
+		for _, tag := range v {
+			if tagStr, ok := tag.(string); ok && tagStr != "" {
+				tagStrings = append(tagStrings, tagStr)
+			}
+		}
+		result.TagsString = strings.Join(tagStrings, ", ")
+	default:
+		return nil, fmt.Errorf("未知的 caption 类型: %T, 值: %v", v, v)
+	}
+
+	if result.TagsString == "" {
+		return nil, fmt.Errorf("无法提取标签，caption: %+v", caption)
+	}
+	return result, nil
+}
+
+// tagsByConfidence 按置信度降序（相同时按名称）返回标签名，没有置信度的标签排在最后
+func tagsByConfidence(tags map[string]interface{}) []string {
+	names := make([]string, 0, len(tags))
+	for name := range tags {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		ci, _ := tags[names[i]].(float64)
+		cj, _ := tags[names[j]].(float64)
+		if ci != cj {
+			return ci > cj
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
@@ -0,0 +1,89 @@
+package tagger
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"pixiv-tailor/backend/internal/logger"
+)
+
+// DefaultCaptionTemplate 描述与标签的默认组合方式
+const DefaultCaptionTemplate = "{caption}, {tags}"
+
+// CaptionFileExt 单独保存描述时的文件扩展名（kohya/sd-scripts 的 caption_extension）
+const CaptionFileExt = ".caption"
+
+// RenderCaptionTemplate 按模板组合描述和标签：{caption} 替换为自然语言描述，{tags} 替换为标签字符串，
+// 去掉因某一部分为空而残留在首尾的逗号和空白
+func RenderCaptionTemplate(template, caption, tags string) string {
+	if template == "" {
+		template = DefaultCaptionTemplate
+	}
+	text := strings.NewReplacer("{caption}", caption, "{tags}", tags).Replace(template)
+	return strings.Trim(text, ", \t\r\n")
+}
+
+// CaptionBackendNames 返回支持自然语言描述的标签后端名称（按名称排序）
+func CaptionBackendNames() []string {
+	var names []string
+	for _, info := range AvailableTaggers() {
+		if info.Capabilities.Caption {
+			names = append(names, info.Name)
+		}
+	}
+	return names
+}
+
+// newCaptioner 创建描述后端（未指定时使用 clip），后端必须支持自然语言描述
+func (t *WD14Tagger) newCaptioner(name string) (Tagger, error) {
+	if name == "" {
+		name = BackendCLIP
+	}
+	captioner, err := NewTaggerBackend(name, TaggerBackendConfig{BaseURL: t.baseURL, ServiceURL: t.serviceURL, CaptionURL: t.captionURL, Client: t.client})
+	if err != nil {
+		return nil, err
+	}
+	if !captioner.Capabilities().Caption {
+		return nil, fmt.Errorf("标签后端 %s 不支持自然语言描述", name)
+	}
+	return captioner, nil
+}
+
+// captionBackendName 当前使用的描述后端名称，未开启描述模式时为空
+func (t *WD14Tagger) captionBackendName() string {
+	if t.captioner == nil {
+		return ""
+	}
+	return t.captioner.Name()
+}
+
+// captionImage 调用描述后端生成单张图片的自然语言描述
+func (t *WD14Tagger) captionImage(ctx context.Context, imageData string) (string, error) {
+	// 标签模型（t.model）不能用于描述后端，使用单独配置的描述模型
+	result, err := t.captioner.Interrogate(ctx, imageData, InterrogateOptions{Model: t.captionModel})
+	if err != nil {
+		return "", err
+	}
+	caption := strings.TrimSpace(result.TagsString)
+	if caption == "" {
+		return "", fmt.Errorf("描述为空（分析器: %s）", t.captioner.Name())
+	}
+	logger.Infof("生成描述: %s", caption)
+	return caption, nil
+}
+
+// saveCaption 按模板组合描述和标签，保存为与标签文件同名的 .caption 文件
+func (t *WD14Tagger) saveCaption(result *InterrogateResult, target tagTarget) (string, error) {
+	if err := os.MkdirAll(target.dir, 0755); err != nil {
+		return "", fmt.Errorf("创建输出目录失败: %v", err)
+	}
+	outputPath := target.path(CaptionFileExt)
+	text := RenderCaptionTemplate(t.captionTemplate, result.Caption, result.TagsString)
+	if err := os.WriteFile(outputPath, []byte(text), 0644); err != nil {
+		return "", fmt.Errorf("写入描述文件失败: %v", err)
+	}
+	logger.Infof("✓ 成功保存描述文件: %s", outputPath)
+	return outputPath, nil
+}
@@ -0,0 +1,161 @@
+package tagger
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// 标签保存格式
+const (
+	SaveTypeTXT   = "txt"   // 每张图片一个逗号分隔的标签文件
+	SaveTypeJSON  = "json"  // 每张图片一个包含置信度和生成参数的 JSON 文件
+	SaveTypeCSV   = "csv"   // 每个任务一个 CSV 文件，每行为 图片,标签,置信度
+	SaveTypeJSONL = "jsonl" // 每个任务一个 JSONL 文件，每行为一张图片的结果
+)
+
+// DefaultExportName 汇总格式（CSV、JSONL）的默认文件名（不含扩展名）
+const DefaultExportName = "tags"
+
+// IsAggregateSaveType 判断保存格式是否将所有图片的结果写入同一个文件
+func IsAggregateSaveType(saveType string) bool {
+	return saveType == SaveTypeCSV || saveType == SaveTypeJSONL
+}
+
+// kaomojiTags 包含下划线的颜文字标签，替换下划线时保持原样
+var kaomojiTags = map[string]bool{
+	"0_0": true, "(o)_(o)": true, "+_+": true, "+_-": true, "._.": true, "<o>_<o>": true, "<|>_<|>": true,
+	"=_=": true, ">_<": true, "3_3": true, "6_9": true, ">_o": true, "@_@": true, "^_^": true,
+	"o_o": true, "u_u": true, "x_x": true, "|_|": true, "||_||": true,
+}
+
+// FormatTag 将标签转换为可直接用于提示词的形式：下划线替换为空格（颜文字除外），括号转义为 \( \)
+func FormatTag(tag string, replaceUnderscore, escapeParentheses bool) string {
+	if replaceUnderscore && !kaomojiTags[tag] {
+		tag = strings.ReplaceAll(tag, "_", " ")
+	}
+	if escapeParentheses {
+		tag = strings.NewReplacer("(", `\(`, ")", `\)`).Replace(tag)
+	}
+	return tag
+}
+
+// tagExportLine JSONL 文件中一张图片的结果
+type tagExportLine struct {
+	Image      string                 `json:"image"`
+	TagsString string                 `json:"tags_string"`
+	Caption    string                 `json:"caption,omitempty"` // 描述模式生成的自然语言描述
+	Tags       []tagExportTag         `json:"tags"`
+	Ratings    map[string]interface{} `json:"ratings,omitempty"`
+	Analyzer   string                 `json:"analyzer"`
+	Model      string                 `json:"model"`
+	Thresholds TagThresholds          `json:"thresholds"`
+}
+
+// tagExportTag JSONL 文件中的单个标签（扩展标签没有置信度）
+type tagExportTag struct {
+	Name       string   `json:"name"`
+	Confidence *float64 `json:"confidence,omitempty"`
+}
+
+// tagExporter 将所有图片的结果写入同一个 CSV 或 JSONL 文件
+type tagExporter struct {
+	saveType string
+	path     string
+	file     *os.File
+	csv      *csv.Writer
+	written  int
+}
+
+// openTagExporter 创建汇总文件；resume 为 true 时（断点续传）追加到已有文件
+func openTagExporter(outputDir, name, saveType string, resume bool) (*tagExporter, error) {
+	if name == "" {
+		name = DefaultExportName
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, fmt.Errorf("创建输出目录失败: %v", err)
+	}
+
+	path := filepath.Join(outputDir, name+"."+saveType)
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	writeHeader := true
+	if resume {
+		if info, err := os.Stat(path); err == nil && info.Size() > 0 {
+			flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+			writeHeader = false
+		}
+	}
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("创建导出文件失败: %v", err)
+	}
+
+	exporter := &tagExporter{saveType: saveType, path: path, file: file}
+	if saveType == SaveTypeCSV {
+		exporter.csv = csv.NewWriter(file)
+		if writeHeader {
+			exporter.csv.Write([]string{"image", "tag", "confidence"})
+		}
+	}
+	return exporter, nil
+}
+
+// write 写入一张图片的结果
+func (e *tagExporter) write(t *WD14Tagger, imagePath string, result *InterrogateResult) error {
+	switch e.saveType {
+	case SaveTypeCSV:
+		for _, tag := range result.FinalTags {
+			confidence := ""
+			if tag.Confidence > 0 {
+				confidence = strconv.FormatFloat(tag.Confidence, 'f', 4, 64)
+			}
+			if err := e.csv.Write([]string{imagePath, tag.Name, confidence}); err != nil {
+				return fmt.Errorf("写入 CSV 失败: %v", err)
+			}
+		}
+		e.csv.Flush()
+		if err := e.csv.Error(); err != nil {
+			return fmt.Errorf("写入 CSV 失败: %v", err)
+		}
+	case SaveTypeJSONL:
+		line := tagExportLine{
+			Image:      imagePath,
+			TagsString: result.TagsString,
+			Caption:    result.Caption,
+			Tags:       make([]tagExportTag, 0, len(result.FinalTags)),
+			Ratings:    result.RatingData,
+			Analyzer:   t.analyzerName(),
+			Model:      t.model,
+			Thresholds: t.thresholds,
+		}
+		for _, tag := range result.FinalTags {
+			exportTag := tagExportTag{Name: tag.Name}
+			if tag.Confidence > 0 {
+				confidence := tag.Confidence
+				exportTag.Confidence = &confidence
+			}
+			line.Tags = append(line.Tags, exportTag)
+		}
+		data, err := json.Marshal(line)
+		if err != nil {
+			return fmt.Errorf("序列化 JSONL 失败: %v", err)
+		}
+		if _, err := e.file.Write(append(data, '\n')); err != nil {
+			return fmt.Errorf("写入 JSONL 失败: %v", err)
+		}
+	}
+	e.written++
+	return nil
+}
+
+// Close 关闭汇总文件
+func (e *tagExporter) Close() error {
+	if e.csv != nil {
+		e.csv.Flush()
+	}
+	return e.file.Close()
+}
@@ -0,0 +1,167 @@
+package tagger
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// TagManifestFile 输出目录中记录 TXT 标签文件生成参数的清单文件（JSON 输出在文件自身中记录）
+const TagManifestFile = ".tagmeta.json"
+
+// tagFileMeta 标签文件的生成参数，用于判断标签文件是否需要重新生成
+type tagFileMeta struct {
+	Analyzer   string        `json:"analyzer"`
+	Model      string        `json:"model"`
+	Thresholds TagThresholds `json:"thresholds"`
+	// 描述模式的生成参数（未开启时为空）
+	CaptionBackend  string `json:"caption_backend"`
+	CaptionModel    string `json:"caption_model"`
+	CaptionTemplate string `json:"caption_template"`
+}
+
+// analyzerName 当前使用的标签后端名称（未指定时为 wd14tagger）
+func (t *WD14Tagger) analyzerName() string {
+	if t.analyzer == "" {
+		return BackendWD14Tagger
+	}
+	return t.analyzer
+}
+
+// fileMeta 本次生成使用的参数
+func (t *WD14Tagger) fileMeta() *tagFileMeta {
+	meta := &tagFileMeta{
+		Analyzer:       t.analyzerName(),
+		Model:          t.model,
+		Thresholds:     t.thresholds,
+		CaptionBackend: t.captionBackendName(),
+	}
+	if t.captioner != nil {
+		meta.CaptionModel = t.captionModel
+		meta.CaptionTemplate = t.captionTemplate
+	}
+	return meta
+}
+
+// tagFileUpToDate 判断图片的标签文件是否已是最新：标签文件存在且不早于图片，
+// 并且记录的分析器、模型、阈值和描述参数与本次相同（JSON 输出以文件自身为准，TXT 输出以目录中的清单为准）
+func (t *WD14Tagger) tagFileUpToDate(imagePath string, target tagTarget, saveType string, manifests *tagManifests) bool {
+	imageInfo, err := os.Stat(imagePath)
+	if err != nil {
+		return false
+	}
+
+	tagPath := target.path(".txt")
+	if saveType == "json" {
+		tagPath = target.path(".json")
+	}
+	tagInfo, err := os.Stat(tagPath)
+	if err != nil || tagInfo.ModTime().Before(imageInfo.ModTime()) {
+		return false
+	}
+
+	if t.captionFile {
+		if _, err := os.Stat(target.path(CaptionFileExt)); err != nil {
+			return false
+		}
+	}
+
+	var meta *tagFileMeta
+	if saveType == "json" {
+		data, err := os.ReadFile(tagPath)
+		if err != nil {
+			return false
+		}
+		meta = &tagFileMeta{}
+		if err := json.Unmarshal(data, meta); err != nil {
+			return false
+		}
+	} else if meta = manifests.lookup(target); meta == nil {
+		return false
+	}
+	return t.sameMeta(meta)
+}
+
+// sameMeta 判断记录的生成参数是否与本次相同
+func (t *WD14Tagger) sameMeta(meta *tagFileMeta) bool {
+	current := t.fileMeta()
+	return meta.Analyzer == current.Analyzer && meta.Model == current.Model && sameThresholds(meta.Thresholds, current.Thresholds) &&
+		meta.CaptionBackend == current.CaptionBackend && meta.CaptionModel == current.CaptionModel && meta.CaptionTemplate == current.CaptionTemplate
+}
+
+// sameThresholds 比较两组阈值（未设置按类别阈值与空集合视为相同）
+func sameThresholds(a, b TagThresholds) bool {
+	if a.General != b.General || a.Character != b.Character || len(a.Categories) != len(b.Categories) {
+		return false
+	}
+	for category, threshold := range a.Categories {
+		if other, ok := b.Categories[category]; !ok || other != threshold {
+			return false
+		}
+	}
+	return true
+}
+
+// tagManifests 按输出目录缓存 TXT 标签文件的生成参数清单（文件名 -> 生成参数），
+// 首次访问目录时读取，保存标签文件时更新，生成结束后统一写回
+type tagManifests struct {
+	entries map[string]map[string]*tagFileMeta
+	dirty   map[string]bool
+}
+
+// newTagManifests 创建清单缓存
+func newTagManifests() *tagManifests {
+	return &tagManifests{
+		entries: make(map[string]map[string]*tagFileMeta),
+		dirty:   make(map[string]bool),
+	}
+}
+
+// load 读取目录的清单，文件不存在或无法解析时视为空清单
+func (m *tagManifests) load(dir string) map[string]*tagFileMeta {
+	if entries, ok := m.entries[dir]; ok {
+		return entries
+	}
+	entries := make(map[string]*tagFileMeta)
+	if data, err := os.ReadFile(filepath.Join(dir, TagManifestFile)); err == nil {
+		if err := json.Unmarshal(data, &entries); err != nil {
+			entries = make(map[string]*tagFileMeta)
+		}
+	}
+	m.entries[dir] = entries
+	return entries
+}
+
+// lookup 获取标签文件记录的生成参数，没有记录时返回 nil
+func (m *tagManifests) lookup(target tagTarget) *tagFileMeta {
+	return m.load(target.dir)[target.name]
+}
+
+// set 记录标签文件的生成参数
+func (m *tagManifests) set(target tagTarget, meta *tagFileMeta) {
+	m.load(target.dir)[target.name] = meta
+	m.dirty[target.dir] = true
+}
+
+// flush 写回有变化的清单
+func (m *tagManifests) flush() error {
+	dirs := make([]string, 0, len(m.dirty))
+	for dir := range m.dirty {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	for _, dir := range dirs {
+		data, err := json.MarshalIndent(m.entries[dir], "", "  ")
+		if err != nil {
+			return fmt.Errorf("序列化标签清单失败: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, TagManifestFile), data, 0644); err != nil {
+			return fmt.Errorf("写入标签清单失败: %v", err)
+		}
+		delete(m.dirty, dir)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package tagger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"pixiv-tailor/backend/pkg/models"
+)
+
+// 标签文件的输出方式
+const (
+	OutputModeFlat    = "flat"    // 全部写入输出目录（默认）
+	OutputModeMirror  = "mirror"  // 在输出目录中按输入目录结构创建子目录
+	OutputModeBeside  = "beside"  // 写在图片旁边
+	OutputModeDataset = "dataset" // 导出为 kohya/sd-scripts 数据集：输出目录/N_概念名/ 下的图片和标签文件
+)
+
+// DefaultDatasetRepeats 数据集导出时默认的重复次数（文件夹名中的 N）
+const DefaultDatasetRepeats = 10
+
+// 产出文件类型（用于输出回调）
+const (
+	OutputKindTag     = "tag"     // 标签文件
+	OutputKindImage   = "image"   // 数据集导出时复制的图片
+	OutputKindCaption = "caption" // 描述模式单独保存的 .caption 文件
+)
+
+// tagTarget 单张图片的标签文件位置：目录和不含扩展名的文件名
+type tagTarget struct {
+	dir  string
+	name string
+}
+
+// path 返回指定扩展名的文件路径
+func (target tagTarget) path(ext string) string {
+	return filepath.Join(target.dir, target.name+ext)
+}
+
+// tagTargetFor 按输出方式计算图片的标签文件位置；roots 为解析后的输入目录
+func tagTargetFor(imagePath string, roots []string, outputDir string, request *models.TagRequest) tagTarget {
+	root := imageRoot(imagePath, roots)
+	imageDir := filepath.Dir(imagePath)
+	name := strings.TrimSuffix(filepath.Base(imagePath), filepath.Ext(imagePath))
+
+	rel, err := filepath.Rel(root, imageDir)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		rel = "."
+	}
+
+	switch request.OutputMode {
+	case OutputModeBeside:
+		return tagTarget{dir: imageDir, name: name}
+	case OutputModeMirror:
+		// 以输入目录名作为第一级子目录，避免不同输入目录的同名图片互相覆盖
+		return tagTarget{dir: filepath.Join(outputDir, filepath.Base(root), rel), name: name}
+	case OutputModeDataset:
+		// sd-scripts 只读取概念文件夹的第一层，子目录中的图片以相对路径作为文件名前缀
+		if rel != "." {
+			name = strings.ReplaceAll(filepath.ToSlash(rel), "/", "_") + "_" + name
+		}
+		// 指定概念名时多个输入目录合并到同一个文件夹，以输入目录名作为前缀避免同名图片互相覆盖
+		if strings.TrimSpace(request.DatasetConcept) != "" && len(roots) > 1 {
+			name = filepath.Base(root) + "_" + name
+		}
+		return tagTarget{dir: filepath.Join(outputDir, datasetFolderName(root, request)), name: name}
+	default:
+		return tagTarget{dir: outputDir, name: name}
+	}
+}
+
+// datasetFolderName 数据集概念文件夹名：N_概念名，未指定重复次数（不经过任务配置 Schema 的请求）时使用 DefaultDatasetRepeats，
+// 未指定概念名时使用输入目录名
+func datasetFolderName(root string, request *models.TagRequest) string {
+	repeats := request.DatasetRepeats
+	if repeats <= 0 {
+		repeats = DefaultDatasetRepeats
+	}
+	concept := strings.TrimSpace(request.DatasetConcept)
+	if concept == "" {
+		concept = filepath.Base(root)
+	}
+	return fmt.Sprintf("%d_%s", repeats, concept)
+}
+
+// imageRoot 返回图片所属的输入目录（取最长的匹配目录），不属于任何输入目录时返回图片所在目录
+func imageRoot(imagePath string, roots []string) string {
+	best := ""
+	for _, root := range roots {
+		rel, err := filepath.Rel(root, imagePath)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		if len(root) > len(best) {
+			best = root
+		}
+	}
+	if best == "" {
+		return filepath.Dir(imagePath)
+	}
+	return best
+}
+
+// copyDatasetImage 将图片放入数据集目录（优先使用硬链接，失败时复制），返回目标路径
+func copyDatasetImage(imagePath string, target tagTarget) (string, error) {
+	destPath := target.path(strings.ToLower(filepath.Ext(imagePath)))
+	if err := os.MkdirAll(target.dir, 0755); err != nil {
+		return "", fmt.Errorf("创建数据集目录失败: %v", err)
+	}
+
+	if srcInfo, err := os.Stat(imagePath); err == nil {
+		if destInfo, err := os.Stat(destPath); err == nil && os.SameFile(srcInfo, destInfo) {
+			return destPath, nil
+		}
+	}
+	os.Remove(destPath)
+	if err := os.Link(imagePath, destPath); err == nil {
+		return destPath, nil
+	}
+
+	src, err := os.Open(imagePath)
+	if err != nil {
+		return "", fmt.Errorf("打开图片失败: %v", err)
+	}
+	defer src.Close()
+
+	dest, err := os.Create(destPath)
+	if err != nil {
+		return "", fmt.Errorf("创建数据集图片失败: %v", err)
+	}
+	if _, err := io.Copy(dest, src); err != nil {
+		dest.Close()
+		return "", fmt.Errorf("复制图片失败: %v", err)
+	}
+	if err := dest.Close(); err != nil {
+		return "", fmt.Errorf("复制图片失败: %v", err)
+	}
+	return destPath, nil
+}
@@ -0,0 +1,81 @@
+package tagger
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"pixiv-tailor/backend/pkg/models"
+)
+
+// interrogateOutcome 单张图片的识别结果
+type interrogateOutcome struct {
+	result   *InterrogateResult
+	err      error
+	skipped  bool // 断点续传或增量打标跳过的图片（不占用并发名额）
+	upToDate bool // 标签文件已是最新而跳过
+}
+
+// startInterrogation 在后台按并发数识别图片（读取、调用接口并应用标签规则），返回与 imageFiles 一一对应的结果通道
+// 调用方按图片顺序读取结果，保存标签文件和回调都在调用方进行，因此输出顺序与串行处理一致；
+// 每读取一个未跳过的结果调用一次 release 释放并发名额，ctx 取消后不再开始新的识别；upToDate 中的图片直接跳过
+func (t *WD14Tagger) startInterrogation(ctx context.Context, imageFiles []string, request *models.TagRequest, skipPatterns []*regexp.Regexp, concurrency int, upToDate map[string]bool) ([]chan *interrogateOutcome, func()) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	slots := make(chan struct{}, concurrency)
+	results := make([]chan *interrogateOutcome, len(imageFiles))
+	for i := range results {
+		results[i] = make(chan *interrogateOutcome, 1)
+	}
+
+	go func() {
+		for i, imagePath := range imageFiles {
+			if t.completedImages[imagePath] {
+				results[i] <- &interrogateOutcome{skipped: true}
+				continue
+			}
+			if upToDate[imagePath] {
+				results[i] <- &interrogateOutcome{skipped: true, upToDate: true}
+				continue
+			}
+
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			go func(i int, imagePath string) {
+				results[i] <- t.interrogateFile(ctx, imagePath, request, skipPatterns)
+			}(i, imagePath)
+		}
+	}()
+
+	return results, func() { <-slots }
+}
+
+// interrogateFile 读取单张图片、调用标签接口并应用标签规则；ctx 取消时中断进行中的请求
+func (t *WD14Tagger) interrogateFile(ctx context.Context, imagePath string, request *models.TagRequest, skipPatterns []*regexp.Regexp) *interrogateOutcome {
+	imageData, err := readImageAsBase64(imagePath)
+	if err != nil {
+		return &interrogateOutcome{err: fmt.Errorf("读取图片失败: %v", err)}
+	}
+
+	result, err := t.interrogateImage(ctx, imageData)
+	if err != nil {
+		return &interrogateOutcome{err: fmt.Errorf("生成标签失败: %v", err)}
+	}
+
+	// 跳过标签、排序并添加扩展标签
+	t.applyTagRules(result, request, skipPatterns)
+
+	if t.captioner != nil {
+		caption, err := t.captionImage(ctx, imageData)
+		if err != nil {
+			return &interrogateOutcome{err: fmt.Errorf("生成描述失败: %v", err)}
+		}
+		result.Caption = caption
+	}
+	return &interrogateOutcome{result: result}
+}
@@ -0,0 +1,171 @@
+package tagger
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"pixiv-tailor/backend/pkg/models"
+)
+
+// 标签排序方式
+const (
+	TagOrderScore     = "score"     // 按置信度降序
+	TagOrderAlphabet  = "alphabet"  // 按字母顺序
+	TagOrderCharacter = "character" // 按类别顺序（角色标签在前），同类别内按置信度降序
+)
+
+// 扩展标签的位置
+const (
+	ExtendPositionAppend  = "append"  // 追加到末尾
+	ExtendPositionPrepend = "prepend" // 插入到开头（如 LoRA 触发词）
+)
+
+// TagCategoryFunc 返回标签类别的排序位置（数值越小越靠前），用于按类别排序
+type TagCategoryFunc func(tag string) int
+
+// CompileTagPatterns 编译标签匹配规则（用于跳过标签和标签编辑）：
+// "/.../" 为正则表达式，包含 * 或 ? 的为通配符，其余为精确匹配；除正则外不区分大小写，空格与下划线视为相同
+func CompileTagPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+
+		var expr string
+		if len(pattern) > 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+			expr = pattern[1 : len(pattern)-1]
+		} else {
+			var builder strings.Builder
+			builder.WriteString("(?i)^")
+			for _, r := range NormalizeTag(pattern) {
+				switch r {
+				case '*':
+					builder.WriteString(".*")
+				case '?':
+					builder.WriteString(".")
+				default:
+					builder.WriteString(regexp.QuoteMeta(string(r)))
+				}
+			}
+			builder.WriteString("$")
+			expr = builder.String()
+		}
+
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("无效的标签规则 %q: %v", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// NormalizeTag 统一空格和下划线（并转为小写），用于匹配和去重
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), " ", "_"))
+}
+
+// MatchTagPatterns 判断标签是否匹配任一规则（正则匹配原始标签，其余规则匹配规范化后的标签）
+func MatchTagPatterns(tag string, patterns []*regexp.Regexp) bool {
+	normalized := NormalizeTag(tag)
+	for _, re := range patterns {
+		if re.MatchString(tag) || re.MatchString(normalized) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyTagRules 按请求跳过标签、排序并添加扩展标签，按需转换为提示词格式，更新结果中的标签字符串和最终标签列表
+func (t *WD14Tagger) applyTagRules(result *InterrogateResult, request *models.TagRequest, skipPatterns []*regexp.Regexp) {
+	// 标签字符串保持接口返回的顺序，置信度从原始数据中查找
+	var tags []TagConfidence
+	for _, name := range strings.Split(result.TagsString, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		tag := TagConfidence{Name: name}
+		if conf, ok := result.TagsData[name].(float64); ok {
+			tag.Confidence = conf
+		}
+		tags = append(tags, tag)
+	}
+
+	confidences := make(map[string]float64, len(tags))
+	for _, tag := range tags {
+		confidences[tag.Name] = tag.Confidence
+	}
+	names := ApplyTagRules(tags, request, skipPatterns, t.categoryFunc)
+	result.FinalTags = make([]TagConfidence, 0, len(names))
+	for i, name := range names {
+		result.FinalTags = append(result.FinalTags, TagConfidence{Name: FormatTag(name, request.ReplaceUnderscore, request.EscapeParentheses), Confidence: confidences[name]})
+		names[i] = result.FinalTags[i].Name
+	}
+	result.TagsString = strings.Join(names, ", ")
+}
+
+// ApplyTagRules 跳过匹配规则的标签，按 tag_order 排序，再按 extend_position 添加扩展标签，返回最终的标签列表
+func ApplyTagRules(input []TagConfidence, request *models.TagRequest, skipPatterns []*regexp.Regexp, categoryOf TagCategoryFunc) []string {
+	tags := make([]TagConfidence, 0, len(input))
+	for _, tag := range input {
+		if !MatchTagPatterns(tag.Name, skipPatterns) {
+			tags = append(tags, tag)
+		}
+	}
+
+	switch request.TagOrder {
+	case TagOrderScore:
+		sort.SliceStable(tags, func(i, j int) bool {
+			return tags[i].Confidence > tags[j].Confidence
+		})
+	case TagOrderAlphabet:
+		sort.SliceStable(tags, func(i, j int) bool {
+			return NormalizeTag(tags[i].Name) < NormalizeTag(tags[j].Name)
+		})
+	case TagOrderCharacter:
+		if categoryOf == nil {
+			categoryOf = func(string) int { return 0 }
+		}
+		sort.SliceStable(tags, func(i, j int) bool {
+			ci, cj := categoryOf(tags[i].Name), categoryOf(tags[j].Name)
+			if ci != cj {
+				return ci < cj
+			}
+			return tags[i].Confidence > tags[j].Confidence
+		})
+	}
+
+	names := make([]string, 0, len(tags)+len(request.ExtendTags))
+	seen := make(map[string]bool, len(tags)+len(request.ExtendTags))
+	var extend []string
+	for _, tag := range request.ExtendTags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[NormalizeTag(tag)] {
+			continue
+		}
+		seen[NormalizeTag(tag)] = true
+		extend = append(extend, tag)
+	}
+
+	if request.ExtendPosition == ExtendPositionPrepend {
+		names = append(names, extend...)
+	}
+	for _, tag := range tags {
+		// 扩展标签已存在时只保留扩展标签的位置
+		if seen[NormalizeTag(tag.Name)] {
+			continue
+		}
+		seen[NormalizeTag(tag.Name)] = true
+		names = append(names, tag.Name)
+	}
+	if request.ExtendPosition != ExtendPositionPrepend {
+		names = append(names, extend...)
+	}
+
+	return names
+}
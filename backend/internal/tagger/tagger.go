@@ -1,10 +1,10 @@
 package tagger
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -19,12 +19,38 @@ import (
 
 // WD14Tagger WD14 Tagger 服务（支持 WD14-Tagger 和 DeepBooru）
 type WD14Tagger struct {
-	baseURL   string
-	timeout   int
-	client    *http.Client
-	threshold float64
-	model     string // 模型名称
-	analyzer  string // 分析器类型：wd14tagger 或 deepbooru
+	baseURL     string
+	timeout     int
+	client      *http.Client
+	thresholds  TagThresholds
+	concurrency int    // 同时识别的图片数量
+	model       string // 模型名称
+	analyzer    string // 标签后端名称（见 RegisterTagger），如 wd14tagger、deepbooru
+	serviceURL  string // 独立标签服务地址
+	backend     Tagger // 本次生成使用的标签后端
+	// 描述模式：描述后端（未开启时为 nil）、描述服务地址和模型、组合模板及是否单独保存 .caption 文件
+	captioner       Tagger
+	captionURL      string
+	captionModel    string
+	captionTemplate string
+	captionFile     bool
+	// 断点续传：已完成的图片路径（将被跳过）及单张图片处理结果回调
+	completedImages map[string]bool
+	itemCallback    GenerateTagsItemCallback
+	outputCallback  GenerateTagsOutputCallback
+	categoryFunc    TagCategoryFunc // tag_order 为 character 时使用的标签类别
+	classifyFunc    TagClassifyFunc // 按类别设置阈值时使用的标签类别
+	stats           TagStats        // 最近一次生成的处理统计
+}
+
+// TagStats 标签生成的处理统计，任务停止时为已处理部分的统计
+type TagStats struct {
+	Total    int  `json:"total"`      // 待处理图片数
+	Tagged   int  `json:"tagged"`     // 成功打标数
+	Failed   int  `json:"failed"`     // 失败数
+	Skipped  int  `json:"skipped"`    // 断点续传跳过数
+	UpToDate int  `json:"up_to_date"` // 标签文件已是最新而跳过的数量
+	Stopped  bool `json:"stopped"`    // 是否因停止或取消而中断
 }
 
 // NewWD14Tagger 创建新的 WD14 Tagger 实例
@@ -41,15 +67,40 @@ func NewWD14TaggerWithModel(model string) (*WD14Tagger, error) {
 		model = "wd14-convnext-v2"
 	}
 
+	// 阈值使用配置，未配置时使用默认值
+	thresholds := TagThresholds{
+		General:    aiConfig.WD14Tagger.Threshold,
+		Character:  aiConfig.WD14Tagger.CharacterThreshold,
+		Categories: make(map[string]float64, len(aiConfig.WD14Tagger.CategoryThresholds)),
+	}
+	if thresholds.General <= 0 {
+		thresholds.General = DefaultThreshold
+	}
+	for category, threshold := range aiConfig.WD14Tagger.CategoryThresholds {
+		thresholds.Categories[category] = threshold
+	}
+	concurrency := aiConfig.WD14Tagger.BatchSize
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	analyzer := aiConfig.WD14Tagger.Backend
+	if analyzer == "" {
+		analyzer = BackendWD14Tagger // 默认使用 WD14-Tagger
+	}
+
 	return &WD14Tagger{
-		baseURL:  aiConfig.SDWebUI.URL,
-		timeout:  aiConfig.SDWebUI.Timeout,
-		analyzer: "wd14tagger", // 默认使用 WD14-Tagger
+		baseURL:      aiConfig.SDWebUI.URL,
+		timeout:      aiConfig.SDWebUI.Timeout,
+		analyzer:     analyzer,
+		serviceURL:   aiConfig.WD14Tagger.ServiceURL,
+		captionURL:   aiConfig.WD14Tagger.CaptionURL,
+		captionModel: aiConfig.WD14Tagger.CaptionModel,
 		client: &http.Client{
 			Timeout: time.Duration(aiConfig.SDWebUI.Timeout) * time.Second,
 		},
-		threshold: 0.35,
-		model:     model,
+		thresholds:  thresholds,
+		concurrency: concurrency,
+		model:       model,
 	}, nil
 }
 
@@ -86,6 +137,61 @@ type GenerateTagsCallback func(current int, total int)
 // GenerateTagsLogCallback 日志回调函数类型
 type GenerateTagsLogCallback func(level string, message string)
 
+// GenerateTagsItemCallback 单张图片处理结果回调函数类型（err 为 nil 表示处理成功）
+type GenerateTagsItemCallback func(imagePath string, err error)
+
+// GenerateTagsOutputCallback 产出文件写入后的回调函数类型，kind 为 OutputKindTag 或 OutputKindImage
+type GenerateTagsOutputCallback func(imagePath, outputPath, kind string)
+
+// SetCompletedImages 设置已完成的图片路径，处理时将跳过这些图片（用于断点续传）
+func (t *WD14Tagger) SetCompletedImages(imagePaths []string) {
+	t.completedImages = make(map[string]bool, len(imagePaths))
+	for _, imagePath := range imagePaths {
+		t.completedImages[imagePath] = true
+	}
+}
+
+// SetItemCallback 设置单张图片处理结果回调
+func (t *WD14Tagger) SetItemCallback(callback GenerateTagsItemCallback) {
+	t.itemCallback = callback
+}
+
+// SetOutputCallback 设置产出文件写入后的回调（用于记录任务产出文件）
+func (t *WD14Tagger) SetOutputCallback(callback GenerateTagsOutputCallback) {
+	t.outputCallback = callback
+}
+
+// SetTagCategoryFunc 设置标签类别函数（tag_order 为 character 时按类别排序）
+func (t *WD14Tagger) SetTagCategoryFunc(fn TagCategoryFunc) {
+	t.categoryFunc = fn
+}
+
+// SetBaseURL 设置标签接口地址（默认使用 SD WebUI 配置中的地址）
+func (t *WD14Tagger) SetBaseURL(baseURL string) {
+	t.baseURL = baseURL
+}
+
+// SetCaptionService 设置描述服务地址和模型（默认使用配置中的 wd14tagger.caption_url 和 caption_model）
+func (t *WD14Tagger) SetCaptionService(captionURL, captionModel string) {
+	t.captionURL = captionURL
+	t.captionModel = captionModel
+}
+
+// SetTagClassifyFunc 设置标签分类函数（按类别设置阈值时使用）
+func (t *WD14Tagger) SetTagClassifyFunc(fn TagClassifyFunc) {
+	t.classifyFunc = fn
+}
+
+// Stats 返回最近一次生成的处理统计
+func (t *WD14Tagger) Stats() TagStats {
+	return t.stats
+}
+
+// Thresholds 当前使用的置信度阈值
+func (t *WD14Tagger) Thresholds() TagThresholds {
+	return t.thresholds
+}
+
 // GenerateTags 为目录中的图片生成标签
 func (t *WD14Tagger) GenerateTags(request *models.TagRequest) error {
 	return t.GenerateTagsWithCallback(request, nil, nil)
@@ -93,6 +199,12 @@ func (t *WD14Tagger) GenerateTags(request *models.TagRequest) error {
 
 // GenerateTagsWithCallback 为目录中的图片生成标签（带进度回调和日志回调）
 func (t *WD14Tagger) GenerateTagsWithCallback(request *models.TagRequest, callback GenerateTagsCallback, logCallback GenerateTagsLogCallback) error {
+	return t.GenerateTagsWithContext(context.Background(), request, callback, logCallback)
+}
+
+// GenerateTagsWithContext 为目录中的图片生成标签，按并发数同时识别多张图片；ctx 取消后不再开始新的识别并返回 ctx 的错误
+func (t *WD14Tagger) GenerateTagsWithContext(ctx context.Context, request *models.TagRequest, callback GenerateTagsCallback, logCallback GenerateTagsLogCallback) error {
+	t.stats = TagStats{}
 	// 如果请求中指定了分析器，使用请求中的分析器
 	if request.Analyzer != "" {
 		t.analyzer = request.Analyzer
@@ -101,6 +213,36 @@ func (t *WD14Tagger) GenerateTagsWithCallback(request *models.TagRequest, callba
 			logCallback("info", fmt.Sprintf("使用分析器: %s", t.analyzer))
 		}
 	}
+	backend, err := NewTaggerBackend(t.analyzerName(), TaggerBackendConfig{BaseURL: t.baseURL, ServiceURL: t.serviceURL, Client: t.client})
+	if err != nil {
+		if logCallback != nil {
+			logCallback("error", err.Error())
+		}
+		return err
+	}
+	t.backend = backend
+
+	// 描述模式：另外调用描述后端生成自然语言描述
+	t.captioner = nil
+	if request.Caption {
+		captioner, err := t.newCaptioner(request.CaptionBackend)
+		if err != nil {
+			if logCallback != nil {
+				logCallback("error", err.Error())
+			}
+			return err
+		}
+		t.captioner = captioner
+		t.captionTemplate = request.CaptionTemplate
+		if t.captionTemplate == "" {
+			t.captionTemplate = DefaultCaptionTemplate
+		}
+		t.captionFile = request.CaptionFile
+		logger.Infof("描述模式: 后端=%s, 模板=%s, 单独保存=%v", captioner.Name(), t.captionTemplate, t.captionFile)
+		if logCallback != nil {
+			logCallback("info", fmt.Sprintf("描述模式: 后端=%s, 模板=%s", captioner.Name(), t.captionTemplate))
+		}
+	}
 
 	// 如果请求中指定了模型，使用请求中的模型
 	if request.Model != "" {
@@ -108,6 +250,31 @@ func (t *WD14Tagger) GenerateTagsWithCallback(request *models.TagRequest, callba
 		logger.Infof("使用指定模型: %s", t.model)
 	}
 
+	// 请求中指定的阈值覆盖配置
+	if request.Threshold > 0 {
+		t.thresholds.General = request.Threshold
+	}
+	if request.CharacterThreshold > 0 {
+		t.thresholds.Character = request.CharacterThreshold
+	}
+	for category, threshold := range request.CategoryThresholds {
+		if t.thresholds.Categories == nil {
+			t.thresholds.Categories = make(map[string]float64)
+		}
+		t.thresholds.Categories[category] = threshold
+	}
+	if logCallback != nil {
+		logCallback("info", fmt.Sprintf("置信度阈值: 通用=%.2f, 角色=%.2f, 按类别=%v", t.thresholds.General, t.thresholds.Character, t.thresholds.Categories))
+	}
+
+	skipPatterns, err := CompileTagPatterns(request.SkipTags)
+	if err != nil {
+		if logCallback != nil {
+			logCallback("error", err.Error())
+		}
+		return err
+	}
+
 	// 获取所有输入目录
 	inputDirs := request.GetInputDirs()
 	if len(inputDirs) == 0 {
@@ -129,6 +296,26 @@ func (t *WD14Tagger) GenerateTagsWithCallback(request *models.TagRequest, callba
 	// 收集所有目录的图片文件
 	var allImageFiles []string
 	var dirLimits map[string]int // 记录每个目录已使用的图片数量
+	var inputRoots []string      // 解析后的输入目录，用于按输入目录结构输出
+
+	// 如果指定了图片文件列表，直接使用，不再扫描输入目录
+	if len(request.ImageFiles) > 0 {
+		for _, inputDir := range inputDirs {
+			inputRoots = append(inputRoots, resolveInputDir(pathManager, inputDir))
+		}
+		for _, imagePath := range request.ImageFiles {
+			if _, err := os.Stat(imagePath); err != nil {
+				warnMsg := fmt.Sprintf("图片文件不存在，跳过: %s", imagePath)
+				logger.Warnf(warnMsg)
+				if logCallback != nil {
+					logCallback("warning", warnMsg)
+				}
+				continue
+			}
+			allImageFiles = append(allImageFiles, imagePath)
+		}
+		inputDirs = nil
+	}
 
 	for dirIndex, inputDir := range inputDirs {
 		logger.Infof("处理输入目录 %d/%d: %s", dirIndex+1, len(inputDirs), inputDir)
@@ -137,19 +324,7 @@ func (t *WD14Tagger) GenerateTagsWithCallback(request *models.TagRequest, callba
 		}
 
 		// 解析路径
-		// 输入目录应该是相对于 images/ 目录的，需要添加前缀
-		var resolvedDir string
-		if pathManager != nil {
-			// 如果已经有前缀，直接解析；否则添加 images/ 前缀
-			if strings.HasPrefix(inputDir, "images/") || strings.HasPrefix(inputDir, "tags/") || strings.HasPrefix(inputDir, "cache/") {
-				resolvedDir = pathManager.ResolvePath(inputDir)
-			} else {
-				// 默认认为是 images/ 下的目录
-				resolvedDir = pathManager.ResolvePath("images/" + inputDir)
-			}
-		} else {
-			resolvedDir = inputDir
-		}
+		resolvedDir := resolveInputDir(pathManager, inputDir)
 
 		logger.Infof("输入目录路径: %s (原始: %s)", resolvedDir, inputDir)
 		if logCallback != nil {
@@ -180,6 +355,7 @@ func (t *WD14Tagger) GenerateTagsWithCallback(request *models.TagRequest, callba
 			logCallback("info", fmt.Sprintf("目录 %s 中找到 %d 张图片", inputDir, len(dirImageFiles)))
 		}
 		allImageFiles = append(allImageFiles, dirImageFiles...)
+		inputRoots = append(inputRoots, resolvedDir)
 
 		if dirLimits == nil {
 			dirLimits = make(map[string]int)
@@ -208,13 +384,16 @@ func (t *WD14Tagger) GenerateTagsWithCallback(request *models.TagRequest, callba
 	if requestedOutputDir == "" {
 		// 如果输出目录为空，使用 tags/ 根目录
 		requestedOutputDir = "tags/"
-	} else if !strings.HasPrefix(requestedOutputDir, "tags/") && !strings.HasPrefix(requestedOutputDir, "images/") && !strings.HasPrefix(requestedOutputDir, "cache/") {
+	} else if !filepath.IsAbs(requestedOutputDir) && !strings.HasPrefix(requestedOutputDir, "tags/") && !strings.HasPrefix(requestedOutputDir, "images/") && !strings.HasPrefix(requestedOutputDir, "cache/") {
 		// 如果不是已有前缀，默认使用 tags/ 前缀
 		requestedOutputDir = "tags/" + requestedOutputDir
 	}
 
 	// 使用 PathManager 解析路径
-	outputDir := pathManager.ResolvePath(requestedOutputDir)
+	outputDir := requestedOutputDir
+	if pathManager != nil {
+		outputDir = pathManager.ResolvePath(requestedOutputDir)
+	}
 	logger.Infof("输出目录解析: 原始=%s, 处理后=%s, 解析后=%s", request.OutputDir, requestedOutputDir, outputDir)
 	if logCallback != nil {
 		logCallback("info", fmt.Sprintf("输出目录: %s", outputDir))
@@ -237,52 +416,152 @@ func (t *WD14Tagger) GenerateTagsWithCallback(request *models.TagRequest, callba
 	// 处理每张图片
 	taggedCount := 0
 	failedCount := 0
+	skippedCount := 0
 	lastError := ""
 
+	if len(t.completedImages) > 0 {
+		logger.Infof("从断点恢复：已有 %d 张图片完成打标，将跳过", len(t.completedImages))
+		if logCallback != nil {
+			logCallback("info", fmt.Sprintf("从断点恢复：已有 %d 张图片完成打标，将跳过", len(t.completedImages)))
+		}
+	}
+
 	logger.Infof("开始处理 %d 张图片", len(imageFiles))
 	if logCallback != nil {
 		logCallback("info", fmt.Sprintf("开始处理 %d 张图片", len(imageFiles)))
 	}
 
+	concurrency := t.concurrency
+	if request.Concurrency > 0 {
+		concurrency = request.Concurrency
+	}
+	if concurrency > 1 {
+		logger.Infof("并发识别图片: 并发数=%d", concurrency)
+		if logCallback != nil {
+			logCallback("info", fmt.Sprintf("并发识别图片: 并发数=%d", concurrency))
+		}
+	}
+
+	// 按输出方式计算每张图片的标签文件位置
+	targets := make([]tagTarget, len(imageFiles))
+	for i, imagePath := range imageFiles {
+		targets[i] = tagTargetFor(imagePath, inputRoots, outputDir, request)
+	}
+	if request.OutputMode != "" && request.OutputMode != OutputModeFlat {
+		logger.Infof("标签输出方式: %s", request.OutputMode)
+		if logCallback != nil {
+			logCallback("info", fmt.Sprintf("标签输出方式: %s", request.OutputMode))
+		}
+	}
+
+	// TXT 标签文件的生成参数记录在所在目录的清单中，生成结束（包括停止）时写回
+	manifests := newTagManifests()
+	defer func() {
+		if err := manifests.flush(); err != nil {
+			logger.Warnf("%v", err)
+		}
+	}()
+
+	// 增量打标：标签文件存在且生成参数相同的图片不再重新识别（force 时全部重新识别）
+	// CSV、JSONL 每次生成完整的汇总文件，不做增量判断
+	upToDate := make(map[string]bool)
+	if !request.Force && !IsAggregateSaveType(request.SaveType) {
+		for i, imagePath := range imageFiles {
+			if t.completedImages[imagePath] || !t.tagFileUpToDate(imagePath, targets[i], request.SaveType, manifests) {
+				continue
+			}
+			// 数据集导出时还需要图片已在概念文件夹中
+			if request.OutputMode == OutputModeDataset {
+				if _, err := os.Stat(targets[i].path(strings.ToLower(filepath.Ext(imagePath)))); err != nil {
+					continue
+				}
+			}
+			upToDate[imagePath] = true
+		}
+		if len(upToDate) > 0 {
+			logger.Infof("增量打标: %d 张图片的标签文件已是最新，将跳过", len(upToDate))
+			if logCallback != nil {
+				logCallback("info", fmt.Sprintf("增量打标: %d 张图片的标签文件已是最新，将跳过（设置 force 可强制重新生成）", len(upToDate)))
+			}
+		}
+	}
+	upToDateCount := 0
+
+	// CSV、JSONL 将所有图片的结果写入输出目录中的同一个文件，断点续传时追加
+	var exporter *tagExporter
+	if IsAggregateSaveType(request.SaveType) {
+		exporter, err = openTagExporter(outputDir, request.ExportName, request.SaveType, len(t.completedImages) > 0)
+		if err != nil {
+			logger.Errorf("%v", err)
+			if logCallback != nil {
+				logCallback("error", err.Error())
+			}
+			return err
+		}
+		logger.Infof("标签汇总文件: %s", exporter.path)
+		if logCallback != nil {
+			logCallback("info", fmt.Sprintf("标签汇总文件: %s", exporter.path))
+		}
+	}
+
+	// 图片在后台并发识别，这里按图片顺序保存结果和回调，输出与串行处理一致
+	outcomes, release := t.startInterrogation(ctx, imageFiles, request, skipPatterns, concurrency, upToDate)
+
 	for i, imagePath := range imageFiles {
 		logger.Infof("处理图片 %d/%d: %s", i+1, len(imageFiles), filepath.Base(imagePath))
 		if logCallback != nil && (i == 0 || (i+1)%10 == 0 || i == len(imageFiles)-1) {
 			logCallback("info", fmt.Sprintf("处理图片 %d/%d: %s", i+1, len(imageFiles), filepath.Base(imagePath)))
 		}
 
+		// 停止或取消后不再保存结果，已保存的标签文件保留
+		var outcome *interrogateOutcome
+		select {
+		case outcome = <-outcomes[i]:
+		case <-ctx.Done():
+		}
+		if outcome == nil || ctx.Err() != nil {
+			break
+		}
+		if !outcome.skipped {
+			release()
+		}
+
 		// 调用进度回调
 		if callback != nil {
 			callback(i+1, len(imageFiles))
 		}
 
-		// 读取图片并转换为 Base64
-		imageData, err := readImageAsBase64(imagePath)
-		if err != nil {
-			errMsg := fmt.Sprintf("读取图片失败 %s: %v", filepath.Base(imagePath), err)
-			logger.Errorf(errMsg)
-			if logCallback != nil {
-				logCallback("error", errMsg)
-			}
-			failedCount++
-			lastError = fmt.Sprintf("读取图片失败: %v", err)
+		// 断点续传或增量打标：跳过已完成的图片
+		if outcome.upToDate {
+			upToDateCount++
+			continue
+		}
+		if outcome.skipped {
+			skippedCount++
 			continue
 		}
 
-		// 调用 WD14 Tagger API
-		result, err := t.interrogateImage(imageData, request)
-		if err != nil {
-			errMsg := fmt.Sprintf("生成标签失败 %s: %v", filepath.Base(imagePath), err)
+		if outcome.err != nil {
+			errMsg := fmt.Sprintf("%s: %v", filepath.Base(imagePath), outcome.err)
 			logger.Errorf(errMsg)
 			if logCallback != nil {
 				logCallback("error", errMsg)
 			}
 			failedCount++
-			lastError = fmt.Sprintf("生成标签失败: %v", err)
+			lastError = outcome.err.Error()
+			t.notifyItem(imagePath, outcome.err)
 			continue
 		}
+		result := outcome.result
 
-		// 保存标签文件
-		if err := t.saveTags(imagePath, result, outputDir, request.SaveType); err != nil {
+		// 保存标签文件（CSV、JSONL 写入汇总文件）
+		var outputPath string
+		if exporter != nil {
+			err = exporter.write(t, imagePath, result)
+		} else {
+			outputPath, err = t.saveTags(imagePath, result, targets[i], request.SaveType)
+		}
+		if err != nil {
 			errMsg := fmt.Sprintf("保存标签文件失败 %s: %v", filepath.Base(imagePath), err)
 			logger.Errorf(errMsg)
 			if logCallback != nil {
@@ -290,23 +569,94 @@ func (t *WD14Tagger) GenerateTagsWithCallback(request *models.TagRequest, callba
 			}
 			failedCount++
 			lastError = fmt.Sprintf("保存标签文件失败: %v", err)
+			t.notifyItem(imagePath, fmt.Errorf("保存标签文件失败: %v", err))
 			continue
 		}
 
+		// 描述模式：单独保存 .caption 文件
+		var captionPath string
+		if exporter == nil && t.captionFile && result.Caption != "" {
+			captionPath, err = t.saveCaption(result, targets[i])
+			if err != nil {
+				errMsg := fmt.Sprintf("保存描述文件失败 %s: %v", filepath.Base(imagePath), err)
+				logger.Errorf(errMsg)
+				if logCallback != nil {
+					logCallback("error", errMsg)
+				}
+				failedCount++
+				lastError = fmt.Sprintf("保存描述文件失败: %v", err)
+				t.notifyItem(imagePath, fmt.Errorf("保存描述文件失败: %v", err))
+				continue
+			}
+		}
+
+		// 数据集导出：图片与标签文件放在同一个概念文件夹中
+		var datasetImage string
+		if request.OutputMode == OutputModeDataset {
+			datasetImage, err = copyDatasetImage(imagePath, targets[i])
+			if err != nil {
+				errMsg := fmt.Sprintf("导出数据集图片失败 %s: %v", filepath.Base(imagePath), err)
+				logger.Errorf(errMsg)
+				if logCallback != nil {
+					logCallback("error", errMsg)
+				}
+				failedCount++
+				lastError = fmt.Sprintf("导出数据集图片失败: %v", err)
+				t.notifyItem(imagePath, fmt.Errorf("导出数据集图片失败: %v", err))
+				continue
+			}
+		}
+
+		if exporter == nil && request.SaveType != "json" {
+			manifests.set(targets[i], t.fileMeta())
+		}
+
 		taggedCount++
+		if t.outputCallback != nil {
+			if outputPath != "" {
+				t.outputCallback(imagePath, outputPath, OutputKindTag)
+			}
+			if captionPath != "" {
+				t.outputCallback(imagePath, captionPath, OutputKindCaption)
+			}
+			if datasetImage != "" {
+				t.outputCallback(imagePath, datasetImage, OutputKindImage)
+			}
+		}
+		t.notifyItem(imagePath, nil)
 		logger.Infof("成功处理图片 %d/%d", taggedCount, len(imageFiles))
 		if logCallback != nil && (taggedCount%10 == 0 || taggedCount == len(imageFiles)) {
 			logCallback("info", fmt.Sprintf("成功处理 %d/%d 张图片", taggedCount, len(imageFiles)))
 		}
 	}
 
-	logger.Infof("标签生成完成: 成功处理 %d/%d 张图片", taggedCount, len(imageFiles))
+	if exporter != nil {
+		if err := exporter.Close(); err != nil {
+			logger.Warnf("关闭标签汇总文件失败: %v", err)
+		}
+		if exporter.written > 0 && t.outputCallback != nil {
+			t.outputCallback("", exporter.path, OutputKindTag)
+		}
+	}
+
+	t.stats = TagStats{Total: len(imageFiles), Tagged: taggedCount, Failed: failedCount, Skipped: skippedCount, UpToDate: upToDateCount}
+	if err := ctx.Err(); err != nil {
+		t.stats.Stopped = true
+		stopMsg := fmt.Sprintf("标签生成已停止: 成功处理 %d/%d 张图片（失败 %d 张，断点跳过 %d 张）", taggedCount, len(imageFiles), failedCount, skippedCount)
+		logger.Infof(stopMsg)
+		if logCallback != nil {
+			logCallback("warning", stopMsg)
+		}
+		return err
+	}
+
+	logger.Infof("标签生成完成: 成功处理 %d/%d 张图片（断点跳过 %d 张，已是最新 %d 张）", taggedCount, len(imageFiles), skippedCount, upToDateCount)
 	if logCallback != nil {
-		logCallback("info", fmt.Sprintf("标签生成完成: 成功处理 %d/%d 张图片", taggedCount, len(imageFiles)))
+		logCallback("info", fmt.Sprintf("标签生成完成: 成功处理 %d/%d 张图片（断点跳过 %d 张，已是最新 %d 张）", taggedCount, len(imageFiles), skippedCount, upToDateCount))
 	}
 
 	// 如果所有图片都处理失败，返回错误
-	if taggedCount == 0 && failedCount > 0 {
+	if taggedCount == 0 && skippedCount == 0 && upToDateCount == 0 && failedCount > 0 {
 		errMsg := fmt.Sprintf("所有图片处理失败（失败 %d 张），最后错误: %s", failedCount, lastError)
 		logger.Errorf(errMsg)
 		if logCallback != nil {
@@ -327,6 +677,13 @@ func (t *WD14Tagger) GenerateTagsWithCallback(request *models.TagRequest, callba
 	return nil
 }
 
+// notifyItem 通知单张图片的处理结果
+func (t *WD14Tagger) notifyItem(imagePath string, err error) {
+	if t.itemCallback != nil {
+		t.itemCallback(imagePath, err)
+	}
+}
+
 // TagConfidence 标签和置信度
 type TagConfidence struct {
 	Name       string
@@ -336,275 +693,105 @@ type TagConfidence struct {
 // InterrogateResult 包含原始标签数据和解析后的标签字符串
 type InterrogateResult struct {
 	TagsString string                 `json:"tags_string"` // 逗号分隔的标签字符串
+	FinalTags  []TagConfidence        `json:"final_tags"`  // 应用标签规则后的标签及置信度（扩展标签置信度为 0）
 	TagsData   map[string]interface{} `json:"tags_data"`   // 原始标签数据（tag -> confidence）
 	RatingData map[string]interface{} `json:"rating_data"` // rating 数据
+	Caption    string                 `json:"caption"`     // 描述模式生成的自然语言描述
 }
 
-// interrogateImage 调用标签生成 API（WD14 Tagger 或 DeepBooru），返回完整结果
-func (t *WD14Tagger) interrogateImage(imageData string, request *models.TagRequest) (*InterrogateResult, error) {
-	var url string
-	var jsonData []byte
-	var err error
-
-	// 根据分析器类型选择不同的 API
-	if t.analyzer == "deepbooru" {
-		// DeepBooru 使用标准 WebUI API
-		url = fmt.Sprintf("%s/sdapi/v1/interrogate", strings.TrimRight(t.baseURL, "/"))
-
-		// DeepBooru 的请求体格式
-		reqBody := map[string]interface{}{
-			"image": imageData,
-			"model": "deepbooru", // 指定使用 deepbooru 模型
-		}
-		jsonData, err = json.Marshal(reqBody)
-		logger.Infof("发送 DeepBooru API 请求")
-	} else {
-		// WD14-Tagger 使用扩展 API
-		url = fmt.Sprintf("%s/tagger/v1/interrogate", strings.TrimRight(t.baseURL, "/"))
-
-		// WD14-Tagger 的请求体格式
-		reqBody := InterrogateRequest{
-			Image:      imageData,
-			Model:      t.model, // 使用配置的模型（默认 wd14-convnext-v2，支持 CPU）
-			Threshold:  t.threshold,
-			BatchCount: 1,
-		}
-		jsonData, err = json.Marshal(reqBody)
-		logger.Infof("发送 WD14 Tagger 请求: model=%s, threshold=%.2f", t.model, t.threshold)
-	}
-
-	if err != nil {
-		return nil, fmt.Errorf("序列化请求失败: %v", err)
-	}
-
-	// 创建 HTTP 请求
-	req, err := http.NewRequest("POST", url, strings.NewReader(string(jsonData)))
-	if err != nil {
-		return nil, fmt.Errorf("创建请求失败: %v", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-
-	// 发送请求
-	resp, err := t.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("发送请求失败: %v", err)
-	}
-	defer resp.Body.Close()
-
-	// 读取响应
-	respBody, err := io.ReadAll(resp.Body)
+// interrogateImage 调用标签后端识别单张图片；后端返回置信度时按类别阈值在本地过滤
+func (t *WD14Tagger) interrogateImage(ctx context.Context, imageData string) (*InterrogateResult, error) {
+	result, err := t.backend.Interrogate(ctx, imageData, InterrogateOptions{
+		Model:     t.model,
+		Threshold: t.thresholds.Min(), // 按最低阈值请求，再按类别在本地过滤
+	})
 	if err != nil {
-		return nil, fmt.Errorf("读取响应失败: %v", err)
-	}
-
-	// 记录实际响应内容用于调试
-	analyzerName := "WD14 Tagger"
-	if t.analyzer == "deepbooru" {
-		analyzerName = "DeepBooru"
-	}
-	logger.Infof("%s API 响应: status=%d, body长度=%d", analyzerName, resp.StatusCode, len(respBody))
-	if len(respBody) < 500 {
-		logger.Infof("完整响应: %s", string(respBody))
-	}
-
-	// 检查状态码
-	if resp.StatusCode != http.StatusOK {
-		// 检查是否是 CUDA/ONNX Runtime 错误
-		if strings.Contains(string(respBody), "ONNX Runtime") || strings.Contains(string(respBody), "CUDA") || strings.Contains(string(respBody), "cuDNN") {
-			return nil, fmt.Errorf("GPU/CUDA 配置错误: %s\n\n"+
-				"解决方案：\n"+
-				"1. 确认已安装 CUDA 12.x 和匹配的 cuDNN 版本\n"+
-				"2. 或在 WebUI 中配置使用 CPU 模式（修改 WebUI 启动参数或扩展配置）\n"+
-				"3. 检查 WebUI 日志了解详细错误信息", string(respBody))
-		}
-		return nil, fmt.Errorf("API 调用失败: status=%d, body=%s", resp.StatusCode, string(respBody))
-	}
-
-	// 首先尝试作为 map 解析
-	var rawResponse map[string]interface{}
-	if err := json.Unmarshal(respBody, &rawResponse); err != nil {
-		return nil, fmt.Errorf("解析响应失败: %v", err)
-	}
-
-	// logger.Infof("原始响应内容: %+v", rawResponse)
-
-	// DeepBooru 可能返回不同的字段名
-	var captionInterface interface{}
-	var exists bool
-
-	if t.analyzer == "deepbooru" {
-		// DeepBooru 可能返回 "caption" 或 "result"
-		captionInterface, exists = rawResponse["caption"]
-		if !exists {
-			captionInterface, exists = rawResponse["result"]
-		}
-	} else {
-		// WD14-Tagger 返回 "caption" 字段
-		captionInterface, exists = rawResponse["caption"]
-	}
-
-	if !exists {
-		return nil, fmt.Errorf("响应中没有 caption 字段（分析器: %s）: %+v", t.analyzer, rawResponse)
+		return nil, err
 	}
 
-	logger.Infof("提取到 caption: 类型=%T, 值（前200字符）=%v", captionInterface, func() string {
-		str := fmt.Sprintf("%v", captionInterface)
-		if len(str) > 200 {
-			return str[:200] + "..."
+	if t.backend.Capabilities().Confidences {
+		t.applyThresholds(result)
+		if result.TagsString == "" {
+			return nil, fmt.Errorf("没有满足阈值的标签（分析器: %s）", t.backend.Name())
 		}
-		return str
-	}())
-
-	// 处理 caption 字段（可能是字符串、对象或数组）
-	result := &InterrogateResult{
-		TagsData:   make(map[string]interface{}),
-		RatingData: make(map[string]interface{}),
 	}
-
-	switch v := captionInterface.(type) {
-	case string:
-		result.TagsString = v
-		logger.Infof("caption 是字符串: %s", result.TagsString)
-	case map[string]interface{}:
-		// 如果是对象，可能是两种格式：
-		// 1. { "tags": [...], "rating": [...] } - 结构化格式
-		// 2. { "tag1": 0.9, "tag2": 0.8 } - 标签名->置信度映射
-		logger.Infof("caption 是对象，包含 %d 个键", len(v))
-		logger.Infof("前10个键: %v", func() []string {
-			keys := make([]string, 0, 10)
-			count := 0
-			for k := range v {
-				if count < 10 {
-					keys = append(keys, k)
-					count++
-				}
-			}
-			return keys
-		}())
-
-		// 将 map 按 rating 和 tags 分类
-		tagNames := make([]string, 0, len(v))
-		result.TagsData = make(map[string]interface{})
-		result.RatingData = make(map[string]interface{})
-
-		for key, value := range v {
-			// 检查是否是 rating 相关字段
-			if key == "general" || key == "sensitive" || key == "questionable" || key == "explicit" {
-				result.RatingData[key] = value
-			} else if key != "rating" {
-				// 这是一个标签
-				result.TagsData[key] = value
-
-				// 检查置信度（如果是数字），满足阈值才加入标签字符串
-				if conf, ok := value.(float64); ok {
-					if conf >= t.threshold {
-						tagNames = append(tagNames, key)
-					}
-				} else {
-					// 如果不是数字，也加入
-					tagNames = append(tagNames, key)
-				}
-			}
-		}
-
-		result.TagsString = strings.Join(tagNames, ", ")
-		logger.Infof("提取到 %d 个标签（阈值过滤后）", len(tagNames))
-		logger.Infof("Rating 数据: %+v", result.RatingData)
-	case []interface{}:
-		// 如果是数组
-		tagStrings := make([]string, 0, len(v))
-		for _, tag := range v {
-			if tagStr, ok := tag.(string); ok && tagStr != "" {
-				tagStrings = append(tagStrings, tagStr)
-			}
-		}
-		result.TagsString = strings.Join(tagStrings, ", ")
-		logger.Infof("从数组提取到标签: %s", result.TagsString)
-	default:
-		return nil, fmt.Errorf("未知的 caption 类型: %T, 值: %v", v, v)
-	}
-
-	if result.TagsString == "" {
-		return nil, fmt.Errorf("无法提取标签，caption: %+v", captionInterface)
-	}
-
 	logger.Infof("最终标签: %s", result.TagsString)
-
-	// 返回完整结果
 	return result, nil
 }
 
-// saveTags 保存标签到文件
-func (t *WD14Tagger) saveTags(imagePath string, result *InterrogateResult, outputDir, saveType string) error {
-	logger.Infof("开始保存标签: 图片=%s, 输出目录=%s, 保存类型=%s", imagePath, outputDir, saveType)
+// saveTags 保存标签到文件，返回写入的文件路径
+func (t *WD14Tagger) saveTags(imagePath string, result *InterrogateResult, target tagTarget, saveType string) (string, error) {
+	logger.Infof("开始保存标签: 图片=%s, 输出目录=%s, 保存类型=%s", imagePath, target.dir, saveType)
 
 	// 确保输出目录存在
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return fmt.Errorf("创建输出目录失败: %v", err)
+	if err := os.MkdirAll(target.dir, 0755); err != nil {
+		return "", fmt.Errorf("创建输出目录失败: %v", err)
 	}
-	logger.Infof("输出目录已创建/验证: %s", outputDir)
-
-	// 生成输出文件名（与原图同名，扩展名不同）
-	imageName := filepath.Base(imagePath)
-	imageNameWithoutExt := strings.TrimSuffix(imageName, filepath.Ext(imageName))
+	logger.Infof("输出目录已创建/验证: %s", target.dir)
 
 	var outputPath string
 	switch saveType {
 	case "json":
-		outputPath = filepath.Join(outputDir, imageNameWithoutExt+".json")
+		outputPath = target.path(".json")
 		logger.Infof("保存为 JSON 格式: %s", outputPath)
 
-		// 按置信度排序 tags
-		tagList := make([]TagConfidence, 0, len(result.TagsData))
-		for tagName, confValue := range result.TagsData {
-			if conf, ok := confValue.(float64); ok {
-				tagList = append(tagList, TagConfidence{Name: tagName, Confidence: conf})
-			}
-		}
-		// 按置信度降序排序
-		for i := 0; i < len(tagList); i++ {
-			for j := i + 1; j < len(tagList); j++ {
-				if tagList[i].Confidence < tagList[j].Confidence {
-					tagList[i], tagList[j] = tagList[j], tagList[i]
-				}
+		// 使用应用标签规则后的最终标签，扩展标签没有置信度
+		tags := make(map[string]interface{}, len(result.FinalTags))
+		for _, tag := range result.FinalTags {
+			if tag.Confidence > 0 {
+				tags[tag.Name] = tag.Confidence
+			} else {
+				tags[tag.Name] = nil
 			}
 		}
 
 		// 构建类似 WebUI 的输出结构，包含格式化数据
-		formattedOutput := t.formatWebUIOutput(result, tagList)
+		formattedOutput := t.formatWebUIOutput(result, result.FinalTags)
 
 		// 构建类似 WebUI 的输出结构
 		tagData := map[string]interface{}{
 			"image_path":  imagePath,
 			"tags_string": result.TagsString,
-			"tags":        result.TagsData,
+			"tags":        tags,
 			"ratings":     result.RatingData,
-			"formatted":   formattedOutput, // WebUI 风格的格式化输出
-			"tags_sorted": tagList,         // 排序后的标签列表
+			"formatted":   formattedOutput,  // WebUI 风格的格式化输出
+			"tags_sorted": result.FinalTags, // 按 tag_order 排序后的最终标签列表
+			"analyzer":    t.analyzerName(), // 生成参数，用于增量打标时判断标签文件是否需要重新生成
+			"model":       t.model,
+			"thresholds":  t.thresholds, // 使用的置信度阈值
 			"created_at":  time.Now().Format(time.RFC3339),
 		}
+		if t.captioner != nil {
+			tagData["caption"] = result.Caption
+			tagData["caption_backend"] = t.captioner.Name()
+			tagData["caption_model"] = t.captionModel
+			tagData["caption_template"] = t.captionTemplate
+		}
 		jsonData, err := json.MarshalIndent(tagData, "", "  ")
 		if err != nil {
-			return fmt.Errorf("序列化 JSON 失败: %v", err)
+			return "", fmt.Errorf("序列化 JSON 失败: %v", err)
 		}
 		if err := os.WriteFile(outputPath, jsonData, 0644); err != nil {
-			return fmt.Errorf("写入文件失败: %v", err)
+			return "", fmt.Errorf("写入文件失败: %v", err)
 		}
 		logger.Infof("✓ 成功保存 JSON 文件: %s (大小: %d 字节)", outputPath, len(jsonData))
 	case "txt":
 		fallthrough
 	default:
-		outputPath = filepath.Join(outputDir, imageNameWithoutExt+".txt")
+		outputPath = target.path(".txt")
 		logger.Infof("保存为 TXT 格式: %s", outputPath)
-		// 保存为 TXT 格式
-		if err := os.WriteFile(outputPath, []byte(result.TagsString), 0644); err != nil {
-			return fmt.Errorf("写入文件失败: %v", err)
+		// 保存为 TXT 格式；描述模式且不单独保存 .caption 文件时按模板组合描述和标签
+		text := result.TagsString
+		if t.captioner != nil && !t.captionFile {
+			text = RenderCaptionTemplate(t.captionTemplate, result.Caption, result.TagsString)
 		}
-		logger.Infof("✓ 成功保存 TXT 文件: %s (大小: %d 字节)", outputPath, len(result.TagsString))
+		if err := os.WriteFile(outputPath, []byte(text), 0644); err != nil {
+			return "", fmt.Errorf("写入文件失败: %v", err)
+		}
+		logger.Infof("✓ 成功保存 TXT 文件: %s (大小: %d 字节)", outputPath, len(text))
 	}
 
-	return nil
+	return outputPath, nil
 }
 
 // formatWebUIOutput 格式化为 WebUI 风格的输出
@@ -638,6 +825,19 @@ func (t *WD14Tagger) formatWebUIOutput(result *InterrogateResult, tagList []TagC
 	return builder.String()
 }
 
+// resolveInputDir 解析输入目录：输入目录应该是相对于 images/ 目录的，没有前缀时添加 images/ 前缀
+func resolveInputDir(pathManager *paths.PathManager, inputDir string) string {
+	if pathManager == nil {
+		return inputDir
+	}
+	// 如果已经有前缀，直接解析；否则添加 images/ 前缀
+	if strings.HasPrefix(inputDir, "images/") || strings.HasPrefix(inputDir, "tags/") || strings.HasPrefix(inputDir, "cache/") {
+		return pathManager.ResolvePath(inputDir)
+	}
+	// 默认认为是 images/ 下的目录
+	return pathManager.ResolvePath("images/" + inputDir)
+}
+
 // getImageFiles 获取目录中的所有图片文件
 func getImageFiles(dir string, limit int) ([]string, error) {
 	var imageFiles []string
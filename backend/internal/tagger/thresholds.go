@@ -0,0 +1,70 @@
+package tagger
+
+import "strings"
+
+// DefaultThreshold 默认置信度阈值（角色标签阈值未配置时使用通用阈值）
+const DefaultThreshold = 0.35
+
+// TagClassifyFunc 返回标签的类别名称（如 character、hair），用于按类别设置阈值
+type TagClassifyFunc func(tag string) string
+
+// TagThresholds 标签置信度阈值（写入 JSON 输出以便复现结果）
+type TagThresholds struct {
+	General    float64            `json:"general"`              // 通用阈值
+	Character  float64            `json:"character"`            // 角色标签阈值，0 表示使用通用阈值
+	Categories map[string]float64 `json:"categories,omitempty"` // 按类别设置的阈值，优先于角色标签阈值和通用阈值
+}
+
+// For 返回指定类别使用的阈值
+func (th TagThresholds) For(category string) float64 {
+	if threshold, ok := th.Categories[category]; ok {
+		return threshold
+	}
+	if category == "character" && th.Character > 0 {
+		return th.Character
+	}
+	return th.General
+}
+
+// Min 所有阈值中的最小值（请求接口时使用，再按类别在本地过滤）
+func (th TagThresholds) Min() float64 {
+	min := th.General
+	if th.Character > 0 && th.Character < min {
+		min = th.Character
+	}
+	for _, threshold := range th.Categories {
+		if threshold < min {
+			min = threshold
+		}
+	}
+	return min
+}
+
+// applyThresholds 按类别阈值过滤标签（有置信度数据时按置信度排序，否则保持后端返回的顺序），没有置信度的标签保留
+func (t *WD14Tagger) applyThresholds(result *InterrogateResult) {
+	names := strings.Split(result.TagsString, ",")
+	if len(result.TagsData) > 0 {
+		names = tagsByConfidence(result.TagsData)
+	}
+	var kept []string
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if conf, ok := result.TagsData[name].(float64); ok && conf < t.thresholdFor(name) {
+			continue
+		}
+		kept = append(kept, name)
+	}
+	result.TagsString = strings.Join(kept, ", ")
+}
+
+// thresholdFor 返回标签使用的阈值
+func (t *WD14Tagger) thresholdFor(tag string) float64 {
+	category := ""
+	if t.classifyFunc != nil {
+		category = t.classifyFunc(tag)
+	}
+	return t.thresholds.For(category)
+}
@@ -5,14 +5,18 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	"pixiv-tailor/backend/internal/events"
 	httphandler "pixiv-tailor/backend/internal/http"
 	"pixiv-tailor/backend/internal/repository"
+	"pixiv-tailor/backend/internal/service"
 	"pixiv-tailor/backend/pkg/models"
 )
 
@@ -76,6 +80,11 @@ func (m *MockTaskService) StartTask(id string) error {
 	return args.Error(0)
 }
 
+func (m *MockTaskService) ResumeTask(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
 func (m *MockTaskService) StopTask(id string) error {
 	args := m.Called(id)
 	return args.Error(0)
@@ -91,17 +100,123 @@ func (m *MockTaskService) DeleteTask(id string) error {
 	return args.Error(0)
 }
 
+func (m *MockTaskService) DeleteTaskWithOptions(id string, purgeFiles bool) (*service.TaskDeleteReport, error) {
+	args := m.Called(id, purgeFiles)
+	return args.Get(0).(*service.TaskDeleteReport), args.Error(1)
+}
+
+func (m *MockTaskService) GetTaskFailedItems(id string) ([]*repository.TaskFailedItem, error) {
+	args := m.Called(id)
+	return args.Get(0).([]*repository.TaskFailedItem), args.Error(1)
+}
+
+func (m *MockTaskService) SearchTasks(filter *repository.TaskFilter, page, pageSize int32) ([]*repository.Task, int, error) {
+	args := m.Called(filter, page, pageSize)
+	return args.Get(0).([]*repository.Task), args.Int(1), args.Error(2)
+}
+
+func (m *MockTaskService) UpdateTaskLabels(id string, labels []string) error {
+	args := m.Called(id, labels)
+	return args.Error(0)
+}
+
+func (m *MockTaskService) UpdateTaskNotes(id, notes string) error {
+	args := m.Called(id, notes)
+	return args.Error(0)
+}
+
+func (m *MockTaskService) ListTaskLabels() (map[string]int, error) {
+	args := m.Called()
+	return args.Get(0).(map[string]int), args.Error(1)
+}
+
+func (m *MockTaskService) CreateTasks(taskType string, configs []string, priority int) ([]*repository.Task, error) {
+	args := m.Called(taskType, configs, priority)
+	return args.Get(0).([]*repository.Task), args.Error(1)
+}
+
+func (m *MockTaskService) CloneTask(id string, overrides map[string]interface{}) (*repository.Task, error) {
+	args := m.Called(id, overrides)
+	return args.Get(0).(*repository.Task), args.Error(1)
+}
+
+func (m *MockTaskService) CreateSweep(taskType string, base map[string]interface{}, sweep map[string][]interface{}, priority int) (string, []*repository.Task, error) {
+	args := m.Called(taskType, base, sweep, priority)
+	return args.String(0), args.Get(1).([]*repository.Task), args.Error(2)
+}
+
+func (m *MockTaskService) GetTaskGroup(groupID string) (*service.TaskGroupProgress, error) {
+	args := m.Called(groupID)
+	return args.Get(0).(*service.TaskGroupProgress), args.Error(1)
+}
+
+func (m *MockTaskService) SetTaskPriority(id string, priority int) error {
+	args := m.Called(id, priority)
+	return args.Error(0)
+}
+
+func (m *MockTaskService) FindTaskIDs(filter *repository.TaskFilter) ([]string, error) {
+	args := m.Called(filter)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockTaskService) BatchTasks(action string, ids []string, priority int, purgeFiles bool) ([]*service.BatchTaskResult, error) {
+	args := m.Called(action, ids, priority, purgeFiles)
+	return args.Get(0).([]*service.BatchTaskResult), args.Error(1)
+}
+
+func (m *MockTaskService) ListTaskArtifacts(taskID, role string) ([]*repository.TaskArtifact, error) {
+	args := m.Called(taskID, role)
+	return args.Get(0).([]*repository.TaskArtifact), args.Error(1)
+}
+
+func (m *MockTaskService) GetTaskArtifact(taskID string, id int64) (*repository.TaskArtifact, string, error) {
+	args := m.Called(taskID, id)
+	return args.Get(0).(*repository.TaskArtifact), args.String(1), args.Error(2)
+}
+
+func (m *MockTaskService) SetTaskStarred(id string, starred bool) error {
+	args := m.Called(id, starred)
+	return args.Error(0)
+}
+
+func (m *MockTaskService) RunRetention(policy *service.RetentionPolicy, dryRun bool) (*service.RetentionReport, error) {
+	args := m.Called(policy, dryRun)
+	return args.Get(0).(*service.RetentionReport), args.Error(1)
+}
+
+func (m *MockTaskService) GetTaskAttempts(id string) ([]*repository.TaskAttempt, error) {
+	args := m.Called(id)
+	return args.Get(0).([]*repository.TaskAttempt), args.Error(1)
+}
+
+func (m *MockTaskService) RetryFailedItems(id string) (*repository.Task, error) {
+	args := m.Called(id)
+	return args.Get(0).(*repository.Task), args.Error(1)
+}
+
+func (m *MockTaskService) GetTaskLogs(id, level string, page, pageSize int32) ([]*repository.TaskLog, int, error) {
+	args := m.Called(id, level, page, pageSize)
+	return args.Get(0).([]*repository.TaskLog), args.Int(1), args.Error(2)
+}
+
 func (m *MockTaskService) CleanupTasks(cleanupType string) (int, error) {
 	args := m.Called(cleanupType)
 	return args.Int(0), args.Error(1)
 }
 
-func (m *MockTaskService) SetLogCallback(callback func(taskID, level, message string)) {
-	m.Called(callback)
+func (m *MockTaskService) EventBus() events.Bus {
+	args := m.Called()
+	return args.Get(0).(events.Bus)
+}
+
+func (m *MockTaskService) RegisterExecutor(taskType string, executor service.TaskExecutor) {
+	// NewAIHandler 构造时会注册执行器，测试中无需断言
 }
 
-func (m *MockTaskService) SetStatusCallback(callback func(taskID, status string, progress int)) {
-	m.Called(callback)
+func (m *MockTaskService) GetConfigSchemas() map[string]*service.ConfigSchema {
+	args := m.Called()
+	return args.Get(0).(map[string]*service.ConfigSchema)
 }
 
 // MockGenerationConfigService 模拟生成配置服务
@@ -184,33 +299,67 @@ func (m *MockGenerationConfigService) DeleteConfigFile(id string) error {
 	return args.Error(0)
 }
 
+// newFakeWebUIServer 模拟 WebUI 的状态检查接口
+func newFakeWebUIServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/sdapi/v1/options" {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{}`))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// chdirToTempDataDir 切换到临时目录，处理器从 data/configs 读取生成配置
+func chdirToTempDataDir(t *testing.T) string {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("获取工作目录失败: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("切换工作目录失败: %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	if err := os.MkdirAll(filepath.Join(dir, "data", "configs"), 0755); err != nil {
+		t.Fatalf("创建配置目录失败: %v", err)
+	}
+	return dir
+}
+
 func TestAIHandler_HandleGenerateWithConfig(t *testing.T) {
+	dir := chdirToTempDataDir(t)
+	webui := newFakeWebUIServer(t)
+
 	// 创建模拟服务
 	mockTaskService := &MockTaskService{}
 	mockConfigService := &MockGenerationConfigService{}
 
 	// 创建AI处理器
 	handler := httphandler.NewAIHandler(nil, mockTaskService, mockConfigService)
+	handler.SetWebUIURL(webui.URL)
 
 	// 测试数据
-	testConfig := &models.GenerationConfigResponse{
-		ID:          "test-config",
-		Name:        "测试配置",
-		Category:    "动漫",
-		Description: "测试用配置",
-		Model:       "test_model.safetensors",
-		Loras: []models.LoraConfig{
-			{
-				Path:   "test_lora.safetensors",
-				Weight: 0.8,
-			},
-		},
-		Steps:    20,
-		CFGScale: 7,
-		Width:    512,
-		Height:   512,
-		Sampler:  "DPM++ 2M Karras",
-		EnableHR: false,
+	testConfig := map[string]interface{}{
+		"id":          "test-config",
+		"name":        "测试配置",
+		"category":    "动漫",
+		"description": "测试用配置",
+		"model":       "test_model.safetensors",
+		"prompt":      "test prompt",
+		"steps":       20,
+		"cfg_scale":   7,
+		"width":       512,
+		"height":      512,
+		"sampler":     "DPM++ 2M Karras",
+		"enable_hr":   false,
+	}
+	configData, _ := json.Marshal(testConfig)
+	if err := os.WriteFile(filepath.Join(dir, "data", "configs", "test-config.json"), configData, 0644); err != nil {
+		t.Fatalf("写入配置文件失败: %v", err)
 	}
 
 	testTask := &repository.Task{
@@ -221,21 +370,15 @@ func TestAIHandler_HandleGenerateWithConfig(t *testing.T) {
 	}
 
 	// 设置模拟期望
-	mockConfigService.On("GetConfig", "test-config").Return(testConfig, nil)
-	mockTaskService.On("CreateTask", "generate", mock.AnythingOfType("string")).Return(testTask, nil)
+	var createdConfig string
+	mockTaskService.On("CreateTask", "generate", mock.AnythingOfType("string")).
+		Run(func(args mock.Arguments) { createdConfig = args.String(1) }).
+		Return(testTask, nil)
 
 	// 创建测试请求
-	requestBody := models.GenerateRequest{
-		Model:          "test_model.safetensors",
-		Prompt:         "test prompt",
-		NegativePrompt: "bad quality",
-		Steps:          20,
-		CFGScale:       7,
-		Width:          512,
-		Height:         512,
-		BatchCount:     1,
-		EnableHR:       false,
-		Options:        map[string]interface{}{},
+	requestBody := httphandler.GenerationWithConfigRequest{
+		ConfigID: "test-config",
+		Override: map[string]interface{}{"steps": 30},
 	}
 
 	jsonBody, _ := json.Marshal(requestBody)
@@ -251,11 +394,25 @@ func TestAIHandler_HandleGenerateWithConfig(t *testing.T) {
 	// 验证响应
 	assert.Equal(t, http.StatusOK, w.Code)
 
-	var response map[string]interface{}
+	var response struct {
+		Status struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"status"`
+		Data map[string]interface{} `json:"data"`
+	}
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err)
-	assert.Equal(t, "success", response["status"])
-	assert.Equal(t, "AI生成任务已创建", response["message"])
+	assert.Equal(t, "success", response.Status.Message)
+	assert.Equal(t, "AI生成任务已创建", response.Data["message"])
+	assert.Equal(t, "test-task-id", response.Data["task_id"])
+
+	// 任务配置应包含配置文件内容和覆盖参数
+	var taskConfig map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(createdConfig), &taskConfig))
+	assert.Equal(t, "test-config", taskConfig["config_id"])
+	assert.Equal(t, "test_model.safetensors", taskConfig["model"])
+	assert.Equal(t, float64(30), taskConfig["steps"])
 
 	// 验证模拟调用
 	mockConfigService.AssertExpectations(t)
@@ -263,21 +420,19 @@ func TestAIHandler_HandleGenerateWithConfig(t *testing.T) {
 }
 
 func TestAIHandler_HandleGenerateWithConfig_InvalidConfig(t *testing.T) {
+	chdirToTempDataDir(t)
+	webui := newFakeWebUIServer(t)
+
 	// 创建模拟服务
 	mockTaskService := &MockTaskService{}
 	mockConfigService := &MockGenerationConfigService{}
 
 	// 创建AI处理器
 	handler := httphandler.NewAIHandler(nil, mockTaskService, mockConfigService)
+	handler.SetWebUIURL(webui.URL)
 
-	// 设置模拟期望 - 配置不存在
-	mockConfigService.On("GetConfig", "invalid-config").Return((*models.GenerationConfigResponse)(nil), assert.AnError)
-
-	// 创建测试请求
-	requestBody := models.GenerateRequest{
-		Model:  "test_model.safetensors",
-		Prompt: "test prompt",
-	}
+	// 创建测试请求 - 配置不存在
+	requestBody := httphandler.GenerationWithConfigRequest{ConfigID: "invalid-config"}
 
 	jsonBody, _ := json.Marshal(requestBody)
 	req := httptest.NewRequest("POST", "/api/generate-with-config", bytes.NewBuffer(jsonBody))
@@ -290,15 +445,21 @@ func TestAIHandler_HandleGenerateWithConfig_InvalidConfig(t *testing.T) {
 	handler.HandleGenerateWithConfig(w, req)
 
 	// 验证响应
-	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, http.StatusNotFound, w.Code)
 
-	var response map[string]interface{}
+	var response struct {
+		Status struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"status"`
+	}
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err)
-	assert.Equal(t, "error", response["status"])
+	assert.Equal(t, http.StatusNotFound, response.Status.Code)
+	assert.Equal(t, "Config not found", response.Status.Message)
 
-	// 验证模拟调用
-	mockConfigService.AssertExpectations(t)
+	// 配置不存在时不应创建任务
+	mockTaskService.AssertNotCalled(t, "CreateTask", mock.Anything, mock.Anything)
 }
 
 // 简化的测试函数
@@ -0,0 +1,37 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"pixiv-tailor/backend/internal/events"
+)
+
+func TestEventBus_FilterAndBoundedBuffer(t *testing.T) {
+	bus := events.NewBus()
+
+	taskLogs := bus.Subscribe("task-logs", events.Filter{Types: []events.EventType{events.TaskLog}, TaskID: "t1"}, 2)
+	defer taskLogs.Unsubscribe()
+	all := bus.Subscribe("all", events.Filter{}, 10)
+	defer all.Unsubscribe()
+
+	// 缓冲区满时发布不阻塞，多余事件被丢弃
+	for i := 0; i < 5; i++ {
+		bus.Publish(&events.Event{Type: events.TaskLog, TaskID: "t1", Data: &events.TaskLogData{Level: "info"}})
+	}
+	bus.Publish(&events.Event{Type: events.TaskLog, TaskID: "t2"})
+	bus.Publish(&events.Event{Type: events.WebUILog})
+
+	assert.Len(t, taskLogs.C, 2)
+	assert.Equal(t, int64(3), taskLogs.Dropped())
+	assert.Len(t, all.C, 7)
+
+	// 取消订阅后通道关闭，重复取消不会 panic
+	taskLogs.Unsubscribe()
+	taskLogs.Unsubscribe()
+	<-taskLogs.C
+	<-taskLogs.C
+	_, ok := <-taskLogs.C
+	assert.False(t, ok)
+}
@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"pixiv-tailor/backend/internal/events"
+	grpcserver "pixiv-tailor/backend/internal/grpc"
+	"pixiv-tailor/backend/internal/repository"
+	"pixiv-tailor/backend/internal/service"
+	pb "pixiv-tailor/proto"
+)
+
+// fakeLogStream 记录 GetTaskLogs 发送的日志
+type fakeLogStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent chan *pb.LogEntry
+}
+
+func (f *fakeLogStream) Context() context.Context {
+	return f.ctx
+}
+
+func (f *fakeLogStream) Send(entry *pb.LogEntry) error {
+	f.sent <- entry
+	return nil
+}
+
+func TestGRPCServer_GetTaskLogsFollow(t *testing.T) {
+	store := newTestStorage(t)
+	taskService := service.NewTaskService(store)
+	task := createStoredTask(t, store, "logs1234", "tag", "running")
+	server := &grpcserver.PixivTailorServer{TaskService: taskService}
+
+	// 历史日志和之后的实时日志时间相同
+	now := time.Now()
+	history := &repository.TaskLog{TaskID: task.ID, Level: "info", Message: "history", CreatedAt: now}
+	require.NoError(t, store.AddTaskLog(history))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &fakeLogStream{ctx: ctx, sent: make(chan *pb.LogEntry, 10)}
+	done := make(chan error, 1)
+	go func() {
+		done <- server.GetTaskLogs(&pb.GetTaskLogsRequest{TaskId: task.ID}, stream)
+	}()
+
+	receive := func() *pb.LogEntry {
+		select {
+		case entry := <-stream.sent:
+			return entry
+		case <-time.After(time.Second):
+			t.Fatal("未收到日志")
+			return nil
+		}
+	}
+	assert.Equal(t, "history", receive().Message)
+
+	publish := func(taskLog *repository.TaskLog) {
+		taskService.EventBus().Publish(&events.Event{
+			Type:   events.TaskLog,
+			TaskID: taskLog.TaskID,
+			Time:   taskLog.CreatedAt,
+			Data:   &events.TaskLogData{ID: taskLog.ID, Level: taskLog.Level, Message: taskLog.Message},
+		})
+	}
+
+	// 已在历史中发送过的日志不重复发送，同一时刻的新日志照常发送
+	publish(history)
+	live := &repository.TaskLog{TaskID: task.ID, Level: "info", Message: "same-time", CreatedAt: now}
+	require.NoError(t, store.AddTaskLog(live))
+	publish(live)
+	assert.Equal(t, "same-time", receive().Message)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("客户端断开后日志流未结束")
+	}
+	assert.Empty(t, stream.sent)
+}
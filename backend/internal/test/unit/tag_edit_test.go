@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pixiv-tailor/backend/internal/service"
+)
+
+func TestTagEditService(t *testing.T) {
+	root := chdirToTempDataDir(t)
+	dir := filepath.Join(root, "tags", "dataset")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	files := map[string]string{
+		"a.txt": "1girl, blue_hair, smiling, mychar\n",
+		"b.txt": "solo, smile, Blue_Hair",
+		"c.txt": "1boy, solo",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	editService := service.NewTagEditService(t.TempDir())
+
+	// 目录必须位于数据目录的 tags/ 或 images/ 之内
+	_, err := editService.Edit(&service.TagEditRequest{Dir: t.TempDir(), Operation: service.TagEditRemove, Tags: []string{"solo"}})
+	assert.Error(t, err)
+	_, err = editService.Edit(&service.TagEditRequest{Dir: "../outside", Operation: service.TagEditRemove, Tags: []string{"solo"}})
+	assert.Error(t, err)
+
+	// 预览不修改文件（相对路径按 tags/ 解析）
+	result, err := editService.Edit(&service.TagEditRequest{Dir: "dataset", Operation: service.TagEditMerge, Tags: []string{"smiling", "smile"}, To: "smile", DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Scanned)
+	assert.Equal(t, 1, result.Changed)
+	assert.Empty(t, result.JournalID)
+	assert.Equal(t, files["a.txt"], read("a.txt"))
+
+	// 重命名（空格与下划线视为相同）
+	result, err = editService.Edit(&service.TagEditRequest{Dir: dir, Operation: service.TagEditRename, From: "blue_hair", To: "blue hair"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Changed)
+	assert.Equal(t, "1girl, blue hair, smiling, mychar\n", read("a.txt"))
+	renameJournal := result.JournalID
+
+	// 只为匹配查询的文件添加标签
+	_, err = editService.Edit(&service.TagEditRequest{Dir: dir, Operation: service.TagEditAdd, Tags: []string{"masterpiece"}, Position: "prepend", Query: []string{"solo", "-1boy"}})
+	require.NoError(t, err)
+	assert.Equal(t, "masterpiece, solo, smile, blue hair", read("b.txt"))
+	assert.Equal(t, files["c.txt"], read("c.txt"))
+
+	// 移除、移到最前
+	_, err = editService.Edit(&service.TagEditRequest{Dir: dir, Operation: service.TagEditRemove, Tags: []string{"smil*"}})
+	require.NoError(t, err)
+	_, err = editService.Edit(&service.TagEditRequest{Dir: dir, Operation: service.TagEditFront, Tags: []string{"mychar"}})
+	require.NoError(t, err)
+	assert.Equal(t, "mychar, 1girl, blue hair\n", read("a.txt"))
+
+	_, err = editService.Edit(&service.TagEditRequest{Dir: dir, Operation: "unknown"})
+	assert.Error(t, err)
+
+	// 撤销最近一次编辑
+	result, err = editService.Undo("")
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Changed)
+	assert.Equal(t, "1girl, blue hair, mychar\n", read("a.txt"))
+
+	// 文件已被后续编辑修改时，撤销较早的编辑记为冲突
+	result, err = editService.Undo(renameJournal)
+	require.NoError(t, err)
+	assert.Len(t, result.Conflicts, 2)
+	_, err = editService.Undo(renameJournal)
+	assert.Error(t, err)
+
+	journals, err := editService.ListJournals()
+	require.NoError(t, err)
+	assert.Len(t, journals, 4)
+	assert.NotNil(t, journals[0].UndoneAt)
+}
@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pixiv-tailor/backend/internal/service"
+	"pixiv-tailor/backend/internal/tagger"
+	"pixiv-tailor/backend/pkg/models"
+)
+
+func TestTagRules(t *testing.T) {
+	tags := []tagger.TagConfidence{
+		{Name: "solo", Confidence: 0.95},
+		{Name: "long hair", Confidence: 0.9},
+		{Name: "hatsune_miku_(vocaloid)", Confidence: 0.8},
+		{Name: "blurry", Confidence: 0.6},
+		{Name: "blurry_background", Confidence: 0.5},
+		{Name: "simple_background", Confidence: 0.4},
+		{Name: "artist_name", Confidence: 0.3},
+	}
+	apply := func(request *models.TagRequest) []string {
+		patterns, err := tagger.CompileTagPatterns(request.SkipTags)
+		require.NoError(t, err)
+		return tagger.ApplyTagRules(tags, request, patterns, service.TagCategoryRank)
+	}
+
+	// 精确匹配不区分空格和下划线，通配符和正则
+	assert.Equal(t, []string{"solo", "hatsune_miku_(vocaloid)", "simple_background", "high_quality"}, apply(&models.TagRequest{
+		SkipTags:   []string{"long_hair", "blurry*", "/^artist_/"},
+		ExtendTags: []string{"high_quality"},
+		TagOrder:   tagger.TagOrderScore,
+	}))
+
+	// 扩展标签插入到开头，已存在的标签不重复
+	assert.Equal(t, []string{"miku_lora", "Solo", "artist_name", "blurry", "blurry_background", "hatsune_miku_(vocaloid)", "long hair", "simple_background"}, apply(&models.TagRequest{
+		ExtendTags:     []string{"miku_lora", "Solo"},
+		ExtendPosition: tagger.ExtendPositionPrepend,
+		TagOrder:       tagger.TagOrderAlphabet,
+	}))
+
+	// 按类别排序：角色标签在前，同类别内按置信度降序
+	assert.Equal(t, []string{"hatsune_miku_(vocaloid)", "solo", "long hair", "blurry_background", "simple_background", "artist_name", "blurry"}, apply(&models.TagRequest{
+		TagOrder: tagger.TagOrderCharacter,
+	}))
+
+	_, err := tagger.CompileTagPatterns([]string{"/(/"})
+	assert.Error(t, err)
+
+	// JSON 输出同样使用应用规则后的最终标签
+	fake := newFakeTaggerServer(t, func(string) time.Duration { return 0 })
+	outputDir := t.TempDir()
+	wd14Tagger, err := tagger.NewWD14Tagger()
+	require.NoError(t, err)
+	wd14Tagger.SetBaseURL(fake.URL)
+	require.NoError(t, wd14Tagger.GenerateTagsWithContext(context.Background(), &models.TagRequest{
+		InputDir:   writeTestImages(t, 1),
+		OutputDir:  outputDir,
+		SaveType:   "json",
+		Threshold:  0.4,
+		SkipTags:   []string{"general"},
+		ExtendTags: []string{"extra_tag"},
+	}, nil, nil))
+
+	data, err := os.ReadFile(filepath.Join(outputDir, "img_00.json"))
+	require.NoError(t, err)
+	var output struct {
+		TagsString string                 `json:"tags_string"`
+		Tags       map[string]interface{} `json:"tags"`
+		TagsSorted []tagger.TagConfidence `json:"tags_sorted"`
+	}
+	require.NoError(t, json.Unmarshal(data, &output))
+	assert.Equal(t, "img_00, extra_tag", output.TagsString)
+	assert.Equal(t, map[string]interface{}{"img_00": 0.9, "extra_tag": nil}, output.Tags)
+	assert.Equal(t, []tagger.TagConfidence{{Name: "img_00", Confidence: 0.9}, {Name: "extra_tag"}}, output.TagsSorted)
+}
+
+func TestTagThresholds(t *testing.T) {
+	thresholds := tagger.TagThresholds{General: 0.35, Character: 0.85, Categories: map[string]float64{"hair": 0.5, "quality": 0.2}}
+	assert.Equal(t, 0.35, thresholds.For("face"))
+	assert.Equal(t, 0.85, thresholds.For("character"))
+	assert.Equal(t, 0.5, thresholds.For("hair"))
+	assert.Equal(t, 0.2, thresholds.Min())
+
+	// 按类别设置的阈值优先于角色标签阈值
+	thresholds.Categories["character"] = 0.7
+	assert.Equal(t, 0.7, thresholds.For("character"))
+
+	// 未知类别和超出范围的阈值在创建任务时被拒绝
+	taskService := service.NewTaskService(newTestStorage(t))
+	_, err := taskService.CreateTask("tag", `{"input_dir": "task_1", "category_thresholds": {"hat": 0.5}}`)
+	assert.Error(t, err)
+	_, err = taskService.CreateTask("tag", `{"input_dir": "task_1", "category_thresholds": {"hair": 1.5}}`)
+	assert.Error(t, err)
+	_, err = taskService.CreateTask("tag", `{"input_dir": "task_1", "character_threshold": 2}`)
+	assert.Error(t, err)
+}
@@ -0,0 +1,412 @@
+package tests
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pixiv-tailor/backend/internal/tagger"
+	"pixiv-tailor/backend/pkg/models"
+)
+
+// fakeTaggerServer 模拟 WD14 Tagger 接口：图片内容即标签名，记录同时处理的最大请求数；
+// WebUI interrogate 接口（CLIP）返回 "a picture of 图片内容" 作为描述
+type fakeTaggerServer struct {
+	*httptest.Server
+	inFlight    int32
+	maxInFlight int32
+	requests    int32
+}
+
+func newFakeTaggerServer(t *testing.T, delay func(image string) time.Duration) *fakeTaggerServer {
+	fake := &fakeTaggerServer{}
+	fake.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fake.requests, 1)
+		current := atomic.AddInt32(&fake.inFlight, 1)
+		defer atomic.AddInt32(&fake.inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&fake.maxInFlight)
+			if current <= max || atomic.CompareAndSwapInt32(&fake.maxInFlight, max, current) {
+				break
+			}
+		}
+
+		var req tagger.InterrogateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		image, _ := base64.StdEncoding.DecodeString(req.Image)
+		select {
+		case <-time.After(delay(string(image))):
+		case <-r.Context().Done():
+			return
+		}
+		if r.URL.Path == "/sdapi/v1/interrogate" {
+			json.NewEncoder(w).Encode(map[string]interface{}{"caption": "a picture of " + string(image)})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"caption": map[string]interface{}{string(image): 0.9, "general": 0.8},
+		})
+	}))
+	t.Cleanup(fake.Close)
+	return fake
+}
+
+// writeTestImages 在临时目录中写入 count 张"图片"（内容为对应的标签名）
+func writeTestImages(t *testing.T, count int) string {
+	dir := t.TempDir()
+	for i := 0; i < count; i++ {
+		name := fmt.Sprintf("img_%02d", i)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name+".png"), []byte(name), 0644))
+	}
+	return dir
+}
+
+func TestWD14Tagger_ConcurrentTagging(t *testing.T) {
+	fake := newFakeTaggerServer(t, func(string) time.Duration { return 30 * time.Millisecond })
+	inputDir := writeTestImages(t, 8)
+	outputDir := t.TempDir()
+
+	wd14Tagger, err := tagger.NewWD14Tagger()
+	require.NoError(t, err)
+	wd14Tagger.SetBaseURL(fake.URL)
+
+	var mu sync.Mutex
+	var items []string
+	var progress []int
+	wd14Tagger.SetItemCallback(func(imagePath string, itemErr error) {
+		assert.NoError(t, itemErr)
+		mu.Lock()
+		items = append(items, filepath.Base(imagePath))
+		mu.Unlock()
+	})
+	request := &models.TagRequest{InputDir: inputDir, OutputDir: outputDir, SaveType: "txt", Concurrency: 3}
+	err = wd14Tagger.GenerateTagsWithContext(context.Background(), request, func(current, total int) {
+		progress = append(progress, current)
+	}, nil)
+	require.NoError(t, err)
+
+	// 并发识别，但回调和进度按图片顺序
+	assert.LessOrEqual(t, fake.maxInFlight, int32(3))
+	assert.Greater(t, fake.maxInFlight, int32(1))
+	assert.Equal(t, []string{"img_00.png", "img_01.png", "img_02.png", "img_03.png", "img_04.png", "img_05.png", "img_06.png", "img_07.png"}, items)
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 6, 7, 8}, progress)
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "img_05.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "img_05", string(content))
+}
+
+func TestWD14Tagger_StopsOnCancel(t *testing.T) {
+	// 前两张图片很快返回，其余图片的请求会一直挂起直到被取消
+	fake := newFakeTaggerServer(t, func(image string) time.Duration {
+		if image == "img_00" || image == "img_01" {
+			return 10 * time.Millisecond
+		}
+		return time.Minute
+	})
+	inputDir := writeTestImages(t, 20)
+
+	wd14Tagger, err := tagger.NewWD14Tagger()
+	require.NoError(t, err)
+	wd14Tagger.SetBaseURL(fake.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var failed []string
+	wd14Tagger.SetItemCallback(func(imagePath string, itemErr error) {
+		if itemErr != nil {
+			failed = append(failed, filepath.Base(imagePath))
+		}
+	})
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		cancel()
+	}()
+
+	request := &models.TagRequest{InputDir: inputDir, OutputDir: t.TempDir(), SaveType: "txt", Concurrency: 2}
+	start := time.Now()
+	err = wd14Tagger.GenerateTagsWithContext(ctx, request, nil, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	// 进行中的请求被立即中断，不等待接口返回
+	assert.Less(t, time.Since(start), 5*time.Second)
+	// 取消后不再开始新的识别，被中断的图片不记为失败
+	assert.LessOrEqual(t, atomic.LoadInt32(&fake.requests), int32(4))
+	assert.Empty(t, failed)
+	assert.Equal(t, tagger.TagStats{Total: 20, Tagged: 2, Stopped: true}, wd14Tagger.Stats())
+}
+
+func TestWD14Tagger_IncrementalTagging(t *testing.T) {
+	// JSON 输出在文件自身中记录生成参数，TXT 输出记录在目录的清单文件中
+	for _, saveType := range []string{"json", "txt"} {
+		t.Run(saveType, func(t *testing.T) {
+			fake := newFakeTaggerServer(t, func(string) time.Duration { return 0 })
+			inputDir := writeTestImages(t, 4)
+			outputDir := t.TempDir()
+
+			run := func(request models.TagRequest) tagger.TagStats {
+				wd14Tagger, err := tagger.NewWD14Tagger()
+				require.NoError(t, err)
+				wd14Tagger.SetBaseURL(fake.URL)
+				request.InputDir = inputDir
+				request.OutputDir = outputDir
+				request.SaveType = saveType
+				require.NoError(t, wd14Tagger.GenerateTagsWithContext(context.Background(), &request, nil, nil))
+				return wd14Tagger.Stats()
+			}
+
+			assert.Equal(t, tagger.TagStats{Total: 4, Tagged: 4}, run(models.TagRequest{Threshold: 0.4}))
+			requests := atomic.LoadInt32(&fake.requests)
+			if saveType == "txt" {
+				assert.NoFileExists(t, filepath.Join(outputDir, "img_00.json"))
+				assert.FileExists(t, filepath.Join(outputDir, tagger.TagManifestFile))
+			}
+
+			// 相同参数再次运行时不再请求接口
+			assert.Equal(t, tagger.TagStats{Total: 4, UpToDate: 4}, run(models.TagRequest{Threshold: 0.4}))
+			assert.Equal(t, requests, atomic.LoadInt32(&fake.requests))
+
+			// 图片比标签文件新时重新生成
+			future := time.Now().Add(time.Hour)
+			require.NoError(t, os.Chtimes(filepath.Join(inputDir, "img_02.png"), future, future))
+			assert.Equal(t, tagger.TagStats{Total: 4, Tagged: 1, UpToDate: 3}, run(models.TagRequest{Threshold: 0.4}))
+			past := time.Now().Add(-time.Hour)
+			require.NoError(t, os.Chtimes(filepath.Join(inputDir, "img_02.png"), past, past))
+
+			// 阈值不同或设置 force 时全部重新生成
+			assert.Equal(t, tagger.TagStats{Total: 4, Tagged: 4}, run(models.TagRequest{Threshold: 0.5}))
+			assert.Equal(t, tagger.TagStats{Total: 4, Tagged: 4}, run(models.TagRequest{Threshold: 0.5, Force: true}))
+
+			// 开启描述模式后生成参数不同，全部重新生成
+			assert.Equal(t, tagger.TagStats{Total: 4, Tagged: 4}, run(models.TagRequest{Threshold: 0.5, Caption: true, CaptionBackend: tagger.BackendCLIP}))
+			assert.Equal(t, tagger.TagStats{Total: 4, UpToDate: 4}, run(models.TagRequest{Threshold: 0.5, Caption: true, CaptionBackend: tagger.BackendCLIP}))
+		})
+	}
+}
+
+func TestWD14Tagger_OutputModes(t *testing.T) {
+	fake := newFakeTaggerServer(t, func(string) time.Duration { return 0 })
+	base := t.TempDir()
+	for _, name := range []string{"a/img_00", "a/sub/img_01", "b/img_00"} {
+		path := filepath.Join(base, filepath.FromSlash(name)+".png")
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(filepath.Base(name)), 0644))
+	}
+	inputDirs := []string{filepath.Join(base, "a"), filepath.Join(base, "b")}
+
+	run := func(request models.TagRequest) string {
+		wd14Tagger, err := tagger.NewWD14Tagger()
+		require.NoError(t, err)
+		wd14Tagger.SetBaseURL(fake.URL)
+		request.InputDir = inputDirs
+		request.OutputDir = t.TempDir()
+		request.SaveType = "txt"
+		require.NoError(t, wd14Tagger.GenerateTagsWithContext(context.Background(), &request, nil, nil))
+		return request.OutputDir
+	}
+	assertFiles := func(dir string, names ...string) {
+		for _, name := range names {
+			assert.FileExists(t, filepath.Join(dir, filepath.FromSlash(name)))
+		}
+	}
+
+	// 按输入目录结构输出，不同输入目录的同名图片不会互相覆盖
+	assertFiles(run(models.TagRequest{OutputMode: tagger.OutputModeMirror}), "a/img_00.txt", "a/sub/img_01.txt", "b/img_00.txt")
+
+	// 数据集导出：N_概念名/ 下的图片和标签文件，子目录中的图片以相对路径作为前缀
+	dataset := run(models.TagRequest{OutputMode: tagger.OutputModeDataset, DatasetRepeats: 5})
+	assertFiles(dataset, "5_a/img_00.png", "5_a/img_00.txt", "5_a/sub_img_01.png", "5_a/sub_img_01.txt", "5_b/img_00.png", "5_b/img_00.txt")
+	content, err := os.ReadFile(filepath.Join(dataset, "5_a", "sub_img_01.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "img_01", string(content))
+	// 指定概念名时多个输入目录合并到同一个文件夹，以输入目录名作为前缀
+	assertFiles(run(models.TagRequest{OutputMode: tagger.OutputModeDataset, DatasetConcept: "miku"}), "10_miku/a_img_00.txt", "10_miku/a_sub_img_01.txt", "10_miku/b_img_00.txt")
+
+	// 写在图片旁边
+	run(models.TagRequest{OutputMode: tagger.OutputModeBeside})
+	assertFiles(base, "a/img_00.txt", "a/sub/img_01.txt", "b/img_00.txt")
+}
+
+func TestWD14Tagger_ExportFormats(t *testing.T) {
+	fake := newFakeTaggerServer(t, func(string) time.Duration { return 0 })
+	inputDir := writeTestImages(t, 2)
+
+	assert.Equal(t, `hatsune miku \(vocaloid\)`, tagger.FormatTag("hatsune_miku_(vocaloid)", true, true))
+	assert.Equal(t, "^_^", tagger.FormatTag("^_^", true, false))
+
+	run := func(saveType string) string {
+		wd14Tagger, err := tagger.NewWD14Tagger()
+		require.NoError(t, err)
+		wd14Tagger.SetBaseURL(fake.URL)
+		request := &models.TagRequest{
+			InputDir:          inputDir,
+			OutputDir:         t.TempDir(),
+			SaveType:          saveType,
+			TagOrder:          tagger.TagOrderScore,
+			ExtendTags:        []string{"hatsune_miku_(vocaloid)"},
+			ReplaceUnderscore: true,
+			EscapeParentheses: true,
+			ExportName:        "tags_task_1",
+		}
+		require.NoError(t, wd14Tagger.GenerateTagsWithContext(context.Background(), request, nil, nil))
+		return filepath.Join(request.OutputDir, "tags_task_1."+saveType)
+	}
+
+	// CSV：每行为 图片,标签,置信度，扩展标签没有置信度
+	file, err := os.Open(run(tagger.SaveTypeCSV))
+	require.NoError(t, err)
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 5)
+	assert.Equal(t, []string{"image", "tag", "confidence"}, rows[0])
+	assert.Equal(t, []string{filepath.Join(inputDir, "img_00.png"), "img 00", "0.9000"}, rows[1])
+	assert.Equal(t, []string{`hatsune miku \(vocaloid\)`, ""}, rows[2][1:])
+
+	// JSONL：每行为一张图片的结果
+	data, err := os.ReadFile(run(tagger.SaveTypeJSONL))
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	var line map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &line))
+	assert.Equal(t, `img 01, hatsune miku \(vocaloid\)`, line["tags_string"])
+	assert.Equal(t, "wd14tagger", line["analyzer"])
+	assert.Len(t, line["tags"], 2)
+}
+
+// stubTaggerBackend 返回固定置信度的测试标签后端
+type stubTaggerBackend struct{}
+
+func (stubTaggerBackend) Name() string { return "stub" }
+
+func (stubTaggerBackend) Capabilities() tagger.TaggerCapabilities {
+	return tagger.TaggerCapabilities{Confidences: true}
+}
+
+func (stubTaggerBackend) Interrogate(ctx context.Context, imageData string, options tagger.InterrogateOptions) (*tagger.InterrogateResult, error) {
+	return &tagger.InterrogateResult{
+		TagsData:   map[string]interface{}{"1girl": 0.95, "solo": 0.6, "blurry": 0.1},
+		RatingData: map[string]interface{}{},
+	}, nil
+}
+
+func TestWD14Tagger_CustomBackend(t *testing.T) {
+	tagger.RegisterTagger("stub", "测试后端", func(cfg tagger.TaggerBackendConfig) tagger.Tagger {
+		return stubTaggerBackend{}
+	})
+
+	names := tagger.TaggerNames()
+	for _, name := range []string{tagger.BackendWD14Tagger, tagger.BackendDeepBooru, tagger.BackendCLIP, tagger.BackendTaggerService, "stub"} {
+		assert.Contains(t, names, name)
+	}
+	for _, info := range tagger.AvailableTaggers() {
+		if info.Name == tagger.BackendCLIP {
+			assert.True(t, info.Capabilities.Caption)
+		}
+	}
+
+	wd14Tagger, err := tagger.NewWD14Tagger()
+	require.NoError(t, err)
+	request := &models.TagRequest{
+		InputDir:  writeTestImages(t, 1),
+		OutputDir: t.TempDir(),
+		SaveType:  tagger.SaveTypeTXT,
+		Analyzer:  "stub",
+		Threshold: 0.5,
+		TagOrder:  tagger.TagOrderScore,
+	}
+	require.NoError(t, wd14Tagger.GenerateTagsWithContext(context.Background(), request, nil, nil))
+
+	// 核心流程按阈值过滤后端返回的标签
+	data, err := os.ReadFile(filepath.Join(request.OutputDir, "img_00.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "1girl, solo", string(data))
+
+	request.Analyzer = "unknown"
+	assert.Error(t, wd14Tagger.GenerateTagsWithContext(context.Background(), request, nil, nil))
+}
+
+func TestWD14Tagger_CaptionMode(t *testing.T) {
+	fake := newFakeTaggerServer(t, func(string) time.Duration { return 0 })
+	inputDir := writeTestImages(t, 1)
+
+	assert.Equal(t, "a cat, 1girl", tagger.RenderCaptionTemplate("", "a cat", "1girl"))
+	assert.Equal(t, "a cat", tagger.RenderCaptionTemplate("{caption}, {tags}", "a cat", ""))
+
+	run := func(saveType string, captionFile bool) string {
+		wd14Tagger, err := tagger.NewWD14Tagger()
+		require.NoError(t, err)
+		wd14Tagger.SetBaseURL(fake.URL)
+		request := &models.TagRequest{
+			InputDir:        inputDir,
+			OutputDir:       t.TempDir(),
+			SaveType:        saveType,
+			TagOrder:        tagger.TagOrderScore,
+			Caption:         true,
+			CaptionFile:     captionFile,
+			CaptionTemplate: "{caption}. {tags}",
+		}
+		require.NoError(t, wd14Tagger.GenerateTagsWithContext(context.Background(), request, nil, nil))
+		return request.OutputDir
+	}
+
+	// 不单独保存时按模板写入 TXT 标签文件
+	data, err := os.ReadFile(filepath.Join(run(tagger.SaveTypeTXT, false), "img_00.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "a picture of img_00. img_00", string(data))
+
+	// 单独保存时标签文件只有标签，描述写入 JSON 和 .caption 文件
+	outputDir := run(tagger.SaveTypeJSON, true)
+	data, err = os.ReadFile(filepath.Join(outputDir, "img_00.caption"))
+	require.NoError(t, err)
+	assert.Equal(t, "a picture of img_00. img_00", string(data))
+	data, err = os.ReadFile(filepath.Join(outputDir, "img_00.json"))
+	require.NoError(t, err)
+	var tagData map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &tagData))
+	assert.Equal(t, "a picture of img_00", tagData["caption"])
+	assert.Equal(t, tagger.BackendCLIP, tagData["caption_backend"])
+	assert.Equal(t, "img_00", tagData["tags_string"])
+
+	// 描述服务使用单独配置的描述模型，而不是标签模型
+	captionModels := make(chan string, 1)
+	captionServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&req)
+		model, _ := req["model"].(string)
+		select {
+		case captionModels <- model:
+		default:
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"caption": "a cat"})
+	}))
+	defer captionServer.Close()
+	wd14Tagger, err := tagger.NewWD14TaggerWithModel("wd14-vit-v2")
+	require.NoError(t, err)
+	wd14Tagger.SetBaseURL(fake.URL)
+	wd14Tagger.SetCaptionService(captionServer.URL, "blip-large")
+	require.NoError(t, wd14Tagger.GenerateTagsWithContext(context.Background(), &models.TagRequest{
+		InputDir:       inputDir,
+		OutputDir:      t.TempDir(),
+		Caption:        true,
+		CaptionBackend: tagger.BackendCaptionService,
+	}, nil, nil))
+	assert.Equal(t, "blip-large", <-captionModels)
+}
@@ -0,0 +1,753 @@
+package tests
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pixiv-tailor/backend/internal/config"
+	"pixiv-tailor/backend/internal/events"
+	"pixiv-tailor/backend/internal/repository"
+	"pixiv-tailor/backend/internal/service"
+)
+
+// createStoredTask 直接写入存储创建任务（不经过 CreateTask，避免自动启动执行）
+func createStoredTask(t *testing.T, store repository.Storage, id, taskType, status string) *repository.Task {
+	task := &repository.Task{ID: id, Type: taskType, Status: status, Config: "{}", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, store.CreateTask(task))
+	return task
+}
+
+func TestTaskService_StatusEvents(t *testing.T) {
+	store := newTestStorage(t)
+	taskService := service.NewTaskService(store)
+	task := createStoredTask(t, store, "stat1234", "tag", "running")
+
+	// 多个订阅者都能收到同一事件
+	filter := events.Filter{Types: []events.EventType{events.TaskStatus}, TaskID: task.ID}
+	first := taskService.EventBus().Subscribe("first", filter, 0)
+	defer first.Unsubscribe()
+	second := taskService.EventBus().Subscribe("second", filter, 0)
+	defer second.Unsubscribe()
+
+	require.NoError(t, taskService.UpdateTaskStatus(task.ID, "completed"))
+
+	for _, sub := range []*events.Subscription{first, second} {
+		select {
+		case event := <-sub.C:
+			assert.Equal(t, task.ID, event.TaskID)
+			data, ok := event.Data.(*events.TaskStatusData)
+			require.True(t, ok)
+			assert.Equal(t, "completed", data.Status)
+		case <-time.After(time.Second):
+			t.Fatal("未收到任务状态事件")
+		}
+	}
+}
+
+func TestTaskService_ResumeKeepsCheckpoints(t *testing.T) {
+	store := newTestStorage(t)
+	taskService := service.NewTaskService(store)
+	task := createStoredTask(t, store, "resu1234", "train", "cancelled")
+
+	require.NoError(t, store.AddTaskCheckpoint(task.ID, "item-1"))
+
+	// 只有失败或取消的任务可以恢复
+	require.Error(t, taskService.ResumeTask("not-exist"))
+
+	require.NoError(t, taskService.ResumeTask(task.ID))
+	checkpoints, err := store.GetTaskCheckpoints(task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"item-1"}, checkpoints)
+
+	// 恢复日志已持久化
+	logs, total, err := taskService.GetTaskLogs(task.ID, "", 1, 0)
+	require.NoError(t, err)
+	assert.NotZero(t, total)
+	assert.NotEmpty(t, logs)
+}
+
+// echoExecutor 测试用执行器：把 message 重复 repeat 次写入任务结果
+type echoExecutor struct {
+	cleaned chan string
+}
+
+type echoConfig struct {
+	Message string `json:"message"`
+	Repeat  int    `json:"repeat"`
+}
+
+func (e *echoExecutor) Schema() *service.ConfigSchema {
+	minRepeat := 1.0
+	return &service.ConfigSchema{
+		Type: "object",
+		Properties: map[string]*service.SchemaProperty{
+			"message": {Type: "string"},
+			"repeat":  {Type: "integer", Default: 2, Minimum: &minRepeat},
+		},
+		Required: []string{"message"},
+	}
+}
+
+func (e *echoExecutor) Validate(config map[string]interface{}) error {
+	if config["message"] == "forbidden" {
+		return fmt.Errorf("message 不允许为 forbidden")
+	}
+	return nil
+}
+
+func (e *echoExecutor) Execute(ctx context.Context, task *repository.Task, config map[string]interface{}, reporter service.TaskReporter) error {
+	var cfg echoConfig
+	if err := service.DecodeConfig(config, &cfg); err != nil {
+		return err
+	}
+	reporter.Stage(50, "回显")
+	reporter.Result(map[string]interface{}{"message": strings.Repeat(cfg.Message, cfg.Repeat)})
+	return nil
+}
+
+func (e *echoExecutor) Cleanup(task *repository.Task) {
+	e.cleaned <- task.ID
+}
+
+func TestTaskService_RegisteredExecutor(t *testing.T) {
+	store := newTestStorage(t)
+	taskService := service.NewTaskService(store)
+
+	// 未注册执行器的类型在创建时即被拒绝
+	_, err := taskService.CreateTask("classify", "{}")
+	require.Error(t, err)
+
+	executor := &echoExecutor{cleaned: make(chan string, 1)}
+	taskService.RegisterExecutor("echo", executor)
+
+	// 配置校验失败时拒绝创建，并返回所有字段级错误
+	_, err = taskService.CreateTask("echo", `{"repeat":0.5,"extra":true}`)
+	var validationErr *service.ConfigValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []service.FieldError{
+		{Field: "message", Message: "必填字段"},
+		{Field: "repeat", Message: "必须是整数"},
+		{Field: "extra", Message: "未知字段"},
+	}, validationErr.Errors)
+
+	_, err = taskService.CreateTask("echo", `{"message":"forbidden"}`)
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "config", validationErr.Errors[0].Field)
+
+	// Schema 可按任务类型查询
+	schemas := taskService.GetConfigSchemas()
+	assert.Contains(t, schemas, "crawl")
+	assert.Contains(t, schemas, "echo")
+	repeats := schemas["tag"].Properties["dataset_repeats"]
+	assert.Equal(t, 10, repeats.Default)
+	assert.Equal(t, 1.0, *repeats.Minimum)
+	// 标签排序和跳过、追加标签的默认值取自配置中的 wd14tagger
+	taggerConfig := config.GetAIConfig().WD14Tagger
+	assert.Equal(t, taggerConfig.TagOrder, schemas["tag"].Properties["tag_order"].Default)
+	assert.Equal(t, taggerConfig.SkipTags, schemas["tag"].Properties["skip_tags"].Default)
+	assert.Equal(t, taggerConfig.ExtendTags, schemas["tag"].Properties["extend_tags"].Default)
+
+	task, err := taskService.CreateTask("echo", `{"message":"hello"}`)
+	require.NoError(t, err)
+
+	select {
+	case id := <-executor.cleaned:
+		assert.Equal(t, task.ID, id)
+	case <-time.After(2 * time.Second):
+		t.Fatal("执行器未执行完成")
+	}
+
+	finished, err := taskService.GetTask(task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "completed", finished.Status)
+	assert.Contains(t, finished.Result, "hellohello")
+	// 默认值在创建时填充并保存
+	assert.JSONEq(t, `{"message":"hello","repeat":2}`, finished.Config)
+}
+
+// blockingExecutor 上报一次日志后一直等待取消的执行器
+type blockingExecutor struct {
+	cleaned chan string
+}
+
+func (e *blockingExecutor) Schema() *service.ConfigSchema {
+	return &service.ConfigSchema{Type: "object", Properties: map[string]*service.SchemaProperty{}}
+}
+
+func (e *blockingExecutor) Validate(config map[string]interface{}) error {
+	return nil
+}
+
+func (e *blockingExecutor) Execute(ctx context.Context, task *repository.Task, config map[string]interface{}, reporter service.TaskReporter) error {
+	reporter.Log("info", "开始等待")
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (e *blockingExecutor) Cleanup(task *repository.Task) {
+	e.cleaned <- task.ID
+}
+
+func TestTaskService_TaskTimeout(t *testing.T) {
+	store := newTestStorage(t)
+	taskService := service.NewTaskService(store)
+
+	executor := &blockingExecutor{cleaned: make(chan string, 1)}
+	taskService.RegisterExecutor("block", executor)
+
+	// timeout_seconds 是通用字段，不受执行器 Schema 的未知字段限制
+	_, err := taskService.CreateTask("block", `{"timeout_seconds":-1}`)
+	var validationErr *service.ConfigValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "timeout_seconds", validationErr.Errors[0].Field)
+	assert.Contains(t, taskService.GetConfigSchemas()["block"].Properties, "timeout_seconds")
+
+	task, err := taskService.CreateTask("block", `{"timeout_seconds":0.2}`)
+	require.NoError(t, err)
+
+	select {
+	case id := <-executor.cleaned:
+		assert.Equal(t, task.ID, id)
+	case <-time.After(2 * time.Second):
+		t.Fatal("任务未按超时结束")
+	}
+
+	finished, err := taskService.GetTask(task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "failed", finished.Status)
+	assert.Equal(t, "任务执行超时", finished.ErrorMessage)
+	// 执行器上报日志时记录了心跳
+	assert.NotNil(t, finished.HeartbeatAt)
+}
+
+// flakyExecutor 前几次执行返回指定错误的执行器
+type flakyExecutor struct {
+	mu       sync.Mutex
+	failures int
+	err      error
+	done     chan string
+}
+
+func (e *flakyExecutor) Schema() *service.ConfigSchema {
+	return nil
+}
+
+func (e *flakyExecutor) Validate(config map[string]interface{}) error {
+	return nil
+}
+
+func (e *flakyExecutor) Execute(ctx context.Context, task *repository.Task, config map[string]interface{}, reporter service.TaskReporter) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.failures > 0 {
+		e.failures--
+		return e.err
+	}
+	return nil
+}
+
+func (e *flakyExecutor) Cleanup(task *repository.Task) {
+	e.done <- task.ID
+}
+
+func TestTaskService_AutomaticRetry(t *testing.T) {
+	store := newTestStorage(t)
+	taskService := service.NewTaskService(store)
+
+	// 网络错误按策略自动重试，第二次成功
+	executor := &flakyExecutor{failures: 1, err: fmt.Errorf("下载失败: dial tcp: connection refused"), done: make(chan string, 4)}
+	taskService.RegisterExecutor("flaky", executor)
+
+	_, err := taskService.CreateTask("flaky", `{"retry":{"max_attempts":"3"}}`)
+	var validationErr *service.ConfigValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "retry", validationErr.Errors[0].Field)
+
+	task, err := taskService.CreateTask("flaky", `{"retry":{"max_attempts":3,"backoff_seconds":0.05}}`)
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		current, err := taskService.GetTask(task.ID)
+		return err == nil && current.Status == "completed"
+	}, 10*time.Second, 20*time.Millisecond)
+
+	finished, err := taskService.GetTask(task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, finished.Attempt)
+	assert.Equal(t, 3, finished.MaxAttempts)
+	assert.Nil(t, finished.NextRetryAt)
+
+	attempts, err := taskService.GetTaskAttempts(task.ID)
+	require.NoError(t, err)
+	require.Len(t, attempts, 2)
+	assert.Equal(t, "failed", attempts[0].Status)
+	assert.Equal(t, "network", attempts[0].ErrorClass)
+	assert.Equal(t, "completed", attempts[1].Status)
+
+	// 不在 retry_on 中的错误分类不重试
+	executor.mu.Lock()
+	executor.failures, executor.err = 1, fmt.Errorf("参数错误")
+	executor.mu.Unlock()
+	task, err = taskService.CreateTask("flaky", `{"retry":{"max_attempts":3,"backoff_seconds":0.05}}`)
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		current, err := taskService.GetTask(task.ID)
+		return err == nil && current.Status == "failed"
+	}, 10*time.Second, 20*time.Millisecond)
+
+	attempts, err = taskService.GetTaskAttempts(task.ID)
+	require.NoError(t, err)
+	require.Len(t, attempts, 1)
+	assert.Equal(t, "unknown", attempts[0].ErrorClass)
+}
+
+func TestTaskService_RequeueDueRetries(t *testing.T) {
+	store := newTestStorage(t)
+
+	// 模拟进程在等待自动重试期间重启：任务仍为 pending，next_retry_at 保存在数据库中
+	past, future := time.Now().Add(-time.Minute), time.Now().Add(time.Hour)
+	for id, nextRetryAt := range map[string]time.Time{"due01234": past, "later123": future} {
+		task := createStoredTask(t, store, id, "flaky", "pending")
+		require.NoError(t, store.UpdateTaskAttempt(task.ID, 1, 3))
+		require.NoError(t, store.UpdateTaskNextRetry(task.ID, &nextRetryAt))
+	}
+
+	due, err := store.ListDueRetryTasks(time.Now())
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, "due01234", due[0].ID)
+
+	// 执行器注册后立即安排到期的重试
+	taskService := service.NewTaskService(store)
+	taskService.RegisterExecutor("flaky", &flakyExecutor{done: make(chan string, 4)})
+
+	require.Eventually(t, func() bool {
+		current, err := taskService.GetTask("due01234")
+		return err == nil && current.Status == "completed"
+	}, 5*time.Second, 20*time.Millisecond)
+
+	finished, err := taskService.GetTask("due01234")
+	require.NoError(t, err)
+	assert.Equal(t, 2, finished.Attempt)
+	assert.Nil(t, finished.NextRetryAt)
+
+	// 未到重试时间的任务继续等待
+	waiting, err := taskService.GetTask("later123")
+	require.NoError(t, err)
+	assert.Equal(t, "pending", waiting.Status)
+	assert.Equal(t, 1, waiting.Attempt)
+	require.NotNil(t, waiting.NextRetryAt)
+}
+
+func TestTaskService_SearchTasks(t *testing.T) {
+	store := newTestStorage(t)
+	taskService := service.NewTaskService(store)
+
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 12, 0, 0, 0, time.Local) }
+	tasks := []*repository.Task{
+		{ID: "task0001", Type: "crawl", Status: "completed", Config: `{"type":"tag","query":"初音ミク 100%"}`, CreatedAt: day(1)},
+		{ID: "task0002", Type: "crawl", Status: "failed", Config: `{"type":"user","user_id":987654}`, CreatedAt: day(2)},
+		{ID: "task0003", Type: "tag", Status: "completed", Config: `{"input_dir":"images/"}`, CreatedAt: day(3)},
+	}
+	for _, task := range tasks {
+		task.UpdatedAt = task.CreatedAt
+		require.NoError(t, store.CreateTask(task))
+	}
+
+	require.NoError(t, taskService.UpdateTaskLabels("task0001", []string{" miku ", "dataset", "miku", ""}))
+	require.NoError(t, taskService.UpdateTaskLabels("task0002", []string{"dataset"}))
+	require.NoError(t, taskService.UpdateTaskNotes("task0003", "第一批训练素材"))
+
+	labeled, err := taskService.GetTask("task0001")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dataset", "miku"}, labeled.Labels)
+
+	labels, err := taskService.ListTaskLabels()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"dataset": 2, "miku": 1}, labels)
+
+	ids := func(filter *repository.TaskFilter) []string {
+		found, total, err := taskService.SearchTasks(filter, 1, 20)
+		require.NoError(t, err)
+		assert.Equal(t, len(found), total)
+		result := make([]string, 0, len(found))
+		for _, task := range found {
+			result = append(result, task.ID)
+		}
+		return result
+	}
+
+	// 默认按创建时间倒序
+	assert.Equal(t, []string{"task0003", "task0002", "task0001"}, ids(nil))
+	assert.Equal(t, []string{"task0001", "task0002"}, ids(&repository.TaskFilter{Labels: []string{"dataset"}, SortOrder: "asc"}))
+	assert.Equal(t, []string{"task0001"}, ids(&repository.TaskFilter{Labels: []string{"dataset", "miku"}}))
+
+	// 关键字搜索配置内容、备注和标签，% 按字面匹配
+	assert.Equal(t, []string{"task0002"}, ids(&repository.TaskFilter{Search: "987654"}))
+	assert.Equal(t, []string{"task0001"}, ids(&repository.TaskFilter{Search: "初音ミク 100%"}))
+	assert.Empty(t, ids(&repository.TaskFilter{Search: "10%0"}))
+	assert.Equal(t, []string{"task0003"}, ids(&repository.TaskFilter{Search: "训练"}))
+	assert.Equal(t, []string{"task0001"}, ids(&repository.TaskFilter{Search: "miku", Type: "crawl"}))
+
+	// 只有日期的上限包含当天
+	from, to, err := service.ParseTaskTimeRange("2026-03-02", "2026-03-03")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"task0002", "task0003"}, ids(&repository.TaskFilter{CreatedFrom: from, CreatedTo: to, SortBy: "created_at", SortOrder: "asc"}))
+
+	_, _, err = service.ParseTaskTimeRange("2026-03-03", "2026-03-02")
+	assert.Error(t, err)
+	assert.Error(t, taskService.UpdateTaskLabels("missing1", []string{"x"}))
+}
+
+func TestTaskService_BatchTasks(t *testing.T) {
+	store := newTestStorage(t)
+	taskService := service.NewTaskService(store)
+	taskService.RegisterExecutor("echo", &echoExecutor{cleaned: make(chan string, 1)})
+
+	// 任意一个配置无效时不创建任何任务，字段错误带配置序号
+	_, err := taskService.CreateTasks("echo", []string{`{"message":"a"}`, `{"repeat":2}`}, 0)
+	var validationErr *service.ConfigValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []service.FieldError{{Field: "[1].message", Message: "必填字段"}}, validationErr.Errors)
+	_, total, err := taskService.SearchTasks(&repository.TaskFilter{Type: "echo"}, 1, 20)
+	require.NoError(t, err)
+	assert.Zero(t, total)
+
+	for _, id := range []string{"batch001", "batch002", "batch003"} {
+		createStoredTask(t, store, id, "train", "pending")
+	}
+	require.NoError(t, taskService.UpdateTaskLabels("batch001", []string{"sweep"}))
+	require.NoError(t, taskService.UpdateTaskLabels("batch002", []string{"sweep"}))
+
+	// 筛选条件不能为空
+	_, err = taskService.FindTaskIDs(&repository.TaskFilter{})
+	assert.Error(t, err)
+	_, err = taskService.BatchTasks("archive", []string{"batch001"}, 0, false)
+	assert.Error(t, err)
+
+	results, err := taskService.BatchTasks(service.BatchActionPrioritize, []string{"batch003", "batch003"}, 5, false)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	sorted, _, err := taskService.SearchTasks(&repository.TaskFilter{Type: "train", SortBy: "priority"}, 1, 20)
+	require.NoError(t, err)
+	assert.Equal(t, "batch003", sorted[0].ID)
+	assert.Equal(t, 5, sorted[0].Priority)
+
+	ids, err := taskService.FindTaskIDs(&repository.TaskFilter{Labels: []string{"sweep"}})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"batch001", "batch002"}, ids)
+
+	// 单个任务失败不影响其他任务
+	results, err = taskService.BatchTasks(service.BatchActionCancel, append(ids, "missing1"), 0, false)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	for _, result := range results {
+		assert.Equal(t, result.TaskID != "missing1", result.Success, result.TaskID)
+	}
+	for _, id := range ids {
+		task, err := taskService.GetTask(id)
+		require.NoError(t, err)
+		assert.Equal(t, "cancelled", task.Status)
+	}
+
+	results, err = taskService.BatchTasks(service.BatchActionDelete, ids, 0, false)
+	require.NoError(t, err)
+	assert.True(t, results[0].Success && results[1].Success)
+	_, total, err = taskService.SearchTasks(&repository.TaskFilter{Type: "train"}, 1, 20)
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+}
+
+// sweepExecutor 带生成参数 Schema 的阻塞执行器
+type sweepExecutor struct {
+	blockingExecutor
+}
+
+func (e *sweepExecutor) Schema() *service.ConfigSchema {
+	return &service.ConfigSchema{
+		Type: "object",
+		Properties: map[string]*service.SchemaProperty{
+			"prompt":    {Type: "string"},
+			"cfg_scale": {Type: "number"},
+			"steps":     {Type: "integer"},
+		},
+	}
+}
+
+func TestTaskService_SweepAndClone(t *testing.T) {
+	store := newTestStorage(t)
+	taskService := service.NewTaskService(store)
+	taskService.RegisterExecutor("sweep", &sweepExecutor{blockingExecutor{cleaned: make(chan string, 1)}})
+
+	base := map[string]interface{}{"prompt": "1girl"}
+	_, _, err := taskService.CreateSweep("sweep", base, map[string][]interface{}{"steps": {}}, 0)
+	assert.Error(t, err)
+
+	// cfg_scale × steps 展开为 3×2 个任务，排在前面的字段变化最慢
+	groupID, tasks, err := taskService.CreateSweep("sweep", base, map[string][]interface{}{
+		"cfg_scale": {5, 7, 9},
+		"steps":     {20, 30},
+	}, 0)
+	require.NoError(t, err)
+	require.Len(t, tasks, 6)
+	assert.JSONEq(t, `{"prompt":"1girl","cfg_scale":5,"steps":20}`, tasks[0].Config)
+	assert.JSONEq(t, `{"prompt":"1girl","cfg_scale":5,"steps":30}`, tasks[1].Config)
+	assert.JSONEq(t, `{"prompt":"1girl","cfg_scale":9,"steps":30}`, tasks[5].Config)
+
+	// 同类型任务串行执行：第一个运行，其余等待
+	group, err := taskService.GetTaskGroup(groupID)
+	require.NoError(t, err)
+	assert.Equal(t, 6, group.Total)
+	assert.Equal(t, "running", group.Status)
+	assert.Equal(t, map[string]int{"running": 1, "pending": 5}, group.StatusCounts)
+
+	require.NoError(t, taskService.UpdateTaskLabels(tasks[0].ID, []string{"sweep"}))
+	clone, err := taskService.CloneTask(tasks[0].ID, map[string]interface{}{"steps": 50, "cfg_scale": nil})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"prompt":"1girl","steps":50}`, clone.Config)
+	assert.Equal(t, []string{"sweep"}, clone.Labels)
+	assert.Empty(t, clone.GroupID)
+
+	_, err = taskService.CloneTask(tasks[0].ID, map[string]interface{}{"steps": "many"})
+	var validationErr *service.ConfigValidationError
+	require.ErrorAs(t, err, &validationErr)
+
+	// 取消整个任务组后汇总状态为 cancelled
+	ids, err := taskService.FindTaskIDs(&repository.TaskFilter{Type: "sweep"})
+	require.NoError(t, err)
+	assert.Len(t, ids, 7)
+	_, err = taskService.BatchTasks(service.BatchActionCancel, ids, 0, false)
+	require.NoError(t, err)
+	group, err = taskService.GetTaskGroup(groupID)
+	require.NoError(t, err)
+	assert.Equal(t, "cancelled", group.Status)
+	assert.Equal(t, 0, group.Progress)
+
+	_, err = taskService.GetTaskGroup("missing1")
+	assert.Error(t, err)
+}
+
+// artifactExecutor 写入一个文件并记录为产出文件的执行器
+type artifactExecutor struct {
+	path string
+	done chan string
+}
+
+func (e *artifactExecutor) Schema() *service.ConfigSchema {
+	return &service.ConfigSchema{Type: "object", Properties: map[string]*service.SchemaProperty{}}
+}
+
+func (e *artifactExecutor) Validate(config map[string]interface{}) error {
+	return nil
+}
+
+func (e *artifactExecutor) Execute(ctx context.Context, task *repository.Task, config map[string]interface{}, reporter service.TaskReporter) error {
+	if err := os.WriteFile(e.path, []byte("1girl, solo"), 0644); err != nil {
+		return err
+	}
+	reporter.Artifact(e.path, service.ArtifactRoleTag)
+	// 文件不存在时只记录警告，不影响任务
+	reporter.Artifact(e.path+".missing", service.ArtifactRoleSidecar)
+	// 结果记录自动关联到当前任务
+	reporter.CrawlResult(&repository.CrawlResult{ID: task.ID + "_1", URL: "https://www.pixiv.net/artworks/1"})
+	reporter.GeneratedImage(&repository.GeneratedImage{ID: task.ID + "_1", Prompt: "1girl"})
+	return nil
+}
+
+func (e *artifactExecutor) Cleanup(task *repository.Task) {
+	e.done <- task.ID
+}
+
+func TestTaskService_TaskArtifacts(t *testing.T) {
+	store := newTestStorage(t)
+	taskService := service.NewTaskService(store)
+	executor := &artifactExecutor{path: filepath.Join(t.TempDir(), "artworks_1_p01.txt"), done: make(chan string, 1)}
+	taskService.RegisterExecutor("artifact", executor)
+
+	task, err := taskService.CreateTask("artifact", "{}")
+	require.NoError(t, err)
+	select {
+	case <-executor.done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("执行器未执行完成")
+	}
+
+	artifacts, err := taskService.ListTaskArtifacts(task.ID, "")
+	require.NoError(t, err)
+	require.Len(t, artifacts, 1)
+	artifact := artifacts[0]
+	assert.Equal(t, service.ArtifactRoleTag, artifact.Role)
+	assert.Equal(t, int64(len("1girl, solo")), artifact.Size)
+	assert.Equal(t, "text/plain; charset=utf-8", artifact.MimeType)
+	sum := sha256.Sum256([]byte("1girl, solo"))
+	assert.Equal(t, hex.EncodeToString(sum[:]), artifact.SHA256)
+	assert.Equal(t, executor.path, service.ResolveArtifactPath(artifact.Path))
+
+	images, err := taskService.ListTaskArtifacts(task.ID, service.ArtifactRoleImage)
+	require.NoError(t, err)
+	assert.Empty(t, images)
+
+	_, path, err := taskService.GetTaskArtifact(task.ID, artifact.ID)
+	require.NoError(t, err)
+	assert.Equal(t, executor.path, path)
+
+	// 文件被删除后无法下载
+	require.NoError(t, os.Remove(executor.path))
+	_, _, err = taskService.GetTaskArtifact(task.ID, artifact.ID)
+	assert.Error(t, err)
+	_, _, err = taskService.GetTaskArtifact("missing1", artifact.ID)
+	assert.Error(t, err)
+
+	results, err := store.GetCrawlResults(10, 0)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, task.ID, results[0].TaskID)
+
+	// 删除任务时一并删除关联的结果记录
+	report, err := taskService.DeleteTaskWithOptions(task.ID, false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), report.Records.CrawlResults)
+	assert.Equal(t, int64(1), report.Records.GeneratedImages)
+}
+
+func TestTaskService_Retention(t *testing.T) {
+	store := newTestStorage(t)
+	taskService := service.NewTaskService(store)
+
+	now := time.Now()
+	stored := func(id, taskType, status string, age time.Duration) {
+		task := &repository.Task{ID: id, Type: taskType, Status: status, Config: "{}", CreatedAt: now.Add(-age), UpdatedAt: now}
+		require.NoError(t, store.CreateTask(task))
+	}
+	stored("crawl001", "crawl", "completed", time.Hour)
+	stored("crawl002", "crawl", "completed", 2*time.Hour)
+	stored("crawl003", "crawl", "failed", 3*time.Hour)
+	stored("tag00001", "tag", "completed", 10*24*time.Hour)
+	stored("tag00002", "tag", "completed", 20*24*time.Hour)
+	stored("tag00003", "tag", "running", 30*24*time.Hour)
+	require.NoError(t, taskService.SetTaskStarred("tag00002", true))
+
+	output := filepath.Join(t.TempDir(), "artworks_1_p0.txt")
+	require.NoError(t, os.WriteFile(output, []byte("1girl"), 0644))
+	require.NoError(t, store.AddTaskArtifact(&repository.TaskArtifact{TaskID: "tag00001", Path: output, Size: 5, SHA256: sha256Hex("1girl"), Role: service.ArtifactRoleTag, CreatedAt: now}))
+
+	policy := service.DefaultRetentionPolicy()
+	policy.KeepLastPerType = 2
+	policy.MaxAgeDays = 7
+
+	// 预览不删除任何内容；加星标和未结束的任务不会被选中
+	report, err := taskService.RunRetention(policy, true)
+	require.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, 0, report.Deleted)
+	require.Len(t, report.Items, 2)
+	assert.Equal(t, "crawl003", report.Items[0].TaskID)
+	assert.Equal(t, service.RetentionReasonKeepLast, report.Items[0].Reason)
+	assert.Equal(t, "tag00001", report.Items[1].TaskID)
+	assert.Equal(t, service.RetentionReasonMaxAge, report.Items[1].Reason)
+	assert.Equal(t, int64(5), report.FreedSize)
+	_, err = taskService.GetTask("crawl003")
+	require.NoError(t, err)
+
+	report, err = taskService.RunRetention(policy, false)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Deleted)
+	_, err = taskService.GetTask("tag00001")
+	assert.Error(t, err)
+	_, err = os.Stat(output)
+	assert.True(t, os.IsNotExist(err))
+	starred, err := taskService.GetTask("tag00002")
+	require.NoError(t, err)
+	assert.True(t, starred.Starred)
+
+	policy.IntervalMinutes = 0
+	_, err = taskService.RunRetention(policy, true)
+	assert.Error(t, err)
+}
+
+func TestTaskService_DeleteTaskFiles(t *testing.T) {
+	store := newTestStorage(t)
+	taskService := service.NewTaskService(store)
+
+	dir := t.TempDir()
+	for _, id := range []string{"keep1234", "plain123", "purge123"} {
+		createStoredTask(t, store, id, "tag", "completed")
+		path := filepath.Join(dir, id+".txt")
+		require.NoError(t, os.WriteFile(path, []byte("1girl"), 0644))
+		require.NoError(t, store.AddTaskArtifact(&repository.TaskArtifact{TaskID: id, Path: path, Size: 5, SHA256: sha256Hex("1girl"), Role: service.ArtifactRoleTag, CreatedAt: time.Now()}))
+	}
+
+	// 保留文件时只删除记录
+	report, err := taskService.DeleteTaskWithOptions("keep1234", false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), report.Records.Tasks)
+	assert.Equal(t, int64(1), report.Records.Artifacts)
+	assert.Empty(t, report.RemovedFiles)
+	assert.FileExists(t, filepath.Join(dir, "keep1234.txt"))
+
+	// DeleteTask 同样默认保留文件
+	require.NoError(t, taskService.DeleteTask("plain123"))
+	assert.FileExists(t, filepath.Join(dir, "plain123.txt"))
+
+	report, err = taskService.DeleteTaskWithOptions("purge123", true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.ToSlash(filepath.Join(dir, "purge123.txt"))}, report.RemovedFiles)
+	assert.Equal(t, int64(5), report.FreedSize)
+	assert.NoFileExists(t, filepath.Join(dir, "purge123.txt"))
+
+	_, err = taskService.DeleteTaskWithOptions("purge123", true)
+	assert.Error(t, err)
+}
+
+func TestTaskService_DeleteTaskKeepsSharedFiles(t *testing.T) {
+	store := newTestStorage(t)
+	taskService := service.NewTaskService(store)
+
+	// 两个标签任务写入同一个输出文件，第二个任务覆盖了第一个任务的内容
+	output := filepath.Join(t.TempDir(), "artworks_1_p0.txt")
+	createStoredTask(t, store, "tagfirst", "tag", "completed")
+	createStoredTask(t, store, "tagsecnd", "tag", "completed")
+	require.NoError(t, store.AddTaskArtifact(&repository.TaskArtifact{TaskID: "tagfirst", Path: output, Size: 5, SHA256: sha256Hex("1girl"), Role: service.ArtifactRoleTag, CreatedAt: time.Now()}))
+	require.NoError(t, os.WriteFile(output, []byte("1girl, solo"), 0644))
+	require.NoError(t, store.AddTaskArtifact(&repository.TaskArtifact{TaskID: "tagsecnd", Path: output, Size: 11, SHA256: sha256Hex("1girl, solo"), Role: service.ArtifactRoleTag, CreatedAt: time.Now()}))
+
+	// 文件仍被第二个任务记录，且内容已不是第一个任务写入的，删除第一个任务时保留
+	report, err := taskService.DeleteTaskWithOptions("tagfirst", true)
+	require.NoError(t, err)
+	assert.Empty(t, report.RemovedFiles)
+	assert.Equal(t, []string{filepath.ToSlash(output)}, report.KeptFiles)
+	assert.FileExists(t, output)
+
+	// 第二个任务是唯一记录者且内容一致，删除时才清理文件
+	report, err = taskService.DeleteTaskWithOptions("tagsecnd", true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.ToSlash(output)}, report.RemovedFiles)
+	assert.NoFileExists(t, output)
+
+	// 内容被外部修改过的文件不删除
+	createStoredTask(t, store, "tagedit1", "tag", "completed")
+	require.NoError(t, os.WriteFile(output, []byte("1girl, edited"), 0644))
+	require.NoError(t, store.AddTaskArtifact(&repository.TaskArtifact{TaskID: "tagedit1", Path: output, Size: 5, SHA256: sha256Hex("1girl"), Role: service.ArtifactRoleTag, CreatedAt: time.Now()}))
+	report, err = taskService.DeleteTaskWithOptions("tagedit1", true)
+	require.NoError(t, err)
+	assert.Empty(t, report.RemovedFiles)
+	assert.FileExists(t, output)
+}
+
+// sha256Hex 计算内容的 sha256（十六进制）
+func sha256Hex(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pixiv-tailor/backend/internal/repository"
+)
+
+// newTestStorage 在临时目录中创建全新的 SQLite 存储
+func newTestStorage(t *testing.T) *repository.SQLiteStorage {
+	store, err := repository.NewSQLiteStorage(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestTaskStorage_CheckpointsAndFailedItems(t *testing.T) {
+	store := newTestStorage(t)
+
+	task := &repository.Task{ID: "abcd1234", Type: "crawl", Status: "failed", Config: "{}", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, store.CreateTask(task))
+
+	// 重复记录同一条目只保留一条
+	require.NoError(t, store.AddTaskCheckpoint(task.ID, "https://example.com/1.jpg"))
+	require.NoError(t, store.AddTaskCheckpoint(task.ID, "https://example.com/1.jpg"))
+	require.NoError(t, store.AddTaskCheckpoint(task.ID, "https://example.com/2.jpg"))
+
+	checkpoints, err := store.GetTaskCheckpoints(task.ID)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"https://example.com/1.jpg", "https://example.com/2.jpg"}, checkpoints)
+
+	require.NoError(t, store.AddTaskFailedItem(&repository.TaskFailedItem{
+		TaskID: task.ID, Item: "https://example.com/3.jpg", Name: "artworks_3_p01.jpg", Error: "timeout", CreatedAt: time.Now(),
+	}))
+	items, err := store.GetTaskFailedItems(task.ID)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "artworks_3_p01.jpg", items[0].Name)
+	assert.Equal(t, "timeout", items[0].Error)
+
+	require.NoError(t, store.RemoveTaskFailedItem(task.ID, "https://example.com/3.jpg"))
+	items, err = store.GetTaskFailedItems(task.ID)
+	require.NoError(t, err)
+	assert.Empty(t, items)
+
+	// 删除任务时一并删除断点
+	require.NoError(t, store.DeleteTask(task.ID))
+	checkpoints, err = store.GetTaskCheckpoints(task.ID)
+	require.NoError(t, err)
+	assert.Empty(t, checkpoints)
+}
+
+func TestTaskStorage_TaskLogs(t *testing.T) {
+	store := newTestStorage(t)
+
+	task := &repository.Task{ID: "logs1234", Type: "tag", Status: "running", Config: "{}", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, store.CreateTask(task))
+
+	levels := []string{"info", "warning", "info", "error", "info"}
+	for i, level := range levels {
+		log := &repository.TaskLog{TaskID: task.ID, Level: level, Message: string(rune('a' + i)), CreatedAt: time.Now()}
+		require.NoError(t, store.AddTaskLog(log))
+		assert.NotZero(t, log.ID)
+	}
+
+	// 按时间顺序分页
+	logs, err := store.ListTaskLogs(task.ID, "", 2, 2)
+	require.NoError(t, err)
+	require.Len(t, logs, 2)
+	assert.Equal(t, "c", logs[0].Message)
+	assert.Equal(t, "d", logs[1].Message)
+
+	// 按级别筛选，limit <= 0 返回全部
+	logs, err = store.ListTaskLogs(task.ID, "info", 0, 0)
+	require.NoError(t, err)
+	assert.Len(t, logs, 3)
+
+	count, err := store.CountTaskLogs(task.ID, "")
+	require.NoError(t, err)
+	assert.Equal(t, len(levels), count)
+
+	// 清理任务后日志一并删除
+	_, err = store.CleanupAllTasks()
+	require.NoError(t, err)
+	count, err = store.CountTaskLogs(task.ID, "")
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestTaskStorage_DeleteTaskRecords(t *testing.T) {
+	store := newTestStorage(t)
+
+	task := &repository.Task{ID: "del12345", Type: "crawl", Status: "completed", Config: "{}", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	require.NoError(t, store.CreateTask(task))
+	require.NoError(t, store.AddTaskCheckpoint(task.ID, "https://example.com/1.jpg"))
+	require.NoError(t, store.SetTaskLabels(task.ID, []string{"dataset"}))
+	require.NoError(t, store.AddCrawlResult(&repository.CrawlResult{ID: "r1", TaskID: task.ID, URL: "https://example.com/1", CreatedAt: time.Now()}))
+	// 旧版本的结果没有 task_id，按ID前缀关联到任务
+	require.NoError(t, store.AddCrawlResult(&repository.CrawlResult{ID: task.ID + "_2", URL: "https://example.com/2", CreatedAt: time.Now()}))
+	require.NoError(t, store.AddCrawlResult(&repository.CrawlResult{ID: "other_1", URL: "https://example.com/3", CreatedAt: time.Now()}))
+	require.NoError(t, store.AddGeneratedImage(&repository.GeneratedImage{ID: "g1", TaskID: task.ID, Prompt: "1girl", CreatedAt: time.Now()}))
+
+	results, err := store.GetCrawlResults(10, 0)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	deleted, err := store.DeleteTaskRecords(task.ID)
+	require.NoError(t, err)
+	assert.Equal(t, &repository.TaskDeleteResult{Tasks: 1, Checkpoints: 1, Labels: 1, CrawlResults: 2, GeneratedImages: 1}, deleted)
+
+	results, err = store.GetCrawlResults(10, 0)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "other_1", results[0].ID)
+	count, err := store.CountGeneratedImages()
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	// 任务不存在时不删除任何记录
+	deleted, err = store.DeleteTaskRecords(task.ID)
+	require.NoError(t, err)
+	assert.Zero(t, deleted.Tasks)
+}
@@ -1,7 +1,11 @@
 package errors
 
 import (
+	"context"
+	stderrors "errors"
 	"fmt"
+	"net"
+	"strings"
 )
 
 // ErrorCode 错误代码类型
@@ -52,6 +56,7 @@ type PixivTailorError struct {
 	Code    ErrorCode `json:"code"`
 	Message string    `json:"message"`
 	Details string    `json:"details,omitempty"`
+	cause   error     // 被包装的原始错误，供 errors.As/Is 和错误分类使用
 }
 
 // Error 实现 error 接口
@@ -62,6 +67,11 @@ func (e *PixivTailorError) Error() string {
 	return fmt.Sprintf("[%d] %s", e.Code, e.Message)
 }
 
+// Unwrap 返回被包装的原始错误
+func (e *PixivTailorError) Unwrap() error {
+	return e.cause
+}
+
 // NewError 创建新错误
 func NewError(code ErrorCode, message string) *PixivTailorError {
 	return &PixivTailorError{
@@ -98,7 +108,9 @@ func Wrap(err error, code ErrorCode, message string) *PixivTailorError {
 	if err != nil {
 		details = err.Error()
 	}
-	return NewErrorWithDetails(code, message, details)
+	wrapped := NewErrorWithDetails(code, message, details)
+	wrapped.cause = err
+	return wrapped
 }
 
 // 预定义错误
@@ -141,3 +153,84 @@ var (
 	ErrStorageSave    = NewError(ErrCodeStorageSave, "存储保存失败")
 	ErrStorageDelete  = NewError(ErrCodeStorageDelete, "存储删除失败")
 )
+
+// ErrorClass 错误分类，用于判断失败的任务是否值得自动重试
+type ErrorClass string
+
+const (
+	ClassNetwork  ErrorClass = "network"  // 网络错误（连接失败、连接重置、DNS 解析失败等）
+	ClassTimeout  ErrorClass = "timeout"  // 超时
+	ClassAuth     ErrorClass = "auth"     // 认证或权限错误
+	ClassInvalid  ErrorClass = "invalid"  // 参数或配置错误，重试也不会成功
+	ClassInternal ErrorClass = "internal" // 内部错误
+	ClassUnknown  ErrorClass = "unknown"  // 无法分类
+)
+
+// Classes 所有错误分类
+func Classes() []ErrorClass {
+	return []ErrorClass{ClassNetwork, ClassTimeout, ClassAuth, ClassInvalid, ClassInternal, ClassUnknown}
+}
+
+// codeClasses 错误代码对应的分类
+var codeClasses = map[ErrorCode]ErrorClass{
+	ErrCodeTimeout:        ClassTimeout,
+	ErrCodeCrawlTimeout:   ClassTimeout,
+	ErrCodeCrawlFailed:    ClassNetwork,
+	ErrCodeStorageConnect: ClassNetwork,
+	ErrCodeUnauthorized:   ClassAuth,
+	ErrCodeForbidden:      ClassAuth,
+	ErrCodeCrawlAuth:      ClassAuth,
+	ErrCodeInvalidParam:   ClassInvalid,
+	ErrCodeNotFound:       ClassInvalid,
+	ErrCodeTaskNotFound:   ClassInvalid,
+	ErrCodeConfigLoad:     ClassInvalid,
+	ErrCodeConfigParse:    ClassInvalid,
+	ErrCodeConfigValidate: ClassInvalid,
+	ErrCodeInternal:       ClassInternal,
+}
+
+// 错误信息中的关键字（很多错误经过 fmt.Errorf("%v") 后只剩下文本）
+var (
+	timeoutKeywords = []string{"timeout", "timed out", "deadline exceeded", "超时"}
+	networkKeywords = []string{
+		"connection refused", "connection reset", "no such host", "network is unreachable",
+		"broken pipe", "unexpected eof", "tls handshake", "proxyconnect", "too many requests",
+	}
+)
+
+// Classify 判断错误的分类：优先使用错误链中的超时/网络错误和错误代码，其次根据错误信息判断
+func Classify(err error) ErrorClass {
+	if err == nil {
+		return ""
+	}
+
+	if stderrors.Is(err, context.DeadlineExceeded) {
+		return ClassTimeout
+	}
+	var netErr net.Error
+	if stderrors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return ClassTimeout
+		}
+		return ClassNetwork
+	}
+	var pixivErr *PixivTailorError
+	if stderrors.As(err, &pixivErr) {
+		if class, exists := codeClasses[pixivErr.Code]; exists {
+			return class
+		}
+	}
+
+	message := strings.ToLower(err.Error())
+	for _, keyword := range timeoutKeywords {
+		if strings.Contains(message, keyword) {
+			return ClassTimeout
+		}
+	}
+	for _, keyword := range networkKeywords {
+		if strings.Contains(message, keyword) {
+			return ClassNetwork
+		}
+	}
+	return ClassUnknown
+}
@@ -175,15 +175,32 @@ type TrainedModel struct {
 
 // TagRequest 标签请求
 type TagRequest struct {
-	InputDir   interface{} `json:"input_dir"` // 可以是字符串或字符串数组
-	OutputDir  string      `json:"output_dir"`
-	Analyzer   string      `json:"analyzer"`
-	Model      string      `json:"model,omitempty"` // 模型名称（可选）
-	SkipTags   []string    `json:"skip_tags"`
-	ExtendTags []string    `json:"extend_tags"`
-	TagOrder   string      `json:"tag_order"`
-	SaveType   string      `json:"save_type"`
-	Limit      int         `json:"limit"`
+	InputDir           interface{}        `json:"input_dir"` // 可以是字符串或字符串数组
+	OutputDir          string             `json:"output_dir"`
+	Analyzer           string             `json:"analyzer"`
+	Model              string             `json:"model,omitempty"`               // 模型名称（可选）
+	SkipTags           []string           `json:"skip_tags"`                     // 跳过的标签（支持 * ? 通配符和 /正则/）
+	ExtendTags         []string           `json:"extend_tags"`                   // 扩展标签
+	ExtendPosition     string             `json:"extend_position,omitempty"`     // 扩展标签的位置：append（默认）或 prepend
+	TagOrder           string             `json:"tag_order"`                     // 排序方式：score、alphabet、character
+	Threshold          float64            `json:"threshold,omitempty"`           // 置信度阈值，0 表示使用配置
+	CharacterThreshold float64            `json:"character_threshold,omitempty"` // 角色标签阈值，0 表示使用配置
+	CategoryThresholds map[string]float64 `json:"category_thresholds,omitempty"` // 按标签类别设置的阈值
+	SaveType           string             `json:"save_type"`
+	Limit              int                `json:"limit"`
+	Concurrency        int                `json:"concurrency,omitempty"`        // 同时识别的图片数量，0 表示使用配置
+	Force              bool               `json:"force,omitempty"`              // 强制重新生成已是最新的标签文件
+	OutputMode         string             `json:"output_mode,omitempty"`        // 输出方式：flat（默认）、mirror、beside、dataset
+	DatasetRepeats     int                `json:"dataset_repeats,omitempty"`    // 数据集导出时文件夹名中的重复次数，0 表示默认值
+	DatasetConcept     string             `json:"dataset_concept,omitempty"`    // 数据集导出时的概念名，为空时使用输入目录名
+	ReplaceUnderscore  bool               `json:"replace_underscore,omitempty"` // 标签中的下划线替换为空格（颜文字除外）
+	EscapeParentheses  bool               `json:"escape_parentheses,omitempty"` // 转义标签中的括号，便于直接用作提示词
+	ExportName         string             `json:"export_name,omitempty"`        // CSV、JSONL 汇总文件名（不含扩展名），为空时为 tags
+	Caption            bool               `json:"caption,omitempty"`            // 描述模式：另外生成自然语言描述
+	CaptionBackend     string             `json:"caption_backend,omitempty"`    // 描述后端（clip 或 caption_service），为空时为 clip
+	CaptionFile        bool               `json:"caption_file,omitempty"`       // 描述单独保存为 .caption 文件（否则按模板写入 TXT 标签文件）
+	CaptionTemplate    string             `json:"caption_template,omitempty"`   // 描述与标签的组合模板，支持 {caption} 和 {tags}
+	ImageFiles         []string           `json:"image_files,omitempty"`        // 指定要处理的图片文件（设置后不再扫描输入目录，用于重试失败条目）
 }
 
 // GetInputDirs 获取输入目录列表（统一返回数组）
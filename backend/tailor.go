@@ -363,6 +363,83 @@ func main() {
 				},
 				Action: commands.TagAction,
 			},
+			{
+				Name:  "tags",
+				Usage: "管理标签文件",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "edit",
+						Usage: "批量编辑标签目录中的 TXT 标签文件",
+						Description: `对标签目录执行批量编辑，每次编辑记录编辑日志，可以撤销。
+
+示例:
+  # 重命名标签（/正则/ 时按正则替换）
+  pixiv-tailor tags edit --dir "tags/task_1" --op rename --from "blue_hair" --to "blue hair"
+
+  # 为包含 1girl 且不包含 solo 的文件添加标签
+  pixiv-tailor tags edit --op add --tag "masterpiece" --query "1girl" --query "-solo"
+
+  # 合并同义标签
+  pixiv-tailor tags edit --op merge --tag "smile" --tag "smiling" --to "smile"
+
+  # 将触发词移到最前面（先预览）
+  pixiv-tailor tags edit --op front --tag "mychar" --dry-run
+
+  # 撤销最近一次编辑
+  pixiv-tailor tags edit --op undo`,
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:    "dir",
+								Aliases: []string{"d"},
+								Usage:   "标签目录（默认 tags/）",
+								Value:   "",
+							},
+							&cli.StringFlag{
+								Name:     "op",
+								Usage:    "操作 (rename/add/remove/merge/front/undo)",
+								Required: true,
+							},
+							&cli.StringSliceFlag{
+								Name:    "tag",
+								Aliases: []string{"t"},
+								Usage:   "标签或匹配规则，可多次指定",
+							},
+							&cli.StringFlag{
+								Name:  "from",
+								Usage: "rename 的源标签",
+							},
+							&cli.StringFlag{
+								Name:  "to",
+								Usage: "rename、merge 的目标标签",
+							},
+							&cli.StringFlag{
+								Name:  "position",
+								Usage: "add 的位置 (append/prepend)",
+								Value: "append",
+							},
+							&cli.StringSliceFlag{
+								Name:    "query",
+								Aliases: []string{"q"},
+								Usage:   "只编辑匹配的文件，以 - 开头表示不包含，可多次指定",
+							},
+							&cli.BoolFlag{
+								Name:    "recursive",
+								Aliases: []string{"r"},
+								Usage:   "包含子目录",
+							},
+							&cli.BoolFlag{
+								Name:  "dry-run",
+								Usage: "只预览修改",
+							},
+							&cli.StringFlag{
+								Name:  "journal",
+								Usage: "undo 时撤销的编辑日志ID（默认最近一次）",
+							},
+						},
+						Action: commands.TagsEditAction,
+					},
+				},
+			},
 			{
 				Name:    "classify",
 				Aliases: []string{"classify"},
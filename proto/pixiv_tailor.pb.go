@@ -578,6 +578,11 @@ type Task struct {
 	CreatedAt     string                 `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
 	UpdatedAt     string                 `protobuf:"bytes,6,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
 	ErrorMessage  string                 `protobuf:"bytes,7,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
+	Attempt       int32                  `protobuf:"varint,8,opt,name=attempt,proto3" json:"attempt,omitempty"`                              // 当前第几次尝试
+	MaxAttempts   int32                  `protobuf:"varint,9,opt,name=max_attempts,json=maxAttempts,proto3" json:"max_attempts,omitempty"`   // 最多尝试次数（包括第一次）
+	NextRetryAt   string                 `protobuf:"bytes,10,opt,name=next_retry_at,json=nextRetryAt,proto3" json:"next_retry_at,omitempty"` // 下一次自动重试的时间，没有待执行的重试时为空
+	Labels        []string               `protobuf:"bytes,11,rep,name=labels,proto3" json:"labels,omitempty"`                                // 标签
+	Notes         string                 `protobuf:"bytes,12,opt,name=notes,proto3" json:"notes,omitempty"`                                  // 备注
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -661,6 +666,41 @@ func (x *Task) GetErrorMessage() string {
 	return ""
 }
 
+func (x *Task) GetAttempt() int32 {
+	if x != nil {
+		return x.Attempt
+	}
+	return 0
+}
+
+func (x *Task) GetMaxAttempts() int32 {
+	if x != nil {
+		return x.MaxAttempts
+	}
+	return 0
+}
+
+func (x *Task) GetNextRetryAt() string {
+	if x != nil {
+		return x.NextRetryAt
+	}
+	return ""
+}
+
+func (x *Task) GetLabels() []string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *Task) GetNotes() string {
+	if x != nil {
+		return x.Notes
+	}
+	return ""
+}
+
 type CreateTaskRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
@@ -866,6 +906,12 @@ type ListTasksRequest struct {
 	Pagination    *Pagination            `protobuf:"bytes,1,opt,name=pagination,proto3" json:"pagination,omitempty"`
 	Status        string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
 	Type          string                 `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`
+	Labels        []string               `protobuf:"bytes,4,rep,name=labels,proto3" json:"labels,omitempty"`                              // 必须同时包含的标签
+	Search        string                 `protobuf:"bytes,5,opt,name=search,proto3" json:"search,omitempty"`                              // 关键字，搜索任务ID、配置内容、备注、错误信息和标签
+	CreatedFrom   string                 `protobuf:"bytes,6,opt,name=created_from,json=createdFrom,proto3" json:"created_from,omitempty"` // RFC3339 或 YYYY-MM-DD
+	CreatedTo     string                 `protobuf:"bytes,7,opt,name=created_to,json=createdTo,proto3" json:"created_to,omitempty"`       // RFC3339 或 YYYY-MM-DD（包含当天）
+	SortBy        string                 `protobuf:"bytes,8,opt,name=sort_by,json=sortBy,proto3" json:"sort_by,omitempty"`                // created_at、updated_at、status、type、progress
+	SortOrder     string                 `protobuf:"bytes,9,opt,name=sort_order,json=sortOrder,proto3" json:"sort_order,omitempty"`       // asc 或 desc
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -921,6 +967,48 @@ func (x *ListTasksRequest) GetType() string {
 	return ""
 }
 
+func (x *ListTasksRequest) GetLabels() []string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *ListTasksRequest) GetSearch() string {
+	if x != nil {
+		return x.Search
+	}
+	return ""
+}
+
+func (x *ListTasksRequest) GetCreatedFrom() string {
+	if x != nil {
+		return x.CreatedFrom
+	}
+	return ""
+}
+
+func (x *ListTasksRequest) GetCreatedTo() string {
+	if x != nil {
+		return x.CreatedTo
+	}
+	return ""
+}
+
+func (x *ListTasksRequest) GetSortBy() string {
+	if x != nil {
+		return x.SortBy
+	}
+	return ""
+}
+
+func (x *ListTasksRequest) GetSortOrder() string {
+	if x != nil {
+		return x.SortOrder
+	}
+	return ""
+}
+
 type ListTasksResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Status        *Status                `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
@@ -1309,6 +1397,323 @@ func (x *GetTaskLogsRequest) GetLimit() int32 {
 	return 0
 }
 
+type TaskArtifact struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	TaskId        string                 `protobuf:"bytes,2,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
+	Path          string                 `protobuf:"bytes,3,opt,name=path,proto3" json:"path,omitempty"` // 相对于数据目录的路径（使用 / 分隔）
+	Size          int64                  `protobuf:"varint,4,opt,name=size,proto3" json:"size,omitempty"`
+	Sha256        string                 `protobuf:"bytes,5,opt,name=sha256,proto3" json:"sha256,omitempty"`
+	MimeType      string                 `protobuf:"bytes,6,opt,name=mime_type,json=mimeType,proto3" json:"mime_type,omitempty"`
+	Role          string                 `protobuf:"bytes,7,opt,name=role,proto3" json:"role,omitempty"` // image、tag、sidecar
+	CreatedAt     string                 `protobuf:"bytes,8,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TaskArtifact) Reset() {
+	*x = TaskArtifact{}
+	mi := &file_pixiv_tailor_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TaskArtifact) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TaskArtifact) ProtoMessage() {}
+
+func (x *TaskArtifact) ProtoReflect() protoreflect.Message {
+	mi := &file_pixiv_tailor_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TaskArtifact.ProtoReflect.Descriptor instead.
+func (*TaskArtifact) Descriptor() ([]byte, []int) {
+	return file_pixiv_tailor_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *TaskArtifact) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *TaskArtifact) GetTaskId() string {
+	if x != nil {
+		return x.TaskId
+	}
+	return ""
+}
+
+func (x *TaskArtifact) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *TaskArtifact) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *TaskArtifact) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
+func (x *TaskArtifact) GetMimeType() string {
+	if x != nil {
+		return x.MimeType
+	}
+	return ""
+}
+
+func (x *TaskArtifact) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+func (x *TaskArtifact) GetCreatedAt() string {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return ""
+}
+
+type ListTaskArtifactsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TaskId        string                 `protobuf:"bytes,1,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
+	Role          string                 `protobuf:"bytes,2,opt,name=role,proto3" json:"role,omitempty"` // 为空时列出全部
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTaskArtifactsRequest) Reset() {
+	*x = ListTaskArtifactsRequest{}
+	mi := &file_pixiv_tailor_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTaskArtifactsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTaskArtifactsRequest) ProtoMessage() {}
+
+func (x *ListTaskArtifactsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pixiv_tailor_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTaskArtifactsRequest.ProtoReflect.Descriptor instead.
+func (*ListTaskArtifactsRequest) Descriptor() ([]byte, []int) {
+	return file_pixiv_tailor_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *ListTaskArtifactsRequest) GetTaskId() string {
+	if x != nil {
+		return x.TaskId
+	}
+	return ""
+}
+
+func (x *ListTaskArtifactsRequest) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+type ListTaskArtifactsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        *Status                `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	Artifacts     []*TaskArtifact        `protobuf:"bytes,2,rep,name=artifacts,proto3" json:"artifacts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTaskArtifactsResponse) Reset() {
+	*x = ListTaskArtifactsResponse{}
+	mi := &file_pixiv_tailor_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTaskArtifactsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTaskArtifactsResponse) ProtoMessage() {}
+
+func (x *ListTaskArtifactsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pixiv_tailor_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTaskArtifactsResponse.ProtoReflect.Descriptor instead.
+func (*ListTaskArtifactsResponse) Descriptor() ([]byte, []int) {
+	return file_pixiv_tailor_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *ListTaskArtifactsResponse) GetStatus() *Status {
+	if x != nil {
+		return x.Status
+	}
+	return nil
+}
+
+func (x *ListTaskArtifactsResponse) GetArtifacts() []*TaskArtifact {
+	if x != nil {
+		return x.Artifacts
+	}
+	return nil
+}
+
+type DownloadTaskArtifactRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TaskId        string                 `protobuf:"bytes,1,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
+	ArtifactId    int64                  `protobuf:"varint,2,opt,name=artifact_id,json=artifactId,proto3" json:"artifact_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DownloadTaskArtifactRequest) Reset() {
+	*x = DownloadTaskArtifactRequest{}
+	mi := &file_pixiv_tailor_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DownloadTaskArtifactRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DownloadTaskArtifactRequest) ProtoMessage() {}
+
+func (x *DownloadTaskArtifactRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pixiv_tailor_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DownloadTaskArtifactRequest.ProtoReflect.Descriptor instead.
+func (*DownloadTaskArtifactRequest) Descriptor() ([]byte, []int) {
+	return file_pixiv_tailor_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *DownloadTaskArtifactRequest) GetTaskId() string {
+	if x != nil {
+		return x.TaskId
+	}
+	return ""
+}
+
+func (x *DownloadTaskArtifactRequest) GetArtifactId() int64 {
+	if x != nil {
+		return x.ArtifactId
+	}
+	return 0
+}
+
+// 文件分块，第一个分块带有 status 和 artifact，之后的分块只有 data
+type ArtifactChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        *Status                `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	Artifact      *TaskArtifact          `protobuf:"bytes,2,opt,name=artifact,proto3" json:"artifact,omitempty"`
+	Data          []byte                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ArtifactChunk) Reset() {
+	*x = ArtifactChunk{}
+	mi := &file_pixiv_tailor_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ArtifactChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ArtifactChunk) ProtoMessage() {}
+
+func (x *ArtifactChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_pixiv_tailor_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ArtifactChunk.ProtoReflect.Descriptor instead.
+func (*ArtifactChunk) Descriptor() ([]byte, []int) {
+	return file_pixiv_tailor_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *ArtifactChunk) GetStatus() *Status {
+	if x != nil {
+		return x.Status
+	}
+	return nil
+}
+
+func (x *ArtifactChunk) GetArtifact() *TaskArtifact {
+	if x != nil {
+		return x.Artifact
+	}
+	return nil
+}
+
+func (x *ArtifactChunk) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
 type CrawlResult struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
@@ -1324,7 +1729,7 @@ type CrawlResult struct {
 
 func (x *CrawlResult) Reset() {
 	*x = CrawlResult{}
-	mi := &file_pixiv_tailor_proto_msgTypes[24]
+	mi := &file_pixiv_tailor_proto_msgTypes[29]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1336,7 +1741,7 @@ func (x *CrawlResult) String() string {
 func (*CrawlResult) ProtoMessage() {}
 
 func (x *CrawlResult) ProtoReflect() protoreflect.Message {
-	mi := &file_pixiv_tailor_proto_msgTypes[24]
+	mi := &file_pixiv_tailor_proto_msgTypes[29]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1349,7 +1754,7 @@ func (x *CrawlResult) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CrawlResult.ProtoReflect.Descriptor instead.
 func (*CrawlResult) Descriptor() ([]byte, []int) {
-	return file_pixiv_tailor_proto_rawDescGZIP(), []int{24}
+	return file_pixiv_tailor_proto_rawDescGZIP(), []int{29}
 }
 
 func (x *CrawlResult) GetId() string {
@@ -1412,7 +1817,7 @@ type GetCrawlResultsRequest struct {
 
 func (x *GetCrawlResultsRequest) Reset() {
 	*x = GetCrawlResultsRequest{}
-	mi := &file_pixiv_tailor_proto_msgTypes[25]
+	mi := &file_pixiv_tailor_proto_msgTypes[30]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1424,7 +1829,7 @@ func (x *GetCrawlResultsRequest) String() string {
 func (*GetCrawlResultsRequest) ProtoMessage() {}
 
 func (x *GetCrawlResultsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_pixiv_tailor_proto_msgTypes[25]
+	mi := &file_pixiv_tailor_proto_msgTypes[30]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1437,7 +1842,7 @@ func (x *GetCrawlResultsRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetCrawlResultsRequest.ProtoReflect.Descriptor instead.
 func (*GetCrawlResultsRequest) Descriptor() ([]byte, []int) {
-	return file_pixiv_tailor_proto_rawDescGZIP(), []int{25}
+	return file_pixiv_tailor_proto_rawDescGZIP(), []int{30}
 }
 
 func (x *GetCrawlResultsRequest) GetPagination() *Pagination {
@@ -1472,7 +1877,7 @@ type GetCrawlResultsResponse struct {
 
 func (x *GetCrawlResultsResponse) Reset() {
 	*x = GetCrawlResultsResponse{}
-	mi := &file_pixiv_tailor_proto_msgTypes[26]
+	mi := &file_pixiv_tailor_proto_msgTypes[31]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1484,7 +1889,7 @@ func (x *GetCrawlResultsResponse) String() string {
 func (*GetCrawlResultsResponse) ProtoMessage() {}
 
 func (x *GetCrawlResultsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_pixiv_tailor_proto_msgTypes[26]
+	mi := &file_pixiv_tailor_proto_msgTypes[31]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1497,7 +1902,7 @@ func (x *GetCrawlResultsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetCrawlResultsResponse.ProtoReflect.Descriptor instead.
 func (*GetCrawlResultsResponse) Descriptor() ([]byte, []int) {
-	return file_pixiv_tailor_proto_rawDescGZIP(), []int{26}
+	return file_pixiv_tailor_proto_rawDescGZIP(), []int{31}
 }
 
 func (x *GetCrawlResultsResponse) GetStatus() *Status {
@@ -1534,7 +1939,7 @@ type GeneratedImage struct {
 
 func (x *GeneratedImage) Reset() {
 	*x = GeneratedImage{}
-	mi := &file_pixiv_tailor_proto_msgTypes[27]
+	mi := &file_pixiv_tailor_proto_msgTypes[32]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1546,7 +1951,7 @@ func (x *GeneratedImage) String() string {
 func (*GeneratedImage) ProtoMessage() {}
 
 func (x *GeneratedImage) ProtoReflect() protoreflect.Message {
-	mi := &file_pixiv_tailor_proto_msgTypes[27]
+	mi := &file_pixiv_tailor_proto_msgTypes[32]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1559,7 +1964,7 @@ func (x *GeneratedImage) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GeneratedImage.ProtoReflect.Descriptor instead.
 func (*GeneratedImage) Descriptor() ([]byte, []int) {
-	return file_pixiv_tailor_proto_rawDescGZIP(), []int{27}
+	return file_pixiv_tailor_proto_rawDescGZIP(), []int{32}
 }
 
 func (x *GeneratedImage) GetId() string {
@@ -1607,7 +2012,7 @@ type GetGeneratedImagesRequest struct {
 
 func (x *GetGeneratedImagesRequest) Reset() {
 	*x = GetGeneratedImagesRequest{}
-	mi := &file_pixiv_tailor_proto_msgTypes[28]
+	mi := &file_pixiv_tailor_proto_msgTypes[33]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1619,7 +2024,7 @@ func (x *GetGeneratedImagesRequest) String() string {
 func (*GetGeneratedImagesRequest) ProtoMessage() {}
 
 func (x *GetGeneratedImagesRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_pixiv_tailor_proto_msgTypes[28]
+	mi := &file_pixiv_tailor_proto_msgTypes[33]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1632,7 +2037,7 @@ func (x *GetGeneratedImagesRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetGeneratedImagesRequest.ProtoReflect.Descriptor instead.
 func (*GetGeneratedImagesRequest) Descriptor() ([]byte, []int) {
-	return file_pixiv_tailor_proto_rawDescGZIP(), []int{28}
+	return file_pixiv_tailor_proto_rawDescGZIP(), []int{33}
 }
 
 func (x *GetGeneratedImagesRequest) GetPagination() *Pagination {
@@ -1660,7 +2065,7 @@ type GetGeneratedImagesResponse struct {
 
 func (x *GetGeneratedImagesResponse) Reset() {
 	*x = GetGeneratedImagesResponse{}
-	mi := &file_pixiv_tailor_proto_msgTypes[29]
+	mi := &file_pixiv_tailor_proto_msgTypes[34]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1672,7 +2077,7 @@ func (x *GetGeneratedImagesResponse) String() string {
 func (*GetGeneratedImagesResponse) ProtoMessage() {}
 
 func (x *GetGeneratedImagesResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_pixiv_tailor_proto_msgTypes[29]
+	mi := &file_pixiv_tailor_proto_msgTypes[34]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1685,7 +2090,7 @@ func (x *GetGeneratedImagesResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetGeneratedImagesResponse.ProtoReflect.Descriptor instead.
 func (*GetGeneratedImagesResponse) Descriptor() ([]byte, []int) {
-	return file_pixiv_tailor_proto_rawDescGZIP(), []int{29}
+	return file_pixiv_tailor_proto_rawDescGZIP(), []int{34}
 }
 
 func (x *GetGeneratedImagesResponse) GetStatus() *Status {
@@ -1722,7 +2127,7 @@ type TrainedModel struct {
 
 func (x *TrainedModel) Reset() {
 	*x = TrainedModel{}
-	mi := &file_pixiv_tailor_proto_msgTypes[30]
+	mi := &file_pixiv_tailor_proto_msgTypes[35]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1734,7 +2139,7 @@ func (x *TrainedModel) String() string {
 func (*TrainedModel) ProtoMessage() {}
 
 func (x *TrainedModel) ProtoReflect() protoreflect.Message {
-	mi := &file_pixiv_tailor_proto_msgTypes[30]
+	mi := &file_pixiv_tailor_proto_msgTypes[35]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1747,7 +2152,7 @@ func (x *TrainedModel) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TrainedModel.ProtoReflect.Descriptor instead.
 func (*TrainedModel) Descriptor() ([]byte, []int) {
-	return file_pixiv_tailor_proto_rawDescGZIP(), []int{30}
+	return file_pixiv_tailor_proto_rawDescGZIP(), []int{35}
 }
 
 func (x *TrainedModel) GetId() string {
@@ -1795,7 +2200,7 @@ type GetTrainedModelsRequest struct {
 
 func (x *GetTrainedModelsRequest) Reset() {
 	*x = GetTrainedModelsRequest{}
-	mi := &file_pixiv_tailor_proto_msgTypes[31]
+	mi := &file_pixiv_tailor_proto_msgTypes[36]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1807,7 +2212,7 @@ func (x *GetTrainedModelsRequest) String() string {
 func (*GetTrainedModelsRequest) ProtoMessage() {}
 
 func (x *GetTrainedModelsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_pixiv_tailor_proto_msgTypes[31]
+	mi := &file_pixiv_tailor_proto_msgTypes[36]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1820,7 +2225,7 @@ func (x *GetTrainedModelsRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetTrainedModelsRequest.ProtoReflect.Descriptor instead.
 func (*GetTrainedModelsRequest) Descriptor() ([]byte, []int) {
-	return file_pixiv_tailor_proto_rawDescGZIP(), []int{31}
+	return file_pixiv_tailor_proto_rawDescGZIP(), []int{36}
 }
 
 func (x *GetTrainedModelsRequest) GetPagination() *Pagination {
@@ -1848,7 +2253,7 @@ type GetTrainedModelsResponse struct {
 
 func (x *GetTrainedModelsResponse) Reset() {
 	*x = GetTrainedModelsResponse{}
-	mi := &file_pixiv_tailor_proto_msgTypes[32]
+	mi := &file_pixiv_tailor_proto_msgTypes[37]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1860,7 +2265,7 @@ func (x *GetTrainedModelsResponse) String() string {
 func (*GetTrainedModelsResponse) ProtoMessage() {}
 
 func (x *GetTrainedModelsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_pixiv_tailor_proto_msgTypes[32]
+	mi := &file_pixiv_tailor_proto_msgTypes[37]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1873,7 +2278,7 @@ func (x *GetTrainedModelsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetTrainedModelsResponse.ProtoReflect.Descriptor instead.
 func (*GetTrainedModelsResponse) Descriptor() ([]byte, []int) {
-	return file_pixiv_tailor_proto_rawDescGZIP(), []int{32}
+	return file_pixiv_tailor_proto_rawDescGZIP(), []int{37}
 }
 
 func (x *GetTrainedModelsResponse) GetStatus() *Status {
@@ -1913,7 +2318,7 @@ type SystemInfo struct {
 
 func (x *SystemInfo) Reset() {
 	*x = SystemInfo{}
-	mi := &file_pixiv_tailor_proto_msgTypes[33]
+	mi := &file_pixiv_tailor_proto_msgTypes[38]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1925,7 +2330,7 @@ func (x *SystemInfo) String() string {
 func (*SystemInfo) ProtoMessage() {}
 
 func (x *SystemInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_pixiv_tailor_proto_msgTypes[33]
+	mi := &file_pixiv_tailor_proto_msgTypes[38]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1938,7 +2343,7 @@ func (x *SystemInfo) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SystemInfo.ProtoReflect.Descriptor instead.
 func (*SystemInfo) Descriptor() ([]byte, []int) {
-	return file_pixiv_tailor_proto_rawDescGZIP(), []int{33}
+	return file_pixiv_tailor_proto_rawDescGZIP(), []int{38}
 }
 
 func (x *SystemInfo) GetVersion() string {
@@ -2009,7 +2414,7 @@ type MemoryInfo struct {
 
 func (x *MemoryInfo) Reset() {
 	*x = MemoryInfo{}
-	mi := &file_pixiv_tailor_proto_msgTypes[34]
+	mi := &file_pixiv_tailor_proto_msgTypes[39]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2021,7 +2426,7 @@ func (x *MemoryInfo) String() string {
 func (*MemoryInfo) ProtoMessage() {}
 
 func (x *MemoryInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_pixiv_tailor_proto_msgTypes[34]
+	mi := &file_pixiv_tailor_proto_msgTypes[39]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2034,7 +2439,7 @@ func (x *MemoryInfo) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MemoryInfo.ProtoReflect.Descriptor instead.
 func (*MemoryInfo) Descriptor() ([]byte, []int) {
-	return file_pixiv_tailor_proto_rawDescGZIP(), []int{34}
+	return file_pixiv_tailor_proto_rawDescGZIP(), []int{39}
 }
 
 func (x *MemoryInfo) GetUsed() int64 {
@@ -2076,7 +2481,7 @@ type CPUInfo struct {
 
 func (x *CPUInfo) Reset() {
 	*x = CPUInfo{}
-	mi := &file_pixiv_tailor_proto_msgTypes[35]
+	mi := &file_pixiv_tailor_proto_msgTypes[40]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2088,7 +2493,7 @@ func (x *CPUInfo) String() string {
 func (*CPUInfo) ProtoMessage() {}
 
 func (x *CPUInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_pixiv_tailor_proto_msgTypes[35]
+	mi := &file_pixiv_tailor_proto_msgTypes[40]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2101,7 +2506,7 @@ func (x *CPUInfo) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CPUInfo.ProtoReflect.Descriptor instead.
 func (*CPUInfo) Descriptor() ([]byte, []int) {
-	return file_pixiv_tailor_proto_rawDescGZIP(), []int{35}
+	return file_pixiv_tailor_proto_rawDescGZIP(), []int{40}
 }
 
 func (x *CPUInfo) GetUsage() int32 {
@@ -2142,7 +2547,7 @@ type DiskInfo struct {
 
 func (x *DiskInfo) Reset() {
 	*x = DiskInfo{}
-	mi := &file_pixiv_tailor_proto_msgTypes[36]
+	mi := &file_pixiv_tailor_proto_msgTypes[41]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2154,7 +2559,7 @@ func (x *DiskInfo) String() string {
 func (*DiskInfo) ProtoMessage() {}
 
 func (x *DiskInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_pixiv_tailor_proto_msgTypes[36]
+	mi := &file_pixiv_tailor_proto_msgTypes[41]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2167,7 +2572,7 @@ func (x *DiskInfo) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DiskInfo.ProtoReflect.Descriptor instead.
 func (*DiskInfo) Descriptor() ([]byte, []int) {
-	return file_pixiv_tailor_proto_rawDescGZIP(), []int{36}
+	return file_pixiv_tailor_proto_rawDescGZIP(), []int{41}
 }
 
 func (x *DiskInfo) GetData() int64 {
@@ -2241,7 +2646,7 @@ type GetSystemInfoRequest struct {
 
 func (x *GetSystemInfoRequest) Reset() {
 	*x = GetSystemInfoRequest{}
-	mi := &file_pixiv_tailor_proto_msgTypes[37]
+	mi := &file_pixiv_tailor_proto_msgTypes[42]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2253,7 +2658,7 @@ func (x *GetSystemInfoRequest) String() string {
 func (*GetSystemInfoRequest) ProtoMessage() {}
 
 func (x *GetSystemInfoRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_pixiv_tailor_proto_msgTypes[37]
+	mi := &file_pixiv_tailor_proto_msgTypes[42]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2266,7 +2671,7 @@ func (x *GetSystemInfoRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetSystemInfoRequest.ProtoReflect.Descriptor instead.
 func (*GetSystemInfoRequest) Descriptor() ([]byte, []int) {
-	return file_pixiv_tailor_proto_rawDescGZIP(), []int{37}
+	return file_pixiv_tailor_proto_rawDescGZIP(), []int{42}
 }
 
 type GetSystemInfoResponse struct {
@@ -2279,7 +2684,7 @@ type GetSystemInfoResponse struct {
 
 func (x *GetSystemInfoResponse) Reset() {
 	*x = GetSystemInfoResponse{}
-	mi := &file_pixiv_tailor_proto_msgTypes[38]
+	mi := &file_pixiv_tailor_proto_msgTypes[43]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2291,7 +2696,7 @@ func (x *GetSystemInfoResponse) String() string {
 func (*GetSystemInfoResponse) ProtoMessage() {}
 
 func (x *GetSystemInfoResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_pixiv_tailor_proto_msgTypes[38]
+	mi := &file_pixiv_tailor_proto_msgTypes[43]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2304,7 +2709,7 @@ func (x *GetSystemInfoResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetSystemInfoResponse.ProtoReflect.Descriptor instead.
 func (*GetSystemInfoResponse) Descriptor() ([]byte, []int) {
-	return file_pixiv_tailor_proto_rawDescGZIP(), []int{38}
+	return file_pixiv_tailor_proto_rawDescGZIP(), []int{43}
 }
 
 func (x *GetSystemInfoResponse) GetStatus() *Status {
@@ -2358,7 +2763,7 @@ const file_pixiv_tailor_proto_rawDesc = "" +
 	"\vconfig_data\x18\x01 \x01(\tR\n" +
 	"configData\"D\n" +
 	"\x14ImportConfigResponse\x12,\n" +
-	"\x06status\x18\x01 \x01(\v2\x14.pixiv_tailor.StatusR\x06status\"\xbd\x01\n" +
+	"\x06status\x18\x01 \x01(\v2\x14.pixiv_tailor.StatusR\x06status\"\xcc\x02\n" +
 	"\x04Task\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
 	"\x04type\x18\x02 \x01(\tR\x04type\x12\x16\n" +
@@ -2368,7 +2773,13 @@ const file_pixiv_tailor_proto_rawDesc = "" +
 	"created_at\x18\x05 \x01(\tR\tcreatedAt\x12\x1d\n" +
 	"\n" +
 	"updated_at\x18\x06 \x01(\tR\tupdatedAt\x12#\n" +
-	"\rerror_message\x18\a \x01(\tR\ferrorMessage\"?\n" +
+	"\rerror_message\x18\a \x01(\tR\ferrorMessage\x12\x18\n" +
+	"\aattempt\x18\b \x01(\x05R\aattempt\x12!\n" +
+	"\fmax_attempts\x18\t \x01(\x05R\vmaxAttempts\x12\"\n" +
+	"\rnext_retry_at\x18\n" +
+	" \x01(\tR\vnextRetryAt\x12\x16\n" +
+	"\x06labels\x18\v \x03(\tR\x06labels\x12\x14\n" +
+	"\x05notes\x18\f \x01(\tR\x05notes\"?\n" +
 	"\x11CreateTaskRequest\x12\x12\n" +
 	"\x04type\x18\x01 \x01(\tR\x04type\x12\x16\n" +
 	"\x06config\x18\x02 \x01(\tR\x06config\"j\n" +
@@ -2379,13 +2790,21 @@ const file_pixiv_tailor_proto_rawDesc = "" +
 	"\atask_id\x18\x01 \x01(\tR\x06taskId\"m\n" +
 	"\x15GetTaskStatusResponse\x12,\n" +
 	"\x06status\x18\x01 \x01(\v2\x14.pixiv_tailor.StatusR\x06status\x12&\n" +
-	"\x04task\x18\x02 \x01(\v2\x12.pixiv_tailor.TaskR\x04task\"x\n" +
+	"\x04task\x18\x02 \x01(\v2\x12.pixiv_tailor.TaskR\x04task\"\xa2\x02\n" +
 	"\x10ListTasksRequest\x128\n" +
 	"\n" +
 	"pagination\x18\x01 \x01(\v2\x18.pixiv_tailor.PaginationR\n" +
 	"pagination\x12\x16\n" +
 	"\x06status\x18\x02 \x01(\tR\x06status\x12\x12\n" +
-	"\x04type\x18\x03 \x01(\tR\x04type\"\xa5\x01\n" +
+	"\x04type\x18\x03 \x01(\tR\x04type\x12\x16\n" +
+	"\x06labels\x18\x04 \x03(\tR\x06labels\x12\x16\n" +
+	"\x06search\x18\x05 \x01(\tR\x06search\x12!\n" +
+	"\fcreated_from\x18\x06 \x01(\tR\vcreatedFrom\x12\x1d\n" +
+	"\n" +
+	"created_to\x18\a \x01(\tR\tcreatedTo\x12\x17\n" +
+	"\asort_by\x18\b \x01(\tR\x06sortBy\x12\x1d\n" +
+	"\n" +
+	"sort_order\x18\t \x01(\tR\tsortOrder\"\xa5\x01\n" +
 	"\x11ListTasksResponse\x12,\n" +
 	"\x06status\x18\x01 \x01(\v2\x14.pixiv_tailor.StatusR\x06status\x12(\n" +
 	"\x05tasks\x18\x02 \x03(\v2\x12.pixiv_tailor.TaskR\x05tasks\x128\n" +
@@ -2411,7 +2830,31 @@ const file_pixiv_tailor_proto_rawDesc = "" +
 	"\adetails\x18\x04 \x01(\tR\adetails\"C\n" +
 	"\x12GetTaskLogsRequest\x12\x17\n" +
 	"\atask_id\x18\x01 \x01(\tR\x06taskId\x12\x14\n" +
-	"\x05limit\x18\x02 \x01(\x05R\x05limit\"\xad\x01\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\"\xc7\x01\n" +
+	"\fTaskArtifact\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x17\n" +
+	"\atask_id\x18\x02 \x01(\tR\x06taskId\x12\x12\n" +
+	"\x04path\x18\x03 \x01(\tR\x04path\x12\x12\n" +
+	"\x04size\x18\x04 \x01(\x03R\x04size\x12\x16\n" +
+	"\x06sha256\x18\x05 \x01(\tR\x06sha256\x12\x1b\n" +
+	"\tmime_type\x18\x06 \x01(\tR\bmimeType\x12\x12\n" +
+	"\x04role\x18\a \x01(\tR\x04role\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\b \x01(\tR\tcreatedAt\"G\n" +
+	"\x18ListTaskArtifactsRequest\x12\x17\n" +
+	"\atask_id\x18\x01 \x01(\tR\x06taskId\x12\x12\n" +
+	"\x04role\x18\x02 \x01(\tR\x04role\"\x83\x01\n" +
+	"\x19ListTaskArtifactsResponse\x12,\n" +
+	"\x06status\x18\x01 \x01(\v2\x14.pixiv_tailor.StatusR\x06status\x128\n" +
+	"\tartifacts\x18\x02 \x03(\v2\x1a.pixiv_tailor.TaskArtifactR\tartifacts\"W\n" +
+	"\x1bDownloadTaskArtifactRequest\x12\x17\n" +
+	"\atask_id\x18\x01 \x01(\tR\x06taskId\x12\x1f\n" +
+	"\vartifact_id\x18\x02 \x01(\x03R\n" +
+	"artifactId\"\x89\x01\n" +
+	"\rArtifactChunk\x12,\n" +
+	"\x06status\x18\x01 \x01(\v2\x14.pixiv_tailor.StatusR\x06status\x126\n" +
+	"\bartifact\x18\x02 \x01(\v2\x1a.pixiv_tailor.TaskArtifactR\bartifact\x12\x12\n" +
+	"\x04data\x18\x03 \x01(\fR\x04data\"\xad\x01\n" +
 	"\vCrawlResult\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x10\n" +
 	"\x03url\x18\x02 \x01(\tR\x03url\x12\x14\n" +
@@ -2506,7 +2949,7 @@ const file_pixiv_tailor_proto_rawDesc = "" +
 	"\x15GetSystemInfoResponse\x12,\n" +
 	"\x06status\x18\x01 \x01(\v2\x14.pixiv_tailor.StatusR\x06status\x129\n" +
 	"\vsystem_info\x18\x02 \x01(\v2\x18.pixiv_tailor.SystemInfoR\n" +
-	"systemInfo2\xdf\t\n" +
+	"systemInfo2\xa7\v\n" +
 	"\x12PixivTailorService\x12L\n" +
 	"\tGetConfig\x12\x1e.pixiv_tailor.GetConfigRequest\x1a\x1f.pixiv_tailor.GetConfigResponse\x12U\n" +
 	"\fUpdateConfig\x12!.pixiv_tailor.UpdateConfigRequest\x1a\".pixiv_tailor.UpdateConfigResponse\x12U\n" +
@@ -2519,7 +2962,9 @@ const file_pixiv_tailor_proto_rawDesc = "" +
 	"\n" +
 	"CancelTask\x12\x1f.pixiv_tailor.CancelTaskRequest\x1a .pixiv_tailor.CancelTaskResponse\x12[\n" +
 	"\x0fGetTaskProgress\x12$.pixiv_tailor.GetTaskProgressRequest\x1a .pixiv_tailor.TaskProgressUpdate0\x01\x12I\n" +
-	"\vGetTaskLogs\x12 .pixiv_tailor.GetTaskLogsRequest\x1a\x16.pixiv_tailor.LogEntry0\x01\x12^\n" +
+	"\vGetTaskLogs\x12 .pixiv_tailor.GetTaskLogsRequest\x1a\x16.pixiv_tailor.LogEntry0\x01\x12d\n" +
+	"\x11ListTaskArtifacts\x12&.pixiv_tailor.ListTaskArtifactsRequest\x1a'.pixiv_tailor.ListTaskArtifactsResponse\x12`\n" +
+	"\x14DownloadTaskArtifact\x12).pixiv_tailor.DownloadTaskArtifactRequest\x1a\x1b.pixiv_tailor.ArtifactChunk0\x01\x12^\n" +
 	"\x0fGetCrawlResults\x12$.pixiv_tailor.GetCrawlResultsRequest\x1a%.pixiv_tailor.GetCrawlResultsResponse\x12g\n" +
 	"\x12GetGeneratedImages\x12'.pixiv_tailor.GetGeneratedImagesRequest\x1a(.pixiv_tailor.GetGeneratedImagesResponse\x12a\n" +
 	"\x10GetTrainedModels\x12%.pixiv_tailor.GetTrainedModelsRequest\x1a&.pixiv_tailor.GetTrainedModelsResponse\x12X\n" +
@@ -2537,47 +2982,52 @@ func file_pixiv_tailor_proto_rawDescGZIP() []byte {
 	return file_pixiv_tailor_proto_rawDescData
 }
 
-var file_pixiv_tailor_proto_msgTypes = make([]protoimpl.MessageInfo, 39)
+var file_pixiv_tailor_proto_msgTypes = make([]protoimpl.MessageInfo, 44)
 var file_pixiv_tailor_proto_goTypes = []any{
-	(*Status)(nil),                     // 0: pixiv_tailor.Status
-	(*Pagination)(nil),                 // 1: pixiv_tailor.Pagination
-	(*ConfigModule)(nil),               // 2: pixiv_tailor.ConfigModule
-	(*GetConfigRequest)(nil),           // 3: pixiv_tailor.GetConfigRequest
-	(*GetConfigResponse)(nil),          // 4: pixiv_tailor.GetConfigResponse
-	(*UpdateConfigRequest)(nil),        // 5: pixiv_tailor.UpdateConfigRequest
-	(*UpdateConfigResponse)(nil),       // 6: pixiv_tailor.UpdateConfigResponse
-	(*ExportConfigRequest)(nil),        // 7: pixiv_tailor.ExportConfigRequest
-	(*ExportConfigResponse)(nil),       // 8: pixiv_tailor.ExportConfigResponse
-	(*ImportConfigRequest)(nil),        // 9: pixiv_tailor.ImportConfigRequest
-	(*ImportConfigResponse)(nil),       // 10: pixiv_tailor.ImportConfigResponse
-	(*Task)(nil),                       // 11: pixiv_tailor.Task
-	(*CreateTaskRequest)(nil),          // 12: pixiv_tailor.CreateTaskRequest
-	(*CreateTaskResponse)(nil),         // 13: pixiv_tailor.CreateTaskResponse
-	(*GetTaskStatusRequest)(nil),       // 14: pixiv_tailor.GetTaskStatusRequest
-	(*GetTaskStatusResponse)(nil),      // 15: pixiv_tailor.GetTaskStatusResponse
-	(*ListTasksRequest)(nil),           // 16: pixiv_tailor.ListTasksRequest
-	(*ListTasksResponse)(nil),          // 17: pixiv_tailor.ListTasksResponse
-	(*CancelTaskRequest)(nil),          // 18: pixiv_tailor.CancelTaskRequest
-	(*CancelTaskResponse)(nil),         // 19: pixiv_tailor.CancelTaskResponse
-	(*TaskProgressUpdate)(nil),         // 20: pixiv_tailor.TaskProgressUpdate
-	(*GetTaskProgressRequest)(nil),     // 21: pixiv_tailor.GetTaskProgressRequest
-	(*LogEntry)(nil),                   // 22: pixiv_tailor.LogEntry
-	(*GetTaskLogsRequest)(nil),         // 23: pixiv_tailor.GetTaskLogsRequest
-	(*CrawlResult)(nil),                // 24: pixiv_tailor.CrawlResult
-	(*GetCrawlResultsRequest)(nil),     // 25: pixiv_tailor.GetCrawlResultsRequest
-	(*GetCrawlResultsResponse)(nil),    // 26: pixiv_tailor.GetCrawlResultsResponse
-	(*GeneratedImage)(nil),             // 27: pixiv_tailor.GeneratedImage
-	(*GetGeneratedImagesRequest)(nil),  // 28: pixiv_tailor.GetGeneratedImagesRequest
-	(*GetGeneratedImagesResponse)(nil), // 29: pixiv_tailor.GetGeneratedImagesResponse
-	(*TrainedModel)(nil),               // 30: pixiv_tailor.TrainedModel
-	(*GetTrainedModelsRequest)(nil),    // 31: pixiv_tailor.GetTrainedModelsRequest
-	(*GetTrainedModelsResponse)(nil),   // 32: pixiv_tailor.GetTrainedModelsResponse
-	(*SystemInfo)(nil),                 // 33: pixiv_tailor.SystemInfo
-	(*MemoryInfo)(nil),                 // 34: pixiv_tailor.MemoryInfo
-	(*CPUInfo)(nil),                    // 35: pixiv_tailor.CPUInfo
-	(*DiskInfo)(nil),                   // 36: pixiv_tailor.DiskInfo
-	(*GetSystemInfoRequest)(nil),       // 37: pixiv_tailor.GetSystemInfoRequest
-	(*GetSystemInfoResponse)(nil),      // 38: pixiv_tailor.GetSystemInfoResponse
+	(*Status)(nil),                      // 0: pixiv_tailor.Status
+	(*Pagination)(nil),                  // 1: pixiv_tailor.Pagination
+	(*ConfigModule)(nil),                // 2: pixiv_tailor.ConfigModule
+	(*GetConfigRequest)(nil),            // 3: pixiv_tailor.GetConfigRequest
+	(*GetConfigResponse)(nil),           // 4: pixiv_tailor.GetConfigResponse
+	(*UpdateConfigRequest)(nil),         // 5: pixiv_tailor.UpdateConfigRequest
+	(*UpdateConfigResponse)(nil),        // 6: pixiv_tailor.UpdateConfigResponse
+	(*ExportConfigRequest)(nil),         // 7: pixiv_tailor.ExportConfigRequest
+	(*ExportConfigResponse)(nil),        // 8: pixiv_tailor.ExportConfigResponse
+	(*ImportConfigRequest)(nil),         // 9: pixiv_tailor.ImportConfigRequest
+	(*ImportConfigResponse)(nil),        // 10: pixiv_tailor.ImportConfigResponse
+	(*Task)(nil),                        // 11: pixiv_tailor.Task
+	(*CreateTaskRequest)(nil),           // 12: pixiv_tailor.CreateTaskRequest
+	(*CreateTaskResponse)(nil),          // 13: pixiv_tailor.CreateTaskResponse
+	(*GetTaskStatusRequest)(nil),        // 14: pixiv_tailor.GetTaskStatusRequest
+	(*GetTaskStatusResponse)(nil),       // 15: pixiv_tailor.GetTaskStatusResponse
+	(*ListTasksRequest)(nil),            // 16: pixiv_tailor.ListTasksRequest
+	(*ListTasksResponse)(nil),           // 17: pixiv_tailor.ListTasksResponse
+	(*CancelTaskRequest)(nil),           // 18: pixiv_tailor.CancelTaskRequest
+	(*CancelTaskResponse)(nil),          // 19: pixiv_tailor.CancelTaskResponse
+	(*TaskProgressUpdate)(nil),          // 20: pixiv_tailor.TaskProgressUpdate
+	(*GetTaskProgressRequest)(nil),      // 21: pixiv_tailor.GetTaskProgressRequest
+	(*LogEntry)(nil),                    // 22: pixiv_tailor.LogEntry
+	(*GetTaskLogsRequest)(nil),          // 23: pixiv_tailor.GetTaskLogsRequest
+	(*TaskArtifact)(nil),                // 24: pixiv_tailor.TaskArtifact
+	(*ListTaskArtifactsRequest)(nil),    // 25: pixiv_tailor.ListTaskArtifactsRequest
+	(*ListTaskArtifactsResponse)(nil),   // 26: pixiv_tailor.ListTaskArtifactsResponse
+	(*DownloadTaskArtifactRequest)(nil), // 27: pixiv_tailor.DownloadTaskArtifactRequest
+	(*ArtifactChunk)(nil),               // 28: pixiv_tailor.ArtifactChunk
+	(*CrawlResult)(nil),                 // 29: pixiv_tailor.CrawlResult
+	(*GetCrawlResultsRequest)(nil),      // 30: pixiv_tailor.GetCrawlResultsRequest
+	(*GetCrawlResultsResponse)(nil),     // 31: pixiv_tailor.GetCrawlResultsResponse
+	(*GeneratedImage)(nil),              // 32: pixiv_tailor.GeneratedImage
+	(*GetGeneratedImagesRequest)(nil),   // 33: pixiv_tailor.GetGeneratedImagesRequest
+	(*GetGeneratedImagesResponse)(nil),  // 34: pixiv_tailor.GetGeneratedImagesResponse
+	(*TrainedModel)(nil),                // 35: pixiv_tailor.TrainedModel
+	(*GetTrainedModelsRequest)(nil),     // 36: pixiv_tailor.GetTrainedModelsRequest
+	(*GetTrainedModelsResponse)(nil),    // 37: pixiv_tailor.GetTrainedModelsResponse
+	(*SystemInfo)(nil),                  // 38: pixiv_tailor.SystemInfo
+	(*MemoryInfo)(nil),                  // 39: pixiv_tailor.MemoryInfo
+	(*CPUInfo)(nil),                     // 40: pixiv_tailor.CPUInfo
+	(*DiskInfo)(nil),                    // 41: pixiv_tailor.DiskInfo
+	(*GetSystemInfoRequest)(nil),        // 42: pixiv_tailor.GetSystemInfoRequest
+	(*GetSystemInfoResponse)(nil),       // 43: pixiv_tailor.GetSystemInfoResponse
 }
 var file_pixiv_tailor_proto_depIdxs = []int32{
 	0,  // 0: pixiv_tailor.GetConfigResponse.status:type_name -> pixiv_tailor.Status
@@ -2594,56 +3044,64 @@ var file_pixiv_tailor_proto_depIdxs = []int32{
 	11, // 11: pixiv_tailor.ListTasksResponse.tasks:type_name -> pixiv_tailor.Task
 	1,  // 12: pixiv_tailor.ListTasksResponse.pagination:type_name -> pixiv_tailor.Pagination
 	0,  // 13: pixiv_tailor.CancelTaskResponse.status:type_name -> pixiv_tailor.Status
-	1,  // 14: pixiv_tailor.GetCrawlResultsRequest.pagination:type_name -> pixiv_tailor.Pagination
-	0,  // 15: pixiv_tailor.GetCrawlResultsResponse.status:type_name -> pixiv_tailor.Status
-	24, // 16: pixiv_tailor.GetCrawlResultsResponse.results:type_name -> pixiv_tailor.CrawlResult
-	1,  // 17: pixiv_tailor.GetCrawlResultsResponse.pagination:type_name -> pixiv_tailor.Pagination
-	1,  // 18: pixiv_tailor.GetGeneratedImagesRequest.pagination:type_name -> pixiv_tailor.Pagination
-	0,  // 19: pixiv_tailor.GetGeneratedImagesResponse.status:type_name -> pixiv_tailor.Status
-	27, // 20: pixiv_tailor.GetGeneratedImagesResponse.images:type_name -> pixiv_tailor.GeneratedImage
-	1,  // 21: pixiv_tailor.GetGeneratedImagesResponse.pagination:type_name -> pixiv_tailor.Pagination
-	1,  // 22: pixiv_tailor.GetTrainedModelsRequest.pagination:type_name -> pixiv_tailor.Pagination
-	0,  // 23: pixiv_tailor.GetTrainedModelsResponse.status:type_name -> pixiv_tailor.Status
-	30, // 24: pixiv_tailor.GetTrainedModelsResponse.models:type_name -> pixiv_tailor.TrainedModel
-	1,  // 25: pixiv_tailor.GetTrainedModelsResponse.pagination:type_name -> pixiv_tailor.Pagination
-	34, // 26: pixiv_tailor.SystemInfo.memory:type_name -> pixiv_tailor.MemoryInfo
-	35, // 27: pixiv_tailor.SystemInfo.cpu:type_name -> pixiv_tailor.CPUInfo
-	36, // 28: pixiv_tailor.SystemInfo.disk:type_name -> pixiv_tailor.DiskInfo
-	0,  // 29: pixiv_tailor.GetSystemInfoResponse.status:type_name -> pixiv_tailor.Status
-	33, // 30: pixiv_tailor.GetSystemInfoResponse.system_info:type_name -> pixiv_tailor.SystemInfo
-	3,  // 31: pixiv_tailor.PixivTailorService.GetConfig:input_type -> pixiv_tailor.GetConfigRequest
-	5,  // 32: pixiv_tailor.PixivTailorService.UpdateConfig:input_type -> pixiv_tailor.UpdateConfigRequest
-	7,  // 33: pixiv_tailor.PixivTailorService.ExportConfig:input_type -> pixiv_tailor.ExportConfigRequest
-	9,  // 34: pixiv_tailor.PixivTailorService.ImportConfig:input_type -> pixiv_tailor.ImportConfigRequest
-	12, // 35: pixiv_tailor.PixivTailorService.CreateTask:input_type -> pixiv_tailor.CreateTaskRequest
-	14, // 36: pixiv_tailor.PixivTailorService.GetTaskStatus:input_type -> pixiv_tailor.GetTaskStatusRequest
-	16, // 37: pixiv_tailor.PixivTailorService.ListTasks:input_type -> pixiv_tailor.ListTasksRequest
-	18, // 38: pixiv_tailor.PixivTailorService.CancelTask:input_type -> pixiv_tailor.CancelTaskRequest
-	21, // 39: pixiv_tailor.PixivTailorService.GetTaskProgress:input_type -> pixiv_tailor.GetTaskProgressRequest
-	23, // 40: pixiv_tailor.PixivTailorService.GetTaskLogs:input_type -> pixiv_tailor.GetTaskLogsRequest
-	25, // 41: pixiv_tailor.PixivTailorService.GetCrawlResults:input_type -> pixiv_tailor.GetCrawlResultsRequest
-	28, // 42: pixiv_tailor.PixivTailorService.GetGeneratedImages:input_type -> pixiv_tailor.GetGeneratedImagesRequest
-	31, // 43: pixiv_tailor.PixivTailorService.GetTrainedModels:input_type -> pixiv_tailor.GetTrainedModelsRequest
-	37, // 44: pixiv_tailor.PixivTailorService.GetSystemInfo:input_type -> pixiv_tailor.GetSystemInfoRequest
-	4,  // 45: pixiv_tailor.PixivTailorService.GetConfig:output_type -> pixiv_tailor.GetConfigResponse
-	6,  // 46: pixiv_tailor.PixivTailorService.UpdateConfig:output_type -> pixiv_tailor.UpdateConfigResponse
-	8,  // 47: pixiv_tailor.PixivTailorService.ExportConfig:output_type -> pixiv_tailor.ExportConfigResponse
-	10, // 48: pixiv_tailor.PixivTailorService.ImportConfig:output_type -> pixiv_tailor.ImportConfigResponse
-	13, // 49: pixiv_tailor.PixivTailorService.CreateTask:output_type -> pixiv_tailor.CreateTaskResponse
-	15, // 50: pixiv_tailor.PixivTailorService.GetTaskStatus:output_type -> pixiv_tailor.GetTaskStatusResponse
-	17, // 51: pixiv_tailor.PixivTailorService.ListTasks:output_type -> pixiv_tailor.ListTasksResponse
-	19, // 52: pixiv_tailor.PixivTailorService.CancelTask:output_type -> pixiv_tailor.CancelTaskResponse
-	20, // 53: pixiv_tailor.PixivTailorService.GetTaskProgress:output_type -> pixiv_tailor.TaskProgressUpdate
-	22, // 54: pixiv_tailor.PixivTailorService.GetTaskLogs:output_type -> pixiv_tailor.LogEntry
-	26, // 55: pixiv_tailor.PixivTailorService.GetCrawlResults:output_type -> pixiv_tailor.GetCrawlResultsResponse
-	29, // 56: pixiv_tailor.PixivTailorService.GetGeneratedImages:output_type -> pixiv_tailor.GetGeneratedImagesResponse
-	32, // 57: pixiv_tailor.PixivTailorService.GetTrainedModels:output_type -> pixiv_tailor.GetTrainedModelsResponse
-	38, // 58: pixiv_tailor.PixivTailorService.GetSystemInfo:output_type -> pixiv_tailor.GetSystemInfoResponse
-	45, // [45:59] is the sub-list for method output_type
-	31, // [31:45] is the sub-list for method input_type
-	31, // [31:31] is the sub-list for extension type_name
-	31, // [31:31] is the sub-list for extension extendee
-	0,  // [0:31] is the sub-list for field type_name
+	0,  // 14: pixiv_tailor.ListTaskArtifactsResponse.status:type_name -> pixiv_tailor.Status
+	24, // 15: pixiv_tailor.ListTaskArtifactsResponse.artifacts:type_name -> pixiv_tailor.TaskArtifact
+	0,  // 16: pixiv_tailor.ArtifactChunk.status:type_name -> pixiv_tailor.Status
+	24, // 17: pixiv_tailor.ArtifactChunk.artifact:type_name -> pixiv_tailor.TaskArtifact
+	1,  // 18: pixiv_tailor.GetCrawlResultsRequest.pagination:type_name -> pixiv_tailor.Pagination
+	0,  // 19: pixiv_tailor.GetCrawlResultsResponse.status:type_name -> pixiv_tailor.Status
+	29, // 20: pixiv_tailor.GetCrawlResultsResponse.results:type_name -> pixiv_tailor.CrawlResult
+	1,  // 21: pixiv_tailor.GetCrawlResultsResponse.pagination:type_name -> pixiv_tailor.Pagination
+	1,  // 22: pixiv_tailor.GetGeneratedImagesRequest.pagination:type_name -> pixiv_tailor.Pagination
+	0,  // 23: pixiv_tailor.GetGeneratedImagesResponse.status:type_name -> pixiv_tailor.Status
+	32, // 24: pixiv_tailor.GetGeneratedImagesResponse.images:type_name -> pixiv_tailor.GeneratedImage
+	1,  // 25: pixiv_tailor.GetGeneratedImagesResponse.pagination:type_name -> pixiv_tailor.Pagination
+	1,  // 26: pixiv_tailor.GetTrainedModelsRequest.pagination:type_name -> pixiv_tailor.Pagination
+	0,  // 27: pixiv_tailor.GetTrainedModelsResponse.status:type_name -> pixiv_tailor.Status
+	35, // 28: pixiv_tailor.GetTrainedModelsResponse.models:type_name -> pixiv_tailor.TrainedModel
+	1,  // 29: pixiv_tailor.GetTrainedModelsResponse.pagination:type_name -> pixiv_tailor.Pagination
+	39, // 30: pixiv_tailor.SystemInfo.memory:type_name -> pixiv_tailor.MemoryInfo
+	40, // 31: pixiv_tailor.SystemInfo.cpu:type_name -> pixiv_tailor.CPUInfo
+	41, // 32: pixiv_tailor.SystemInfo.disk:type_name -> pixiv_tailor.DiskInfo
+	0,  // 33: pixiv_tailor.GetSystemInfoResponse.status:type_name -> pixiv_tailor.Status
+	38, // 34: pixiv_tailor.GetSystemInfoResponse.system_info:type_name -> pixiv_tailor.SystemInfo
+	3,  // 35: pixiv_tailor.PixivTailorService.GetConfig:input_type -> pixiv_tailor.GetConfigRequest
+	5,  // 36: pixiv_tailor.PixivTailorService.UpdateConfig:input_type -> pixiv_tailor.UpdateConfigRequest
+	7,  // 37: pixiv_tailor.PixivTailorService.ExportConfig:input_type -> pixiv_tailor.ExportConfigRequest
+	9,  // 38: pixiv_tailor.PixivTailorService.ImportConfig:input_type -> pixiv_tailor.ImportConfigRequest
+	12, // 39: pixiv_tailor.PixivTailorService.CreateTask:input_type -> pixiv_tailor.CreateTaskRequest
+	14, // 40: pixiv_tailor.PixivTailorService.GetTaskStatus:input_type -> pixiv_tailor.GetTaskStatusRequest
+	16, // 41: pixiv_tailor.PixivTailorService.ListTasks:input_type -> pixiv_tailor.ListTasksRequest
+	18, // 42: pixiv_tailor.PixivTailorService.CancelTask:input_type -> pixiv_tailor.CancelTaskRequest
+	21, // 43: pixiv_tailor.PixivTailorService.GetTaskProgress:input_type -> pixiv_tailor.GetTaskProgressRequest
+	23, // 44: pixiv_tailor.PixivTailorService.GetTaskLogs:input_type -> pixiv_tailor.GetTaskLogsRequest
+	25, // 45: pixiv_tailor.PixivTailorService.ListTaskArtifacts:input_type -> pixiv_tailor.ListTaskArtifactsRequest
+	27, // 46: pixiv_tailor.PixivTailorService.DownloadTaskArtifact:input_type -> pixiv_tailor.DownloadTaskArtifactRequest
+	30, // 47: pixiv_tailor.PixivTailorService.GetCrawlResults:input_type -> pixiv_tailor.GetCrawlResultsRequest
+	33, // 48: pixiv_tailor.PixivTailorService.GetGeneratedImages:input_type -> pixiv_tailor.GetGeneratedImagesRequest
+	36, // 49: pixiv_tailor.PixivTailorService.GetTrainedModels:input_type -> pixiv_tailor.GetTrainedModelsRequest
+	42, // 50: pixiv_tailor.PixivTailorService.GetSystemInfo:input_type -> pixiv_tailor.GetSystemInfoRequest
+	4,  // 51: pixiv_tailor.PixivTailorService.GetConfig:output_type -> pixiv_tailor.GetConfigResponse
+	6,  // 52: pixiv_tailor.PixivTailorService.UpdateConfig:output_type -> pixiv_tailor.UpdateConfigResponse
+	8,  // 53: pixiv_tailor.PixivTailorService.ExportConfig:output_type -> pixiv_tailor.ExportConfigResponse
+	10, // 54: pixiv_tailor.PixivTailorService.ImportConfig:output_type -> pixiv_tailor.ImportConfigResponse
+	13, // 55: pixiv_tailor.PixivTailorService.CreateTask:output_type -> pixiv_tailor.CreateTaskResponse
+	15, // 56: pixiv_tailor.PixivTailorService.GetTaskStatus:output_type -> pixiv_tailor.GetTaskStatusResponse
+	17, // 57: pixiv_tailor.PixivTailorService.ListTasks:output_type -> pixiv_tailor.ListTasksResponse
+	19, // 58: pixiv_tailor.PixivTailorService.CancelTask:output_type -> pixiv_tailor.CancelTaskResponse
+	20, // 59: pixiv_tailor.PixivTailorService.GetTaskProgress:output_type -> pixiv_tailor.TaskProgressUpdate
+	22, // 60: pixiv_tailor.PixivTailorService.GetTaskLogs:output_type -> pixiv_tailor.LogEntry
+	26, // 61: pixiv_tailor.PixivTailorService.ListTaskArtifacts:output_type -> pixiv_tailor.ListTaskArtifactsResponse
+	28, // 62: pixiv_tailor.PixivTailorService.DownloadTaskArtifact:output_type -> pixiv_tailor.ArtifactChunk
+	31, // 63: pixiv_tailor.PixivTailorService.GetCrawlResults:output_type -> pixiv_tailor.GetCrawlResultsResponse
+	34, // 64: pixiv_tailor.PixivTailorService.GetGeneratedImages:output_type -> pixiv_tailor.GetGeneratedImagesResponse
+	37, // 65: pixiv_tailor.PixivTailorService.GetTrainedModels:output_type -> pixiv_tailor.GetTrainedModelsResponse
+	43, // 66: pixiv_tailor.PixivTailorService.GetSystemInfo:output_type -> pixiv_tailor.GetSystemInfoResponse
+	51, // [51:67] is the sub-list for method output_type
+	35, // [35:51] is the sub-list for method input_type
+	35, // [35:35] is the sub-list for extension type_name
+	35, // [35:35] is the sub-list for extension extendee
+	0,  // [0:35] is the sub-list for field type_name
 }
 
 func init() { file_pixiv_tailor_proto_init() }
@@ -2657,7 +3115,7 @@ func file_pixiv_tailor_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pixiv_tailor_proto_rawDesc), len(file_pixiv_tailor_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   39,
+			NumMessages:   44,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
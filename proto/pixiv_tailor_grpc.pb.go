@@ -19,20 +19,22 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	PixivTailorService_GetConfig_FullMethodName          = "/pixiv_tailor.PixivTailorService/GetConfig"
-	PixivTailorService_UpdateConfig_FullMethodName       = "/pixiv_tailor.PixivTailorService/UpdateConfig"
-	PixivTailorService_ExportConfig_FullMethodName       = "/pixiv_tailor.PixivTailorService/ExportConfig"
-	PixivTailorService_ImportConfig_FullMethodName       = "/pixiv_tailor.PixivTailorService/ImportConfig"
-	PixivTailorService_CreateTask_FullMethodName         = "/pixiv_tailor.PixivTailorService/CreateTask"
-	PixivTailorService_GetTaskStatus_FullMethodName      = "/pixiv_tailor.PixivTailorService/GetTaskStatus"
-	PixivTailorService_ListTasks_FullMethodName          = "/pixiv_tailor.PixivTailorService/ListTasks"
-	PixivTailorService_CancelTask_FullMethodName         = "/pixiv_tailor.PixivTailorService/CancelTask"
-	PixivTailorService_GetTaskProgress_FullMethodName    = "/pixiv_tailor.PixivTailorService/GetTaskProgress"
-	PixivTailorService_GetTaskLogs_FullMethodName        = "/pixiv_tailor.PixivTailorService/GetTaskLogs"
-	PixivTailorService_GetCrawlResults_FullMethodName    = "/pixiv_tailor.PixivTailorService/GetCrawlResults"
-	PixivTailorService_GetGeneratedImages_FullMethodName = "/pixiv_tailor.PixivTailorService/GetGeneratedImages"
-	PixivTailorService_GetTrainedModels_FullMethodName   = "/pixiv_tailor.PixivTailorService/GetTrainedModels"
-	PixivTailorService_GetSystemInfo_FullMethodName      = "/pixiv_tailor.PixivTailorService/GetSystemInfo"
+	PixivTailorService_GetConfig_FullMethodName            = "/pixiv_tailor.PixivTailorService/GetConfig"
+	PixivTailorService_UpdateConfig_FullMethodName         = "/pixiv_tailor.PixivTailorService/UpdateConfig"
+	PixivTailorService_ExportConfig_FullMethodName         = "/pixiv_tailor.PixivTailorService/ExportConfig"
+	PixivTailorService_ImportConfig_FullMethodName         = "/pixiv_tailor.PixivTailorService/ImportConfig"
+	PixivTailorService_CreateTask_FullMethodName           = "/pixiv_tailor.PixivTailorService/CreateTask"
+	PixivTailorService_GetTaskStatus_FullMethodName        = "/pixiv_tailor.PixivTailorService/GetTaskStatus"
+	PixivTailorService_ListTasks_FullMethodName            = "/pixiv_tailor.PixivTailorService/ListTasks"
+	PixivTailorService_CancelTask_FullMethodName           = "/pixiv_tailor.PixivTailorService/CancelTask"
+	PixivTailorService_GetTaskProgress_FullMethodName      = "/pixiv_tailor.PixivTailorService/GetTaskProgress"
+	PixivTailorService_GetTaskLogs_FullMethodName          = "/pixiv_tailor.PixivTailorService/GetTaskLogs"
+	PixivTailorService_ListTaskArtifacts_FullMethodName    = "/pixiv_tailor.PixivTailorService/ListTaskArtifacts"
+	PixivTailorService_DownloadTaskArtifact_FullMethodName = "/pixiv_tailor.PixivTailorService/DownloadTaskArtifact"
+	PixivTailorService_GetCrawlResults_FullMethodName      = "/pixiv_tailor.PixivTailorService/GetCrawlResults"
+	PixivTailorService_GetGeneratedImages_FullMethodName   = "/pixiv_tailor.PixivTailorService/GetGeneratedImages"
+	PixivTailorService_GetTrainedModels_FullMethodName     = "/pixiv_tailor.PixivTailorService/GetTrainedModels"
+	PixivTailorService_GetSystemInfo_FullMethodName        = "/pixiv_tailor.PixivTailorService/GetSystemInfo"
 )
 
 // PixivTailorServiceClient is the client API for PixivTailorService service.
@@ -52,6 +54,9 @@ type PixivTailorServiceClient interface {
 	// 进度跟踪
 	GetTaskProgress(ctx context.Context, in *GetTaskProgressRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[TaskProgressUpdate], error)
 	GetTaskLogs(ctx context.Context, in *GetTaskLogsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[LogEntry], error)
+	// 任务产出文件
+	ListTaskArtifacts(ctx context.Context, in *ListTaskArtifactsRequest, opts ...grpc.CallOption) (*ListTaskArtifactsResponse, error)
+	DownloadTaskArtifact(ctx context.Context, in *DownloadTaskArtifactRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ArtifactChunk], error)
 	// 数据管理
 	GetCrawlResults(ctx context.Context, in *GetCrawlResultsRequest, opts ...grpc.CallOption) (*GetCrawlResultsResponse, error)
 	GetGeneratedImages(ctx context.Context, in *GetGeneratedImagesRequest, opts ...grpc.CallOption) (*GetGeneratedImagesResponse, error)
@@ -186,6 +191,35 @@ func (c *pixivTailorServiceClient) GetTaskLogs(ctx context.Context, in *GetTaskL
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type PixivTailorService_GetTaskLogsClient = grpc.ServerStreamingClient[LogEntry]
 
+func (c *pixivTailorServiceClient) ListTaskArtifacts(ctx context.Context, in *ListTaskArtifactsRequest, opts ...grpc.CallOption) (*ListTaskArtifactsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListTaskArtifactsResponse)
+	err := c.cc.Invoke(ctx, PixivTailorService_ListTaskArtifacts_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pixivTailorServiceClient) DownloadTaskArtifact(ctx context.Context, in *DownloadTaskArtifactRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ArtifactChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &PixivTailorService_ServiceDesc.Streams[2], PixivTailorService_DownloadTaskArtifact_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[DownloadTaskArtifactRequest, ArtifactChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PixivTailorService_DownloadTaskArtifactClient = grpc.ServerStreamingClient[ArtifactChunk]
+
 func (c *pixivTailorServiceClient) GetCrawlResults(ctx context.Context, in *GetCrawlResultsRequest, opts ...grpc.CallOption) (*GetCrawlResultsResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(GetCrawlResultsResponse)
@@ -243,6 +277,9 @@ type PixivTailorServiceServer interface {
 	// 进度跟踪
 	GetTaskProgress(*GetTaskProgressRequest, grpc.ServerStreamingServer[TaskProgressUpdate]) error
 	GetTaskLogs(*GetTaskLogsRequest, grpc.ServerStreamingServer[LogEntry]) error
+	// 任务产出文件
+	ListTaskArtifacts(context.Context, *ListTaskArtifactsRequest) (*ListTaskArtifactsResponse, error)
+	DownloadTaskArtifact(*DownloadTaskArtifactRequest, grpc.ServerStreamingServer[ArtifactChunk]) error
 	// 数据管理
 	GetCrawlResults(context.Context, *GetCrawlResultsRequest) (*GetCrawlResultsResponse, error)
 	GetGeneratedImages(context.Context, *GetGeneratedImagesRequest) (*GetGeneratedImagesResponse, error)
@@ -289,6 +326,12 @@ func (UnimplementedPixivTailorServiceServer) GetTaskProgress(*GetTaskProgressReq
 func (UnimplementedPixivTailorServiceServer) GetTaskLogs(*GetTaskLogsRequest, grpc.ServerStreamingServer[LogEntry]) error {
 	return status.Errorf(codes.Unimplemented, "method GetTaskLogs not implemented")
 }
+func (UnimplementedPixivTailorServiceServer) ListTaskArtifacts(context.Context, *ListTaskArtifactsRequest) (*ListTaskArtifactsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTaskArtifacts not implemented")
+}
+func (UnimplementedPixivTailorServiceServer) DownloadTaskArtifact(*DownloadTaskArtifactRequest, grpc.ServerStreamingServer[ArtifactChunk]) error {
+	return status.Errorf(codes.Unimplemented, "method DownloadTaskArtifact not implemented")
+}
 func (UnimplementedPixivTailorServiceServer) GetCrawlResults(context.Context, *GetCrawlResultsRequest) (*GetCrawlResultsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetCrawlResults not implemented")
 }
@@ -488,6 +531,35 @@ func _PixivTailorService_GetTaskLogs_Handler(srv interface{}, stream grpc.Server
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type PixivTailorService_GetTaskLogsServer = grpc.ServerStreamingServer[LogEntry]
 
+func _PixivTailorService_ListTaskArtifacts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTaskArtifactsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PixivTailorServiceServer).ListTaskArtifacts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PixivTailorService_ListTaskArtifacts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PixivTailorServiceServer).ListTaskArtifacts(ctx, req.(*ListTaskArtifactsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PixivTailorService_DownloadTaskArtifact_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DownloadTaskArtifactRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PixivTailorServiceServer).DownloadTaskArtifact(m, &grpc.GenericServerStream[DownloadTaskArtifactRequest, ArtifactChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type PixivTailorService_DownloadTaskArtifactServer = grpc.ServerStreamingServer[ArtifactChunk]
+
 func _PixivTailorService_GetCrawlResults_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetCrawlResultsRequest)
 	if err := dec(in); err != nil {
@@ -599,6 +671,10 @@ var PixivTailorService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "CancelTask",
 			Handler:    _PixivTailorService_CancelTask_Handler,
 		},
+		{
+			MethodName: "ListTaskArtifacts",
+			Handler:    _PixivTailorService_ListTaskArtifacts_Handler,
+		},
 		{
 			MethodName: "GetCrawlResults",
 			Handler:    _PixivTailorService_GetCrawlResults_Handler,
@@ -627,6 +703,11 @@ var PixivTailorService_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _PixivTailorService_GetTaskLogs_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "DownloadTaskArtifact",
+			Handler:       _PixivTailorService_DownloadTaskArtifact_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "pixiv_tailor.proto",
 }